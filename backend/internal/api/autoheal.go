@@ -0,0 +1,167 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"cyber-container-platform/internal/autoheal"
+	"cyber-container-platform/internal/docker"
+	"cyber-container-platform/internal/websocket"
+
+	"github.com/docker/docker/api/types"
+	"github.com/gin-gonic/gin"
+)
+
+// AutohealEvent records something the auto-heal supervisor did to a
+// container: "restarted", "restart_failed" or "recovered".
+type AutohealEvent struct {
+	ID            int64  `json:"id"`
+	ContainerID   string `json:"container_id"`
+	ContainerName string `json:"container_name"`
+	Event         string `json:"event"`
+	FailingStreak int    `json:"failing_streak"`
+	// Output is the output of the failed probe that led to a restart.
+	Output    string    `json:"output,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const autohealEventColumns = "id, container_id, container_name, event, failing_streak, output, error, created_at"
+
+func scanAutohealEvent(row rowScanner) (*AutohealEvent, error) {
+	var e AutohealEvent
+	if err := row.Scan(&e.ID, &e.ContainerID, &e.ContainerName, &e.Event, &e.FailingStreak, &e.Output, &e.Error, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// recordAutohealEvent stores an event and broadcasts it as a
+// container_autoheal message.
+func (s *Server) recordAutohealEvent(e *AutohealEvent) {
+	result, err := s.db.GetDB().Exec(
+		`INSERT INTO autoheal_events (container_id, container_name, event, failing_streak, output, error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		e.ContainerID, e.ContainerName, e.Event, e.FailingStreak, e.Output, e.Error, e.CreatedAt,
+	)
+	if err != nil {
+		s.logger.Error("Failed to record auto-heal event", err, map[string]interface{}{"container": e.ContainerName})
+	} else {
+		e.ID, _ = result.LastInsertId()
+	}
+	s.wsHub.Broadcast(websocket.Message{Type: "container_autoheal", Data: e})
+}
+
+// healContainers restarts the running containers labelled for auto-heal
+// whose healthcheck failed at least their threshold of times in a row.
+func (s *Server) healContainers(now time.Time) error {
+	containers, err := s.dockerClient.QueryContainers(docker.ContainerQuery{
+		States: []string{"running"},
+		Labels: []string{autoheal.Label},
+	})
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(containers))
+	for _, cont := range containers {
+		if !autoheal.Enabled(cont.Labels) || cont.Health == nil {
+			continue
+		}
+		seen[cont.ID] = true
+
+		threshold := autoheal.Threshold(cont.Labels, s.config.AutohealThreshold)
+		event := &AutohealEvent{
+			ContainerID:   cont.ID,
+			ContainerName: cont.Name,
+			FailingStreak: cont.Health.FailingStreak,
+			CreatedAt:     now.UTC(),
+		}
+
+		switch s.healer.Observe(cont.ID, cont.Health.Status, cont.Health.FailingStreak, threshold, now) {
+		case autoheal.Restart:
+			event.Event = "restarted"
+			event.Output = cont.Health.LastOutput
+			if err := s.dockerClient.RestartContainer(cont.ID, s.config.StopTimeout); err != nil {
+				event.Event = "restart_failed"
+				event.Error = err.Error()
+			}
+			state := s.healer.Restarted(cont.ID, now)
+			s.logger.Info("Auto-heal restarted unhealthy container", map[string]interface{}{
+				"container": cont.Name, "failing_streak": cont.Health.FailingStreak,
+				"restarts": state.Restarts, "next_attempt": state.NextAttempt, "error": event.Error,
+			})
+			s.recordAutohealEvent(event)
+		case autoheal.Recovered:
+			event.Event = "recovered"
+			s.recordAutohealEvent(event)
+		}
+	}
+	s.healer.Retain(seen)
+	return nil
+}
+
+// superviseHealth runs healContainers every configured interval.
+func (s *Server) superviseHealth() {
+	if s.config.AutohealIntervalSeconds <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(s.config.AutohealIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if err := s.healContainers(now); err != nil {
+			s.logger.Error("Auto-heal check failed", err)
+		}
+	}
+}
+
+// getContainerAutoheal returns the auto-heal settings, backoff state and
+// event history, newest first, of a container.
+func (s *Server) getContainerAutoheal(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	cont, err := s.dockerClient.InspectContainer(c.Param("id"))
+	if err != nil {
+		c.JSON(containerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	rows, err := s.db.GetDB().Query(
+		"SELECT "+autohealEventColumns+" FROM autoheal_events WHERE container_id = ? ORDER BY id DESC LIMIT ?",
+		cont.ID, limit,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	events := []*AutohealEvent{}
+	for rows.Next() {
+		event, err := scanAutohealEvent(rows)
+		if err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+
+	response := gin.H{
+		"enabled":   s.config.AutohealIntervalSeconds > 0 && autoheal.Enabled(cont.Config.Labels),
+		"threshold": autoheal.Threshold(cont.Config.Labels, s.config.AutohealThreshold),
+		"events":    events,
+	}
+	if cont.State != nil && cont.State.Health != nil && cont.State.Health.Status != types.NoHealthcheck {
+		response["health"] = cont.State.Health.Status
+	}
+	if state, ok := s.healer.State(cont.ID); ok {
+		response["state"] = state
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"cyber-container-platform/internal/backup"
+	"cyber-container-platform/internal/docker"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RestoreVolumeRequest struct {
+	BackupID int64 `json:"backup_id" binding:"required"`
+	Replace  bool  `json:"replace"`
+}
+
+// backupErrorStatus maps backup manager errors to HTTP status codes.
+func backupErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, backup.ErrBackupNotFound), docker.IsNotFound(err):
+		return http.StatusNotFound
+	case errors.Is(err, backup.ErrVolumeExists), errors.Is(err, backup.ErrBusy):
+		return http.StatusConflict
+	case errors.Is(err, backup.ErrChecksum), errors.Is(err, backup.ErrCorrupt):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (s *Server) backupVolume(c *gin.Context) {
+	name := c.Param("name")
+
+	record, err := s.backups.Backup(name, currentUser(c).ownerID())
+	if err != nil {
+		response := gin.H{"error": err.Error()}
+		if record != nil {
+			response["backup"] = record
+		}
+		c.JSON(backupErrorStatus(err), response)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"backup": record, "message": "Volume backed up successfully"})
+}
+
+// listVolumeBackups serves both /volumes/backups (all volumes) and
+// /volumes/:name/backups.
+func (s *Server) listVolumeBackups(c *gin.Context) {
+	records, err := s.backups.List(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"backups": records})
+}
+
+func (s *Server) restoreVolume(c *gin.Context) {
+	name := c.Param("name")
+
+	var req RestoreVolumeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.backups.Restore(name, req.BackupID, req.Replace); err != nil {
+		c.JSON(backupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Volume restored successfully"})
+}
+
+func (s *Server) downloadVolumeBackup(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid backup ID"})
+		return
+	}
+
+	record, err := s.backups.Get(id)
+	if err != nil {
+		c.JSON(backupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if record.Status != backup.StatusCompleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup has no archive"})
+		return
+	}
+
+	c.FileAttachment(record.File, filepath.Base(record.File))
+}
+
+func (s *Server) deleteVolumeBackup(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid backup ID"})
+		return
+	}
+
+	if err := s.backups.Delete(id); err != nil {
+		c.JSON(backupErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Backup deleted successfully"})
+}
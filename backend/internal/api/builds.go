@@ -0,0 +1,399 @@
+package api
+
+import (
+	"archive/tar"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cyber-container-platform/internal/docker"
+	"cyber-container-platform/internal/registry"
+	"cyber-container-platform/internal/websocket"
+
+	registrytypes "github.com/docker/docker/api/types/registry"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	buildStatusRunning   = "running"
+	buildStatusSucceeded = "succeeded"
+	buildStatusFailed    = "failed"
+)
+
+// buildLogLines is how much of a build's output is kept in its history
+// record. The full log is only available live over the WebSocket.
+const buildLogLines = 200
+
+// ImageBuild is the history record of one image build.
+type ImageBuild struct {
+	ID         int64      `json:"id"`
+	Tags       []string   `json:"tags"`
+	Dockerfile string     `json:"dockerfile"`
+	Target     string     `json:"target,omitempty"`
+	Status     string     `json:"status"`
+	ImageID    string     `json:"image_id,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	Log        string     `json:"log,omitempty"`
+	DurationMs int64      `json:"duration_ms"`
+	CreatedBy  int64      `json:"created_by"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func validateBuildOptions(opts docker.BuildOptions) error {
+	for _, tag := range opts.Tags {
+		if err := registry.ValidateTag(tag); err != nil {
+			return err
+		}
+	}
+	if opts.Dockerfile != "" {
+		if strings.HasPrefix(opts.Dockerfile, "/") || strings.Contains("/"+opts.Dockerfile+"/", "/../") {
+			return fmt.Errorf("dockerfile must be a path inside the build context")
+		}
+	}
+	for key := range opts.BuildArgs {
+		if key == "" {
+			return fmt.Errorf("build argument names must not be empty")
+		}
+	}
+	for key := range opts.Labels {
+		if key == "" {
+			return fmt.Errorf("label keys must not be empty")
+		}
+	}
+	return nil
+}
+
+// addTarFile appends r to the archive as name. Multipart parts have no known
+// size, so the content is spooled to a scratch file first.
+func addTarFile(tw *tar.Writer, name string, r io.Reader) error {
+	scratch, err := os.CreateTemp("", "build-file-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(scratch.Name())
+	defer scratch.Close()
+
+	size, err := io.Copy(scratch, r)
+	if err != nil {
+		return err
+	}
+	if _, err := scratch.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, scratch)
+	return err
+}
+
+var errMixedBuildInput = errors.New("send either one context archive or a dockerfile with files")
+
+// readBuildParts writes the build context of a multipart build request to
+// spool: either a ready-made context archive from the "context" field, or a
+// tar packed here from the "dockerfile" field and any "file" fields.
+func readBuildParts(form *multipart.Reader, spool io.Writer) (docker.BuildOptions, error) {
+	var opts docker.BuildOptions
+	var tw *tar.Writer
+	hasContext, hasDockerfile := false, false
+
+	for {
+		part, err := form.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return opts, err
+		}
+
+		switch part.FormName() {
+		case "options":
+			if err := json.NewDecoder(part).Decode(&opts); err != nil {
+				return opts, fmt.Errorf("invalid options: %w", err)
+			}
+		case "context":
+			if tw != nil || hasContext {
+				return opts, errMixedBuildInput
+			}
+			hasContext = true
+			if _, err := io.Copy(spool, part); err != nil {
+				return opts, err
+			}
+		case "dockerfile", "file":
+			if hasContext {
+				return opts, errMixedBuildInput
+			}
+			if tw == nil {
+				tw = tar.NewWriter(spool)
+			}
+
+			name := "Dockerfile"
+			if part.FormName() == "file" {
+				name = part.FileName()
+				if !validUploadName(name) || name == "Dockerfile" {
+					return opts, fmt.Errorf("invalid file name %q", name)
+				}
+			} else if hasDockerfile {
+				return opts, fmt.Errorf("only one dockerfile may be sent")
+			}
+			hasDockerfile = hasDockerfile || part.FormName() == "dockerfile"
+
+			if err := addTarFile(tw, name, part); err != nil {
+				return opts, err
+			}
+		}
+	}
+
+	switch {
+	case tw != nil && !hasDockerfile:
+		return opts, fmt.Errorf("a dockerfile field is required when sending loose files")
+	case tw != nil:
+		opts.Dockerfile = "Dockerfile"
+		if err := tw.Close(); err != nil {
+			return opts, err
+		}
+	case !hasContext:
+		return opts, fmt.Errorf("a context archive or a dockerfile is required")
+	}
+
+	return opts, validateBuildOptions(opts)
+}
+
+// spoolBuildContext reads the multipart build request into a temporary tar
+// file. It writes the error response itself and returns nil on failure.
+func (s *Server) spoolBuildContext(c *gin.Context) (*os.File, docker.BuildOptions) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, s.config.MaxBuildContextBytes)
+	form, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, docker.BuildOptions{}
+	}
+
+	spool, err := os.CreateTemp("", "build-context-*.tar")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, docker.BuildOptions{}
+	}
+
+	opts, err := readBuildParts(form, spool)
+	if err == nil {
+		_, err = spool.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("Build context exceeds the limit of %d bytes", s.config.MaxBuildContextBytes),
+			})
+			return nil, opts
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, opts
+	}
+
+	return spool, opts
+}
+
+// buildImage starts a build in the background and returns its history
+// record. Progress is broadcast as image_build_log events, the outcome as an
+// image_build_finished event.
+func (s *Server) buildImage(c *gin.Context) {
+	spool, opts := s.spoolBuildContext(c)
+	if spool == nil {
+		return
+	}
+	for _, tag := range opts.Tags {
+		if err := s.checkReferencePolicy(tag); err != nil {
+			spool.Close()
+			os.Remove(spool.Name())
+			respondImagePolicyError(c, err)
+			return
+		}
+	}
+
+	// Stored registry logins are an administrator's to use; other users
+	// build from public base images only.
+	user := currentUser(c)
+	var auth map[string]registrytypes.AuthConfig
+	if user.IsAdmin() {
+		var err error
+		if auth, err = s.registries.AuthConfigs(); err != nil {
+			spool.Close()
+			os.Remove(spool.Name())
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if opts.Tags == nil {
+		opts.Tags = []string{}
+	}
+	if opts.Dockerfile == "" {
+		opts.Dockerfile = "Dockerfile"
+	}
+	tags, _ := json.Marshal(opts.Tags)
+
+	started := time.Now()
+	result, err := s.db.GetDB().Exec(
+		"INSERT INTO image_builds (tags, dockerfile, target, status, created_by, started_at) VALUES (?, ?, ?, ?, ?, ?)",
+		string(tags), opts.Dockerfile, opts.Target, buildStatusRunning, user.ownerID(), started,
+	)
+	if err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	id, _ := result.LastInsertId()
+
+	go s.runBuild(id, spool, opts, auth, user.Username, started)
+
+	build, err := s.findImageBuild(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"build": build, "message": "Build started"})
+}
+
+func (s *Server) runBuild(id int64, spool *os.File, opts docker.BuildOptions, auth map[string]registrytypes.AuthConfig, username string, started time.Time) {
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	var tail []string
+	imageID, err := s.dockerClient.BuildImage(spool, opts, auth, func(line string) {
+		if len(tail) == buildLogLines {
+			tail = tail[1:]
+		}
+		tail = append(tail, line)
+		s.wsHub.Broadcast(websocket.Message{
+			Type: "image_build_log",
+			Data: map[string]interface{}{"build_id": id, "stream": line},
+		})
+	})
+
+	finished := time.Now()
+	status, message := buildStatusSucceeded, ""
+	fields := map[string]interface{}{"build": id, "tags": opts.Tags, "user": username}
+	if err != nil {
+		status, message = buildStatusFailed, err.Error()
+		s.logger.Error("Image build failed", err, fields)
+	} else {
+		fields["image_id"] = imageID
+		s.logger.Info("Image built", fields)
+	}
+
+	if _, err := s.db.GetDB().Exec(
+		"UPDATE image_builds SET status = ?, image_id = ?, error = ?, log = ?, duration_ms = ?, finished_at = ? WHERE id = ?",
+		status, imageID, message, strings.Join(tail, ""), finished.Sub(started).Milliseconds(), finished, id,
+	); err != nil {
+		s.logger.Error("Failed to record image build", err, fields)
+	}
+
+	if build, err := s.findImageBuild(id); err == nil {
+		build.Log = ""
+		s.wsHub.Broadcast(websocket.Message{Type: "image_build_finished", Data: build})
+	}
+}
+
+// failInterruptedBuilds marks builds left running by a previous process as
+// failed; their Docker stream is gone.
+func (s *Server) failInterruptedBuilds() {
+	s.db.GetDB().Exec(
+		"UPDATE image_builds SET status = ?, error = ?, finished_at = ? WHERE status = ?",
+		buildStatusFailed, "interrupted by shutdown", time.Now(), buildStatusRunning,
+	)
+}
+
+const imageBuildColumns = "id, tags, dockerfile, target, status, image_id, error, log, duration_ms, created_by, started_at, finished_at"
+
+func scanImageBuild(row rowScanner) (*ImageBuild, error) {
+	var build ImageBuild
+	var tags string
+	var createdBy sql.NullInt64
+	var finishedAt sql.NullTime
+
+	if err := row.Scan(&build.ID, &tags, &build.Dockerfile, &build.Target, &build.Status, &build.ImageID,
+		&build.Error, &build.Log, &build.DurationMs, &createdBy, &build.StartedAt, &finishedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(tags), &build.Tags); err != nil {
+		return nil, fmt.Errorf("corrupt image build %d: %w", build.ID, err)
+	}
+	build.CreatedBy = createdBy.Int64
+	if finishedAt.Valid {
+		build.FinishedAt = &finishedAt.Time
+	}
+
+	return &build, nil
+}
+
+func (s *Server) findImageBuild(id int64) (*ImageBuild, error) {
+	return scanImageBuild(s.db.GetDB().QueryRow("SELECT "+imageBuildColumns+" FROM image_builds WHERE id = ?", id))
+}
+
+// listImageBuilds returns the build history, newest first, without logs.
+func (s *Server) listImageBuilds(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	rows, err := s.db.GetDB().Query("SELECT "+imageBuildColumns+" FROM image_builds ORDER BY id DESC LIMIT ?", limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	builds := []*ImageBuild{}
+	for rows.Next() {
+		build, err := scanImageBuild(rows)
+		if err != nil {
+			continue
+		}
+		build.Log = ""
+		builds = append(builds, build)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"builds": builds})
+}
+
+func (s *Server) getImageBuild(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid build ID"})
+		return
+	}
+
+	build, err := s.findImageBuild(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Build not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"build": build})
+}
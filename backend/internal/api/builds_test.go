@@ -0,0 +1,73 @@
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"mime/multipart"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadBuildParts(t *testing.T) {
+	read := func(fields func(w *multipart.Writer)) (*bytes.Buffer, error) {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		fields(w)
+		w.Close()
+
+		var spool bytes.Buffer
+		_, err := readBuildParts(multipart.NewReader(&body, w.Boundary()), &spool)
+		return &spool, err
+	}
+
+	spool, err := read(func(w *multipart.Writer) {
+		w.WriteField("options", `{"tags":["api:dev"],"build_args":{"VERSION":"1.2"}}`)
+		w.WriteField("dockerfile", "FROM alpine\nCOPY app.sh /\n")
+		part, _ := w.CreateFormFile("file", "app.sh")
+		part.Write([]byte("echo hi\n"))
+	})
+	assert.NoError(t, err)
+
+	var names []string
+	archive := tar.NewReader(spool)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		names = append(names, header.Name)
+	}
+	assert.Equal(t, []string{"Dockerfile", "app.sh"}, names)
+
+	_, err = read(func(w *multipart.Writer) {
+		part, _ := w.CreateFormFile("file", "app.sh")
+		part.Write([]byte("echo hi\n"))
+	})
+	assert.Error(t, err, "loose files need a dockerfile")
+
+	_, err = read(func(w *multipart.Writer) {
+		w.WriteField("dockerfile", "FROM alpine\n")
+		part, _ := w.CreateFormFile("context", "context.tar")
+		part.Write([]byte("tar"))
+	})
+	assert.Error(t, err, "context archive and dockerfile are exclusive")
+
+	_, err = read(func(w *multipart.Writer) {
+		w.WriteField("options", `{"tags":["API:Dev"]}`)
+		w.WriteField("dockerfile", "FROM alpine\n")
+	})
+	assert.Error(t, err, "invalid tag")
+
+	_, err = read(func(w *multipart.Writer) {
+		w.WriteField("options", `{"dockerfile":"../Dockerfile"}`)
+		part, _ := w.CreateFormFile("context", "context.tar")
+		part.Write([]byte("tar"))
+	})
+	assert.Error(t, err, "dockerfile outside the context")
+
+	_, err = read(func(w *multipart.Writer) {})
+	assert.Error(t, err)
+}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"cyber-container-platform/internal/docker"
+	"cyber-container-platform/internal/labels"
+
+	"github.com/gin-gonic/gin"
+)
+
+// composeProjectLabel is set by Docker Compose on every container of a
+// project.
+const composeProjectLabel = "com.docker.compose.project"
+
+const (
+	defaultBulkConcurrency = 4
+	maxBulkConcurrency     = 16
+)
+
+var bulkActions = map[string]bool{
+	"start": true, "stop": true, "restart": true, "pause": true,
+	"unpause": true, "kill": true, "remove": true,
+}
+
+// ContainerSelector picks the containers of a bulk operation. All given
+// criteria must match.
+type ContainerSelector struct {
+	IDs            []string `json:"ids"`
+	Name           string   `json:"name"`
+	Labels         []string `json:"labels"`
+	ComposeProject string   `json:"compose_project"`
+}
+
+// BulkContainerRequest applies one action to every selected container.
+// Timeout applies to stop and restart, Signal to kill, and Force and
+// RemoveVolumes to remove.
+type BulkContainerRequest struct {
+	Action        string            `json:"action" binding:"required"`
+	Selector      ContainerSelector `json:"selector"`
+	Timeout       *int              `json:"timeout"`
+	Signal        string            `json:"signal"`
+	Force         *bool             `json:"force"`
+	RemoveVolumes bool              `json:"remove_volumes"`
+	Concurrency   int               `json:"concurrency"`
+}
+
+type BulkResult struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// labelSelector returns the label requirements of sel, the compose project
+// included.
+func (sel ContainerSelector) labelSelector() (labels.Selector, error) {
+	selector, err := labels.Parse(sel.Labels)
+	if err != nil {
+		return nil, err
+	}
+	if sel.ComposeProject != "" {
+		selector = append(selector, labels.Requirement{Key: composeProjectLabel, Value: sel.ComposeProject, HasValue: true})
+	}
+	return selector, nil
+}
+
+// query returns the listing that selectContainers needs: the label
+// requirements Docker can evaluate narrow it, unless IDs are given, which
+// must be resolved against every container.
+func (sel ContainerSelector) query() (docker.ContainerQuery, error) {
+	selector, err := sel.labelSelector()
+	if err != nil || len(sel.IDs) > 0 {
+		return docker.ContainerQuery{}, err
+	}
+	return docker.ContainerQuery{Labels: selector.DockerFilters()}, nil
+}
+
+// resolveContainer finds the container id refers to, like the Docker CLI: a
+// full ID or a name wins over ID prefixes, and a prefix matching several
+// containers is an error.
+func resolveContainer(containers []docker.ContainerInfo, id string) (string, bool, error) {
+	var matches []string
+	for _, cont := range containers {
+		if cont.ID == id || cont.Name == id {
+			return cont.ID, true, nil
+		}
+		if len(id) >= 4 && strings.HasPrefix(cont.ID, id) {
+			matches = append(matches, cont.ID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", false, nil
+	case 1:
+		return matches[0], true, nil
+	default:
+		return "", false, fmt.Errorf("container ID prefix %q is ambiguous, it matches %d containers", id, len(matches))
+	}
+}
+
+// selectContainers returns the containers matching sel, in the order they
+// were listed. IDs may be full IDs, unambiguous ID prefixes or names; an
+// entry matching no container is returned as a failed result.
+func selectContainers(containers []docker.ContainerInfo, sel ContainerSelector) ([]docker.ContainerInfo, []BulkResult, error) {
+	if len(sel.IDs) == 0 && sel.Name == "" && len(sel.Labels) == 0 && sel.ComposeProject == "" {
+		return nil, nil, errors.New("selector must specify ids, name, labels or compose_project")
+	}
+	if sel.Name != "" {
+		if _, err := path.Match(sel.Name, ""); err != nil {
+			return nil, nil, fmt.Errorf("invalid name pattern %q", sel.Name)
+		}
+	}
+	selector, err := sel.labelSelector()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var missing []BulkResult
+	wanted := make(map[string]bool)
+	for _, id := range sel.IDs {
+		full, found, err := resolveContainer(containers, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !found {
+			missing = append(missing, BulkResult{ID: id, Error: "No such container"})
+			continue
+		}
+		wanted[full] = true
+	}
+
+	var selected []docker.ContainerInfo
+	for _, cont := range containers {
+		if len(sel.IDs) > 0 && !wanted[cont.ID] {
+			continue
+		}
+		if sel.Name != "" {
+			if ok, _ := path.Match(sel.Name, cont.Name); !ok {
+				continue
+			}
+		}
+		if !selector.Matches(cont.Labels) {
+			continue
+		}
+		selected = append(selected, cont)
+	}
+	return selected, missing, nil
+}
+
+func (s *Server) runBulkAction(req BulkContainerRequest, signal string, id string) error {
+	timeout := s.config.StopTimeout
+	if req.Timeout != nil {
+		timeout = *req.Timeout
+	}
+
+	switch req.Action {
+	case "start":
+		return s.dockerClient.StartContainer(id)
+	case "stop":
+		return s.dockerClient.StopContainer(id, timeout)
+	case "restart":
+		return s.dockerClient.RestartContainer(id, timeout)
+	case "pause":
+		return s.dockerClient.PauseContainer(id)
+	case "unpause":
+		return s.dockerClient.UnpauseContainer(id)
+	case "kill":
+		return s.dockerClient.KillContainer(id, signal)
+	case "remove":
+		force := true
+		if req.Force != nil {
+			force = *req.Force
+		}
+		return s.dockerClient.RemoveContainer(id, force, req.RemoveVolumes)
+	}
+	return fmt.Errorf("unknown action %q", req.Action)
+}
+
+// bulkContainers applies an action to the containers matching a selector,
+// running at most req.Concurrency operations at a time. One container
+// failing does not stop the others; the response lists every result.
+func (s *Server) bulkContainers(c *gin.Context) {
+	var req BulkContainerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !bulkActions[req.Action] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown action %q", req.Action)})
+		return
+	}
+	if req.Timeout != nil && *req.Timeout < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "timeout must be a non-negative number of seconds"})
+		return
+	}
+	if req.Concurrency < 0 || req.Concurrency > maxBulkConcurrency {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("concurrency must be between 1 and %d", maxBulkConcurrency)})
+		return
+	}
+	if req.Concurrency == 0 {
+		req.Concurrency = defaultBulkConcurrency
+	}
+	var signal string
+	if req.Action == "kill" {
+		var err error
+		if signal, err = parseSignal(req.Signal); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	query, err := req.Selector.query()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	containers, err := s.dockerClient.QueryContainers(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	selected, missing, err := selectContainers(containers, req.Selector)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results := make([]BulkResult, len(selected))
+	sem := make(chan struct{}, req.Concurrency)
+	var wg sync.WaitGroup
+	for i, cont := range selected {
+		wg.Add(1)
+		go func(i int, cont docker.ContainerInfo) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = BulkResult{ID: cont.ID, Name: cont.Name, Success: true}
+			if err := s.runBulkAction(req, signal, cont.ID); err != nil {
+				results[i].Success = false
+				results[i].Error = err.Error()
+			}
+		}(i, cont)
+	}
+	wg.Wait()
+	results = append(results, missing...)
+
+	succeeded := 0
+	for _, result := range results {
+		if result.Success {
+			succeeded++
+		}
+	}
+
+	s.logger.Info("Bulk container action", map[string]interface{}{
+		"action":    req.Action,
+		"user":      currentUser(c).Username,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"action":    req.Action,
+		"results":   results,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+	})
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"testing"
+
+	"cyber-container-platform/internal/docker"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectContainers(t *testing.T) {
+	containers := []docker.ContainerInfo{
+		{ID: "aaaa1111", Name: "web-1", Labels: map[string]string{"com.docker.compose.project": "shop", "tier": "web"}},
+		{ID: "bbbb2222", Name: "web-2", Labels: map[string]string{"com.docker.compose.project": "shop", "tier": "web", "pinned": "true"}},
+		{ID: "cccc3333", Name: "db", Labels: map[string]string{"com.docker.compose.project": "shop"}},
+		{ID: "dddd4444", Name: "web-3", Labels: map[string]string{"tier": "web"}},
+	}
+	names := func(selected []docker.ContainerInfo) []string {
+		var out []string
+		for _, cont := range selected {
+			out = append(out, cont.Name)
+		}
+		return out
+	}
+
+	selected, missing, err := selectContainers(containers, ContainerSelector{Name: "web-*", ComposeProject: "shop"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web-1", "web-2"}, names(selected))
+	assert.Empty(t, missing)
+
+	selected, _, err = selectContainers(containers, ContainerSelector{Labels: []string{"tier=web", "!pinned"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web-1", "web-3"}, names(selected))
+
+	selected, missing, err = selectContainers(containers, ContainerSelector{IDs: []string{"db", "bbbb", "gone"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web-2", "db"}, names(selected))
+	assert.Equal(t, []BulkResult{{ID: "gone", Error: "No such container"}}, missing)
+
+	_, _, err = selectContainers(containers, ContainerSelector{IDs: []string{"web-1", "aaaa"}})
+	assert.NoError(t, err)
+	containers = append(containers, docker.ContainerInfo{ID: "aaaa5555", Name: "cache"})
+	_, _, err = selectContainers(containers, ContainerSelector{IDs: []string{"aaaa"}})
+	assert.Error(t, err, "a prefix matching several containers is ambiguous")
+	selected, _, err = selectContainers(containers, ContainerSelector{IDs: []string{"aaaa1"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web-1"}, names(selected))
+
+	query, err := ContainerSelector{Labels: []string{"tier=web", "!pinned"}, ComposeProject: "shop"}.query()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tier=web", "com.docker.compose.project=shop"}, query.Labels)
+	query, err = ContainerSelector{IDs: []string{"db"}, Labels: []string{"tier=web"}}.query()
+	assert.NoError(t, err)
+	assert.Empty(t, query.Labels, "IDs are resolved against all containers")
+
+	_, _, err = selectContainers(containers, ContainerSelector{})
+	assert.Error(t, err, "an empty selector would match everything")
+	_, _, err = selectContainers(containers, ContainerSelector{Name: "web-["})
+	assert.Error(t, err)
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cyber-container-platform/internal/docker"
+	"cyber-container-platform/internal/registry"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CommitContainerRequest snapshots a container into Repository:Tag. Author
+// defaults to the calling user and Pause to true.
+type CommitContainerRequest struct {
+	Repository string   `json:"repository" binding:"required"`
+	Tag        string   `json:"tag"`
+	Message    string   `json:"message"`
+	Author     string   `json:"author"`
+	Pause      *bool    `json:"pause"`
+	Changes    []string `json:"changes"`
+}
+
+// ImageCommit records an image committed from a container.
+type ImageCommit struct {
+	ID            int64     `json:"id"`
+	ContainerID   string    `json:"container_id"`
+	ContainerName string    `json:"container_name"`
+	ImageID       string    `json:"image_id"`
+	Reference     string    `json:"reference"`
+	Message       string    `json:"message"`
+	Author        string    `json:"author"`
+	CreatedBy     int64     `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+const imageCommitColumns = "id, container_id, container_name, image_id, reference, message, author, created_by, created_at"
+
+func scanImageCommit(row rowScanner) (*ImageCommit, error) {
+	var commit ImageCommit
+	var createdBy sql.NullInt64
+	if err := row.Scan(&commit.ID, &commit.ContainerID, &commit.ContainerName, &commit.ImageID, &commit.Reference,
+		&commit.Message, &commit.Author, &createdBy, &commit.CreatedAt); err != nil {
+		return nil, err
+	}
+	commit.CreatedBy = createdBy.Int64
+	return &commit, nil
+}
+
+// getContainerChanges lists the paths changed in the container's
+// filesystem, optionally only those of one ?kind.
+func (s *Server) getContainerChanges(c *gin.Context) {
+	kind := c.Query("kind")
+	switch kind {
+	case "", "added", "modified", "deleted":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be added, modified or deleted"})
+		return
+	}
+
+	changes, err := s.dockerClient.ContainerChanges(c.Param("id"))
+	if err != nil {
+		c.JSON(containerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	summary := map[string]int{"added": 0, "modified": 0, "deleted": 0}
+	filtered := []docker.FileChange{}
+	for _, change := range changes {
+		summary[change.Kind]++
+		if kind == "" || change.Kind == kind {
+			filtered = append(filtered, change)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"changes": filtered, "summary": summary})
+}
+
+func (s *Server) commitContainer(c *gin.Context) {
+	var req CommitContainerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reference, err := registry.TagReference(req.Repository, req.Tag)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.checkReferencePolicy(reference); err != nil {
+		respondImagePolicyError(c, err)
+		return
+	}
+	if err := docker.ValidateCommitChanges(req.Changes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	opts := docker.CommitOptions{
+		Reference: reference,
+		Message:   strings.TrimSpace(req.Message),
+		Author:    strings.TrimSpace(req.Author),
+		Pause:     req.Pause == nil || *req.Pause,
+		Changes:   req.Changes,
+	}
+	if opts.Author == "" {
+		opts.Author = user.Username
+	}
+
+	cont, err := s.dockerClient.InspectContainer(c.Param("id"))
+	if err != nil {
+		c.JSON(containerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	imageID, err := s.dockerClient.CommitContainer(cont.ID, opts)
+	if err != nil {
+		c.JSON(containerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := s.db.GetDB().Exec(
+		`INSERT INTO image_commits (container_id, container_name, image_id, reference, message, author, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		cont.ID, strings.TrimPrefix(cont.Name, "/"), imageID, reference, opts.Message, opts.Author, user.ownerID(), time.Now().UTC(),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Image %s committed but not recorded: %v", reference, err)})
+		return
+	}
+	id, _ := result.LastInsertId()
+
+	commit, err := scanImageCommit(s.db.GetDB().QueryRow("SELECT "+imageCommitColumns+" FROM image_commits WHERE id = ?", id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.logger.Info("Container committed", map[string]interface{}{
+		"container": commit.ContainerName, "image": reference, "user": user.Username,
+	})
+	c.JSON(http.StatusCreated, gin.H{"commit": commit, "message": "Container committed successfully"})
+}
+
+// listImageCommits returns committed images, newest first, optionally only
+// those of one ?container (ID or name).
+func (s *Server) listImageCommits(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	query := "SELECT " + imageCommitColumns + " FROM image_commits"
+	args := []interface{}{}
+	if name := c.Query("container"); name != "" {
+		query += " WHERE container_id = ? OR container_name = ?"
+		args = append(args, name, name)
+	}
+	rows, err := s.db.GetDB().Query(query+" ORDER BY id DESC LIMIT ?", append(args, limit)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	commits := []*ImageCommit{}
+	for rows.Next() {
+		commit, err := scanImageCommit(rows)
+		if err != nil {
+			continue
+		}
+		commits = append(commits, commit)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"commits": commits})
+}
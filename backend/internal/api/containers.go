@@ -0,0 +1,259 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"cyber-container-platform/internal/docker"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/gin-gonic/gin"
+)
+
+// containerErrorStatus maps Docker errors of container operations to HTTP
+// status codes.
+func containerErrorStatus(err error) int {
+	switch {
+	case docker.IsNotFound(err):
+		return http.StatusNotFound
+	case docker.IsConflict(err):
+		return http.StatusConflict
+	case docker.IsInvalid(err):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// stopTimeout reads the ?timeout= seconds parameter of stop and restart,
+// defaulting to the configured stop timeout.
+func (s *Server) stopTimeout(c *gin.Context) (int, bool) {
+	raw := c.Query("timeout")
+	if raw == "" {
+		return s.config.StopTimeout, true
+	}
+	timeout, err := strconv.Atoi(raw)
+	if err != nil || timeout < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "timeout must be a non-negative number of seconds"})
+		return 0, false
+	}
+	return timeout, true
+}
+
+// boolQuery reads an optional boolean query parameter.
+func boolQuery(c *gin.Context, name string, defaultValue bool) (bool, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return defaultValue, true
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be true or false", name)})
+		return false, false
+	}
+	return value, true
+}
+
+func (s *Server) restartContainer(c *gin.Context) {
+	timeout, ok := s.stopTimeout(c)
+	if !ok {
+		return
+	}
+
+	if err := s.dockerClient.RestartContainer(c.Param("id"), timeout); err != nil {
+		c.JSON(containerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Container restarted successfully"})
+}
+
+func (s *Server) pauseContainer(c *gin.Context) {
+	if err := s.dockerClient.PauseContainer(c.Param("id")); err != nil {
+		c.JSON(containerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Container paused successfully"})
+}
+
+func (s *Server) unpauseContainer(c *gin.Context) {
+	if err := s.dockerClient.UnpauseContainer(c.Param("id")); err != nil {
+		c.JSON(containerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Container unpaused successfully"})
+}
+
+// signalPattern accepts signal names with or without the SIG prefix
+// ("HUP", "SIGUSR1", "SIGRTMIN+3") and signal numbers.
+var signalPattern = regexp.MustCompile(`^(SIG)?[A-Z][A-Z0-9]*([+-][0-9]+)?$|^[1-9][0-9]?$`)
+
+func parseSignal(raw string) (string, error) {
+	if raw == "" {
+		return "SIGKILL", nil
+	}
+	signal := strings.ToUpper(raw)
+	if !signalPattern.MatchString(signal) {
+		return "", fmt.Errorf("invalid signal %q", raw)
+	}
+	if n, err := strconv.Atoi(signal); err == nil && n > 64 {
+		return "", fmt.Errorf("invalid signal %q", raw)
+	}
+	return signal, nil
+}
+
+// killContainer sends ?signal= (default SIGKILL) to the container.
+func (s *Server) killContainer(c *gin.Context) {
+	signal, err := parseSignal(c.Query("signal"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.dockerClient.KillContainer(c.Param("id"), signal); err != nil {
+		c.JSON(containerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Sent %s to container", signal)})
+}
+
+type RenameContainerRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+func (s *Server) renameContainer(c *gin.Context) {
+	var req RenameContainerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if !isValidContainerName(req.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid container name format"})
+		return
+	}
+
+	if err := s.dockerClient.RenameContainer(c.Param("id"), req.Name); err != nil {
+		c.JSON(containerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"name": req.Name, "message": "Container renamed successfully"})
+}
+
+// UpdateContainerRequest changes resource limits of a container without
+// recreating it. Omitted fields are left unchanged.
+type UpdateContainerRequest struct {
+	CPUs              *float64 `json:"cpus"`
+	CPUShares         *int64   `json:"cpu_shares"`
+	Memory            *int64   `json:"memory"`
+	MemoryReservation *int64   `json:"memory_reservation"`
+	MemorySwap        *int64   `json:"memory_swap"`
+	PidsLimit         *int64   `json:"pids_limit"`
+	RestartPolicy     *string  `json:"restart_policy"`
+}
+
+// parseRestartPolicy parses "no", "always", "unless-stopped" or
+// "on-failure[:max-retries]".
+func parseRestartPolicy(raw string) (container.RestartPolicy, error) {
+	name, retries, hasRetries := strings.Cut(raw, ":")
+	policy := container.RestartPolicy{Name: container.RestartPolicyMode(name)}
+
+	switch policy.Name {
+	case container.RestartPolicyDisabled, container.RestartPolicyAlways, container.RestartPolicyUnlessStopped:
+		if hasRetries {
+			return policy, fmt.Errorf("restart policy %q does not take a retry count", name)
+		}
+	case container.RestartPolicyOnFailure:
+		if hasRetries {
+			n, err := strconv.Atoi(retries)
+			if err != nil || n < 0 {
+				return policy, fmt.Errorf("invalid retry count in restart policy %q", raw)
+			}
+			policy.MaximumRetryCount = n
+		}
+	default:
+		return policy, fmt.Errorf("unknown restart policy %q", raw)
+	}
+	return policy, nil
+}
+
+func (r UpdateContainerRequest) resourceUpdate() (docker.ResourceUpdate, error) {
+	update := docker.ResourceUpdate{
+		CPUShares:         r.CPUShares,
+		Memory:            r.Memory,
+		MemoryReservation: r.MemoryReservation,
+		MemorySwap:        r.MemorySwap,
+		PidsLimit:         r.PidsLimit,
+	}
+
+	if r.CPUs == nil && r.CPUShares == nil && r.Memory == nil && r.MemoryReservation == nil &&
+		r.MemorySwap == nil && r.PidsLimit == nil && r.RestartPolicy == nil {
+		return update, fmt.Errorf("no changes given")
+	}
+
+	if r.CPUs != nil {
+		if *r.CPUs < 0 {
+			return update, fmt.Errorf("cpus must not be negative")
+		}
+		nano := int64(math.Round(*r.CPUs * 1e9))
+		update.NanoCPUs = &nano
+	}
+	for name, value := range map[string]*int64{
+		"cpu_shares":         r.CPUShares,
+		"memory":             r.Memory,
+		"memory_reservation": r.MemoryReservation,
+	} {
+		if value != nil && *value < 0 {
+			return update, fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	// -1 means unlimited for swap and pids.
+	if r.MemorySwap != nil && *r.MemorySwap < -1 {
+		return update, fmt.Errorf("memory_swap must be -1 (unlimited) or more")
+	}
+	if r.PidsLimit != nil && *r.PidsLimit < -1 {
+		return update, fmt.Errorf("pids_limit must be -1 (unlimited) or more")
+	}
+
+	if r.RestartPolicy != nil {
+		policy, err := parseRestartPolicy(*r.RestartPolicy)
+		if err != nil {
+			return update, err
+		}
+		update.RestartPolicy = &policy
+	}
+	return update, nil
+}
+
+// updateContainer changes resource limits and the restart policy of a
+// container in place.
+func (s *Server) updateContainer(c *gin.Context) {
+	var req UpdateContainerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	update, err := req.resourceUpdate()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	warnings, err := s.dockerClient.UpdateContainer(c.Param("id"), update)
+	if err != nil {
+		c.JSON(containerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"warnings": warnings, "message": "Container updated successfully"})
+}
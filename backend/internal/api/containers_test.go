@@ -0,0 +1,39 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerLifecycleValidation(t *testing.T) {
+	for raw, want := range map[string]string{"": "SIGKILL", "hup": "HUP", "SIGUSR1": "SIGUSR1", "SIGRTMIN+3": "SIGRTMIN+3", "15": "15"} {
+		got, err := parseSignal(raw)
+		assert.NoError(t, err, raw)
+		assert.Equal(t, want, got, raw)
+	}
+	for _, raw := range []string{"SIG TERM", "0", "99", "-9", "TERM;ls"} {
+		_, err := parseSignal(raw)
+		assert.Error(t, err, raw)
+	}
+
+	policy, err := parseRestartPolicy("on-failure:5")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, policy.MaximumRetryCount)
+	for _, raw := range []string{"sometimes", "always:3", "on-failure:x"} {
+		_, err := parseRestartPolicy(raw)
+		assert.Error(t, err, raw)
+	}
+
+	cpus, memory, swap := 1.5, int64(512<<20), int64(-1)
+	update, err := UpdateContainerRequest{CPUs: &cpus, Memory: &memory, MemorySwap: &swap}.resourceUpdate()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1500000000), *update.NanoCPUs)
+	assert.Nil(t, update.RestartPolicy)
+
+	_, err = UpdateContainerRequest{}.resourceUpdate()
+	assert.Error(t, err, "an empty update is rejected")
+	negative := int64(-5)
+	_, err = UpdateContainerRequest{Memory: &negative}.resourceUpdate()
+	assert.Error(t, err)
+}
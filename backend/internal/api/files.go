@@ -0,0 +1,369 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"cyber-container-platform/internal/docker"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxListHeaders bounds how many entries a directory listing returns, or
+// archive entries it scans, before it reports a truncated result.
+const maxListHeaders = 20000
+
+var errPathTraversal = errors.New("path must not contain '..' segments")
+
+// cleanFilePath normalizes a user supplied path to an absolute, clean path.
+// ".." segments are rejected outright rather than resolved, so a request can
+// never address anything outside the volume root.
+func cleanFilePath(raw string) (string, error) {
+	if strings.ContainsRune(raw, 0) {
+		return "", fmt.Errorf("path contains a NUL byte")
+	}
+	for _, segment := range strings.Split(raw, "/") {
+		if segment == ".." {
+			return "", errPathTraversal
+		}
+	}
+	return path.Clean("/" + raw), nil
+}
+
+// validUploadName accepts plain file names only.
+func validUploadName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\\x00")
+}
+
+// fileTarget is the filesystem a file browser request operates on: either a
+// container, or a volume reached through a helper container.
+type fileTarget struct {
+	container string
+	volume    string
+	release   func()
+}
+
+// openFileTarget resolves the :id (container) or :name (volume) route
+// parameter. It writes the error response itself and returns nil on failure.
+func (s *Server) openFileTarget(c *gin.Context, readOnly bool) *fileTarget {
+	name := c.Param("name")
+	if name == "" {
+		return &fileTarget{container: c.Param("id"), release: func() {}}
+	}
+
+	if _, err := s.dockerClient.InspectVolume(name); err != nil {
+		c.JSON(fileErrorStatus(err), gin.H{"error": err.Error()})
+		return nil
+	}
+
+	helper, release, err := s.dockerClient.OpenVolume(name, s.config.BackupHelperImage, readOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil
+	}
+
+	return &fileTarget{container: helper, volume: name, release: release}
+}
+
+// containerPath maps a browser path to the path inside the target container.
+func (t *fileTarget) containerPath(p string) string {
+	if t.volume == "" {
+		return p
+	}
+	return path.Join(docker.VolumeMountPath, p)
+}
+
+// browserPath is the inverse of containerPath.
+func (t *fileTarget) browserPath(p string) string {
+	if t.volume == "" {
+		return p
+	}
+	return path.Clean("/" + strings.TrimPrefix(p, docker.VolumeMountPath))
+}
+
+func (t *fileTarget) entry(entry docker.FileEntry) docker.FileEntry {
+	entry.Path = t.browserPath(entry.Path)
+	if entry.Path == "/" {
+		entry.Name = "/"
+	}
+	return entry
+}
+
+func fileErrorStatus(err error) int {
+	if docker.IsNotFound(err) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// filePath reads and cleans the ?path= query parameter.
+func filePath(c *gin.Context) (string, bool) {
+	p, err := cleanFilePath(c.DefaultQuery("path", "/"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", false
+	}
+	return p, true
+}
+
+func (s *Server) listFiles(c *gin.Context) {
+	p, ok := filePath(c)
+	if !ok {
+		return
+	}
+
+	var (
+		target    *fileTarget
+		entries   []docker.FileEntry
+		truncated bool
+		err       error
+	)
+	if name := c.Param("name"); name != "" {
+		// A volume helper is never started, so ListDir would always take
+		// the archive fallback; list with a one-shot helper instead.
+		if _, err := s.dockerClient.InspectVolume(name); err != nil {
+			c.JSON(fileErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		target = &fileTarget{volume: name}
+		entries, truncated, err = s.dockerClient.ListVolumeDir(name, s.config.BackupHelperImage, target.containerPath(p), maxListHeaders)
+	} else {
+		target = &fileTarget{container: c.Param("id")}
+		entries, truncated, err = s.dockerClient.ListDir(target.container, p, maxListHeaders)
+	}
+	if err != nil {
+		c.JSON(fileErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	for i := range entries {
+		entries[i] = target.entry(entries[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"path": p, "files": entries, "truncated": truncated})
+}
+
+func (s *Server) statFile(c *gin.Context) {
+	p, ok := filePath(c)
+	if !ok {
+		return
+	}
+
+	target := s.openFileTarget(c, true)
+	if target == nil {
+		return
+	}
+	defer target.release()
+
+	entry, err := s.dockerClient.StatPath(target.container, target.containerPath(p))
+	if err != nil {
+		c.JSON(fileErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"file": target.entry(entry)})
+}
+
+// downloadFile streams a file's contents, or a directory as a tar archive.
+func (s *Server) downloadFile(c *gin.Context) {
+	p, ok := filePath(c)
+	if !ok {
+		return
+	}
+
+	target := s.openFileTarget(c, true)
+	if target == nil {
+		return
+	}
+	defer target.release()
+
+	reader, entry, err := s.dockerClient.OpenPath(target.container, target.containerPath(p))
+	if err != nil {
+		c.JSON(fileErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer reader.Close()
+
+	name := path.Base(p)
+	if p == "/" {
+		name = "root"
+		if target.volume != "" {
+			name = target.volume
+		}
+	}
+
+	switch entry.Type {
+	case docker.FileTypeFile:
+		if entry.Size > s.config.MaxDownloadBytes {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("File is %d bytes, the download limit is %d bytes", entry.Size, s.config.MaxDownloadBytes),
+			})
+			return
+		}
+		c.DataFromReader(http.StatusOK, entry.Size, "application/octet-stream", reader, map[string]string{
+			"Content-Disposition": fmt.Sprintf("attachment; filename=%q", name),
+		})
+	case docker.FileTypeDirectory:
+		// The archive size is unknown up front, so the limit is enforced
+		// while streaming.
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".tar"))
+		c.Header("Content-Type", "application/x-tar")
+		c.Status(http.StatusOK)
+		limited := &limitedReader{r: reader, n: s.config.MaxDownloadBytes}
+		if _, err := io.Copy(c.Writer, limited); err != nil {
+			s.logger.Error("Directory download aborted", err, map[string]interface{}{"path": p})
+			abortResponse(c)
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Cannot download a %s", entry.Type)})
+	}
+}
+
+// errDownloadTooLarge stops a directory download past the download limit.
+var errDownloadTooLarge = errors.New("download exceeds the size limit")
+
+// limitedReader fails with errDownloadTooLarge once more than n bytes are
+// read, unlike io.LimitReader, which ends the stream silently.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, errDownloadTooLarge
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n - int(-l.n), errDownloadTooLarge
+	}
+	return n, err
+}
+
+// abortResponse closes the connection of a response whose body is already
+// partly sent, so the client sees an incomplete transfer rather than a body
+// that merely ends early.
+func abortResponse(c *gin.Context) {
+	c.Writer.Flush()
+	if conn, _, err := c.Writer.Hijack(); err == nil {
+		conn.Close()
+	}
+	c.Abort()
+}
+
+// uploadFiles writes the multipart "file" fields into the directory given by
+// ?path=. Existing files of the same name are replaced.
+func (s *Server) uploadFiles(c *gin.Context) {
+	dir, ok := filePath(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, s.config.MaxUploadBytes)
+	form, err := c.MultipartForm()
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("Upload exceeds the limit of %d bytes", s.config.MaxUploadBytes),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer form.RemoveAll()
+
+	files := form.File["file"]
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No files in the \"file\" form field"})
+		return
+	}
+	for _, header := range files {
+		if !validUploadName(header.Filename) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid file name %q", header.Filename)})
+			return
+		}
+	}
+
+	target := s.openFileTarget(c, false)
+	if target == nil {
+		return
+	}
+	defer target.release()
+
+	stat, err := s.dockerClient.StatPath(target.container, target.containerPath(dir))
+	if err != nil {
+		c.JSON(fileErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if stat.Type != docker.FileTypeDirectory {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s is not a directory", dir)})
+		return
+	}
+
+	uploaded := make([]string, 0, len(files))
+	for _, header := range files {
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		err = s.dockerClient.WriteFile(target.container, target.containerPath(dir), header.Filename, file, header.Size)
+		file.Close()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "uploaded": uploaded})
+			return
+		}
+		uploaded = append(uploaded, path.Join(dir, header.Filename))
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"uploaded": uploaded, "message": "Files uploaded successfully"})
+}
+
+// deleteFile removes a file or directory tree. Container files are removed
+// with rm inside the (running) container; volume files through a helper.
+func (s *Server) deleteFile(c *gin.Context) {
+	p, ok := filePath(c)
+	if !ok {
+		return
+	}
+	if p == "/" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Refusing to delete the root directory"})
+		return
+	}
+
+	if name := c.Param("name"); name != "" {
+		if _, err := s.dockerClient.InspectVolume(name); err != nil {
+			c.JSON(fileErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		if err := s.dockerClient.RemoveVolumePath(name, s.config.BackupHelperImage, p); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "File deleted successfully"})
+		return
+	}
+
+	id := c.Param("id")
+	if _, err := s.dockerClient.StatPath(id, p); err != nil {
+		c.JSON(fileErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.dockerClient.RemovePath(id, p); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "File deleted successfully"})
+}
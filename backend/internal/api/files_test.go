@@ -0,0 +1,138 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectoryDownloadLimit(t *testing.T) {
+	archive := bytes.Repeat([]byte("x"), 64<<10)
+	client, _ := newFakeDocker(t, func(route string, w http.ResponseWriter, r *http.Request) bool {
+		if route != "GET /containers/web/archive" {
+			return false
+		}
+		stat := `{"name": "data", "size": 4096, "mode": 2147484141, "mtime": "2025-10-15T16:00:00Z"}`
+		w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString([]byte(stat)))
+		w.Header().Set("Content-Type", "application/x-tar")
+		w.Write(archive)
+		return true
+	})
+	server := newTestServerWith(t, client)
+	srv := httptest.NewServer(server.router)
+	defer srv.Close()
+
+	download := func() ([]byte, error) {
+		req, _ := http.NewRequest("GET", srv.URL+"/api/v1/containers/web/files/download?path=/data", nil)
+		req.Header.Set("Authorization", "Bearer "+testToken(t, server, "admin"))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		return io.ReadAll(resp.Body)
+	}
+
+	server.config.MaxDownloadBytes = int64(len(archive))
+	body, err := download()
+	assert.NoError(t, err)
+	assert.Equal(t, archive, body)
+
+	server.config.MaxDownloadBytes = 16 << 10
+	body, err = download()
+	assert.Error(t, err, "the transfer must fail, not end early")
+	assert.LessOrEqual(t, len(body), 16<<10)
+}
+
+func TestLimitedReader(t *testing.T) {
+	l := &limitedReader{r: bytes.NewReader([]byte("abcdef")), n: 4}
+	data, err := io.ReadAll(l)
+	assert.ErrorIs(t, err, errDownloadTooLarge)
+	assert.Equal(t, "abcd", string(data))
+
+	l = &limitedReader{r: bytes.NewReader([]byte("abcd")), n: 4}
+	data, err = io.ReadAll(l)
+	assert.NoError(t, err)
+	assert.Equal(t, "abcd", string(data))
+}
+
+func TestListVolumeFilesRunsHelper(t *testing.T) {
+	listing := "81a4 5 1700000001\x00app.db\x00\x00"
+	var helper struct {
+		Cmd        []string
+		HostConfig container.HostConfig
+	}
+	client, fake := newFakeDocker(t, func(route string, w http.ResponseWriter, r *http.Request) bool {
+		switch route {
+		case "GET /volumes/data":
+			io.WriteString(w, `{"Name": "data", "Driver": "local"}`)
+		case "GET /images/busybox:stable/json":
+			io.WriteString(w, `{"Id": "sha256:b0b0"}`)
+		case "POST /containers/create":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&helper))
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"Id": "h1", "Warnings": []}`)
+		case "POST /containers/h1/wait":
+			io.WriteString(w, `{"StatusCode": 0}`)
+		case "POST /containers/h1/start", "DELETE /containers/h1":
+			w.WriteHeader(http.StatusNoContent)
+		case "GET /containers/h1/logs":
+			// A stdout frame of the multiplexed log stream.
+			header := make([]byte, 8)
+			header[0] = 1
+			binary.BigEndian.PutUint32(header[4:], uint32(len(listing)))
+			w.Write(append(header, listing...))
+		default:
+			return false
+		}
+		return true
+	})
+	server := newTestServerWith(t, client)
+	server.config.BackupHelperImage = "busybox:stable"
+
+	w := serve(t, server, "GET", "/api/v1/volumes/data/files?path=/db", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"path":"/db/app.db"`)
+
+	require.Len(t, helper.Cmd, 6)
+	assert.Equal(t, []string{"/volume/db", "20000"}, helper.Cmd[4:])
+	require.Len(t, helper.HostConfig.Mounts, 1)
+	assert.Equal(t, mount.Mount{Type: mount.TypeVolume, Source: "data", Target: "/volume", ReadOnly: true}, helper.HostConfig.Mounts[0])
+	assert.False(t, fake.requested("GET /containers/h1/archive"), "the listing must not fall back to the archive")
+	assert.True(t, fake.requested("DELETE /containers/h1"), "the helper is removed")
+}
+
+func TestCleanFilePath(t *testing.T) {
+	for raw, want := range map[string]string{
+		"":               "/",
+		"/":              "/",
+		"etc/nginx/":     "/etc/nginx",
+		"/var//log/./x/": "/var/log/x",
+		"/..hidden":      "/..hidden",
+	} {
+		got, err := cleanFilePath(raw)
+		assert.NoError(t, err, raw)
+		assert.Equal(t, want, got, raw)
+	}
+
+	for _, raw := range []string{"..", "/../etc/passwd", "data/../../etc", "a\x00b"} {
+		_, err := cleanFilePath(raw)
+		assert.Error(t, err, raw)
+	}
+
+	assert.True(t, validUploadName("app.conf"))
+	assert.False(t, validUploadName("../app.conf"))
+	assert.False(t, validUploadName(".."))
+	assert.False(t, validUploadName(`dir\app.conf`))
+}
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateGroupRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type AddGroupMemberRequest struct {
+	Username string `json:"username" binding:"required"`
+}
+
+func (s *Server) listGroups(c *gin.Context) {
+	user := currentUser(c)
+
+	rows, err := s.db.GetDB().Query(
+		`SELECT g.id, g.name, g.created_by, g.created_at,
+			(SELECT COUNT(*) FROM group_members m WHERE m.group_id = g.id)
+		FROM user_groups g
+		WHERE ? OR g.id IN (SELECT group_id FROM group_members WHERE user_id = ?)
+		ORDER BY g.name`,
+		user.IsAdmin(), user.ID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var groups []map[string]interface{}
+	for rows.Next() {
+		var id, members int64
+		var name string
+		var createdBy sql.NullInt64
+		var createdAt time.Time
+
+		if err := rows.Scan(&id, &name, &createdBy, &createdAt, &members); err != nil {
+			continue
+		}
+
+		groups = append(groups, map[string]interface{}{
+			"id":         id,
+			"name":       name,
+			"created_by": createdBy.Int64,
+			"members":    members,
+			"created_at": createdAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"groups": groups})
+}
+
+func (s *Server) createGroup(c *gin.Context) {
+	var req CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Group name is required"})
+		return
+	}
+
+	user := currentUser(c)
+	result, err := s.db.GetDB().Exec("INSERT INTO user_groups (name, created_by) VALUES (?, ?)", req.Name, user.ownerID())
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group already exists"})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+
+	// The creator is the first member so that team templates work immediately.
+	if user.ID != 0 {
+		s.db.GetDB().Exec("INSERT INTO group_members (group_id, user_id) VALUES (?, ?)", id, user.ID)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": id, "message": "Group created successfully"})
+}
+
+// findManagedGroup loads the group in the :id parameter if the caller created
+// it or is an administrator, writing an error response otherwise. Creators
+// may remove members; only administrators add them.
+func (s *Server) findManagedGroup(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return 0, false
+	}
+
+	var createdBy sql.NullInt64
+	if err := s.db.GetDB().QueryRow("SELECT created_by FROM user_groups WHERE id = ?", id).Scan(&createdBy); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return 0, false
+	}
+
+	user := currentUser(c)
+	if !user.IsAdmin() && (!createdBy.Valid || createdBy.Int64 != user.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the group creator can manage its members"})
+		return 0, false
+	}
+
+	return id, true
+}
+
+func (s *Server) listGroupMembers(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	user := currentUser(c)
+	rows, err := s.db.GetDB().Query(
+		`SELECT u.id, u.username, m.created_at
+		FROM group_members m JOIN users u ON u.id = m.user_id
+		WHERE m.group_id = ? AND (? OR ? IN (SELECT user_id FROM group_members WHERE group_id = ?))
+		ORDER BY u.username`,
+		id, user.IsAdmin(), user.ID, id,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var members []map[string]interface{}
+	for rows.Next() {
+		var userID int64
+		var username string
+		var joinedAt time.Time
+
+		if err := rows.Scan(&userID, &username, &joinedAt); err != nil {
+			continue
+		}
+
+		members = append(members, map[string]interface{}{
+			"user_id":   userID,
+			"username":  username,
+			"joined_at": joinedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+// addGroupMember is admin-only: membership grants access to the team
+// templates of every other member, so users cannot enrol each other.
+func (s *Server) addGroupMember(c *gin.Context) {
+	groupID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+	var exists bool
+	if err := s.db.GetDB().QueryRow("SELECT EXISTS(SELECT 1 FROM user_groups WHERE id = ?)", groupID).Scan(&exists); err != nil || !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+
+	var req AddGroupMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var userID int64
+	if err := s.db.GetDB().QueryRow("SELECT id FROM users WHERE username = ?", req.Username).Scan(&userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	_, err = s.db.GetDB().Exec("INSERT OR IGNORE INTO group_members (group_id, user_id) VALUES (?, ?)", groupID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member added successfully"})
+}
+
+func (s *Server) removeGroupMember(c *gin.Context) {
+	groupID, ok := s.findManagedGroup(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	_, err = s.db.GetDB().Exec("DELETE FROM group_members WHERE group_id = ? AND user_id = ?", groupID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
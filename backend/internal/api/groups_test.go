@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnlyAdminsAddGroupMembers(t *testing.T) {
+	server := newTestServer(t)
+	registerUser(t, server, "mallory")
+	victim := registerUser(t, server, "alice")
+
+	w := serveAs(t, server, "alice", "POST", "/api/v1/templates", map[string]interface{}{
+		"name": "internal", "image": "nginx:1.25", "config": "{}", "visibility": "team",
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = serveAs(t, server, "mallory", "POST", "/api/v1/groups", map[string]string{"name": "trap"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var group struct{ ID int64 }
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &group))
+	members := fmt.Sprintf("/api/v1/groups/%d/members", group.ID)
+
+	w = serveAs(t, server, "mallory", "POST", members, map[string]string{"username": "alice"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = serveAs(t, server, "mallory", "GET", "/api/v1/templates", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), `"internal"`)
+
+	w = serve(t, server, "POST", members, map[string]string{"username": "alice"})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = serve(t, server, "POST", "/api/v1/groups/999/members", map[string]string{"username": "alice"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The creator can still remove members.
+	w = serveAs(t, server, "mallory", "DELETE", fmt.Sprintf("%s/%d", members, victim), nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
@@ -1,15 +1,18 @@
 package api
 
 import (
+	"database/sql"
 	"fmt"
 	"io"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
+	"cyber-container-platform/internal/database"
+	"cyber-container-platform/internal/docker"
+	"cyber-container-platform/internal/templates"
+
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/go-connections/nat"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
@@ -36,20 +39,38 @@ type CreateContainerRequest struct {
 }
 
 type CreateNetworkRequest struct {
-	Name   string `json:"name" binding:"required"`
-	Driver string `json:"driver"`
+	Name        string            `json:"name" binding:"required"`
+	Driver      string            `json:"driver"`
+	Subnet      string            `json:"subnet"`
+	Gateway     string            `json:"gateway"`
+	IPRange     string            `json:"ip_range"`
+	IPAMDriver  string            `json:"ipam_driver"`
+	Internal    bool              `json:"internal"`
+	Attachable  bool              `json:"attachable"`
+	EnableIPv6  bool              `json:"enable_ipv6"`
+	IPv6Subnet  string            `json:"ipv6_subnet"`
+	IPv6Gateway string            `json:"ipv6_gateway"`
+	Labels      map[string]string `json:"labels"`
+	Options     map[string]string `json:"options"`
 }
 
 type CreateVolumeRequest struct {
-	Name   string `json:"name" binding:"required"`
-	Driver string `json:"driver"`
+	Name    string            `json:"name" binding:"required"`
+	Driver  string            `json:"driver"`
+	Options map[string]string `json:"options"`
+	Labels  map[string]string `json:"labels"`
 }
 
 type CreateTemplateRequest struct {
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description"`
-	Image       string `json:"image" binding:"required"`
-	Config      string `json:"config" binding:"required"`
+	Name        string                 `json:"name" binding:"required"`
+	Description string                 `json:"description"`
+	Image       string                 `json:"image" binding:"required"`
+	Config      string                 `json:"config" binding:"required"`
+	Version     string                 `json:"version"`
+	Icon        string                 `json:"icon"`
+	Schema      map[string]interface{} `json:"schema"`
+	Parameters  []templates.Parameter  `json:"parameters"`
+	Visibility  string                 `json:"visibility"`
 }
 
 func (s *Server) login(c *gin.Context) {
@@ -59,8 +80,11 @@ func (s *Server) login(c *gin.Context) {
 		return
 	}
 
-	// Simple authentication - in production, use proper user management
-	if req.Username == "admin" && req.Password == "admin" {
+	var passwordHash string
+	err := s.db.GetDB().QueryRow("SELECT password_hash FROM users WHERE username = ?", req.Username).Scan(&passwordHash)
+	authenticated := err == nil && bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password)) == nil
+
+	if authenticated {
 		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 			"username": req.Username,
 			"exp":      time.Now().Add(time.Hour * 24).Unix(),
@@ -86,6 +110,12 @@ func (s *Server) register(c *gin.Context) {
 		return
 	}
 
+	// The built-in administrator's name is taken in any letter case
+	if strings.EqualFold(strings.TrimSpace(req.Username), database.AdminUsername) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Username is reserved"})
+		return
+	}
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -110,16 +140,6 @@ func (s *Server) logout(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
 
-func (s *Server) listContainers(c *gin.Context) {
-	containers, err := s.dockerClient.ListContainers()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"containers": containers})
-}
-
 func (s *Server) createContainer(c *gin.Context) {
 	var req CreateContainerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -168,22 +188,6 @@ func (s *Server) createContainer(c *gin.Context) {
 	// Create host config
 	hostConfig := &container.HostConfig{}
 
-	// Add port bindings
-	if len(req.Ports) > 0 {
-		portBindings := make(nat.PortMap)
-		exposedPorts := make(nat.PortSet)
-
-		for hostPort, containerPort := range req.Ports {
-			portBindings[nat.Port(containerPort+"/tcp")] = []nat.PortBinding{
-				{HostPort: hostPort},
-			}
-			exposedPorts[nat.Port(containerPort+"/tcp")] = struct{}{}
-		}
-
-		hostConfig.PortBindings = portBindings
-		config.ExposedPorts = exposedPorts
-	}
-
 	// Add volume mounts
 	if len(req.Volumes) > 0 {
 		var binds []string
@@ -193,6 +197,26 @@ func (s *Server) createContainer(c *gin.Context) {
 		hostConfig.Binds = binds
 	}
 
+	if err := s.checkDeployPolicies(req.Image); err != nil {
+		respondImageCheckError(c, err, "")
+		return
+	}
+
+	// Hold the port lock until the container has bound its ports, so that
+	// concurrent requests cannot be given the same ones.
+	s.portMu.Lock()
+	defer s.portMu.Unlock()
+
+	portBindings, exposedPorts, err := s.resolvePortBindings(req.Ports)
+	if err != nil {
+		respondPortError(c, err)
+		return
+	}
+	if len(portBindings) > 0 {
+		hostConfig.PortBindings = portBindings
+		config.ExposedPorts = exposedPorts
+	}
+
 	containerID, err := s.dockerClient.CreateContainer(config, hostConfig, nil, req.Name)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -201,12 +225,23 @@ func (s *Server) createContainer(c *gin.Context) {
 
 	// Start the container after creation
 	err = s.dockerClient.StartContainer(containerID)
+	if docker.IsPortInUse(err) {
+		// Taken outside what the checks can see; do not leave the
+		// container behind so the request can be retried as is.
+		s.dockerClient.RemoveContainer(containerID, true, false)
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Host port already in use: %v", err)})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Container created but failed to start: %v", err)})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"id": containerID, "message": "Container created and started successfully"})
+	c.JSON(http.StatusCreated, gin.H{
+		"id":      containerID,
+		"ports":   publishedPorts(portBindings),
+		"message": "Container created and started successfully",
+	})
 }
 
 func (s *Server) getContainer(c *gin.Context) {
@@ -219,6 +254,11 @@ func (s *Server) getContainer(c *gin.Context) {
 
 	for _, container := range containers {
 		if container.ID == id {
+			if container.Health != nil {
+				if health, err := s.dockerClient.ContainerHealth(id); err == nil && health != nil {
+					container.Health = health
+				}
+			}
 			c.JSON(http.StatusOK, gin.H{"container": container})
 			return
 		}
@@ -240,20 +280,36 @@ func (s *Server) startContainer(c *gin.Context) {
 
 func (s *Server) stopContainer(c *gin.Context) {
 	id := c.Param("id")
-	err := s.dockerClient.StopContainer(id)
+	timeout, ok := s.stopTimeout(c)
+	if !ok {
+		return
+	}
+
+	err := s.dockerClient.StopContainer(id, timeout)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(containerErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Container stopped successfully"})
 }
 
+// removeContainer removes a container. Running containers are removed too
+// unless ?force=false; ?volumes=true also removes its anonymous volumes.
 func (s *Server) removeContainer(c *gin.Context) {
 	id := c.Param("id")
-	err := s.dockerClient.RemoveContainer(id)
+	force, ok := boolQuery(c, "force", true)
+	if !ok {
+		return
+	}
+	removeVolumes, ok := boolQuery(c, "volumes", false)
+	if !ok {
+		return
+	}
+
+	err := s.dockerClient.RemoveContainer(id, force, removeVolumes)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(containerErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -342,31 +398,43 @@ func (s *Server) createNetwork(c *gin.Context) {
 		req.Driver = "bridge"
 	}
 
-	networkID, err := s.dockerClient.CreateNetwork(req.Name, req.Driver)
+	opts := req.networkOptions()
+	if err := validateNetworkOptions(opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	networkID, err := s.dockerClient.CreateNetwork(opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if err := s.saveNetworkConfig(opts, currentUser(c)); err != nil {
+		s.logger.Error("Failed to persist network config", err, map[string]interface{}{"network": opts.Name})
+	}
+
 	c.JSON(http.StatusCreated, gin.H{"id": networkID, "message": "Network created successfully"})
 }
 
 func (s *Server) getNetwork(c *gin.Context) {
 	id := c.Param("id")
-	networks, err := s.dockerClient.ListNetworks()
+	network, err := s.dockerClient.InspectNetwork(id)
 	if err != nil {
+		if docker.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Network not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	for _, network := range networks {
-		if network.ID == id {
-			c.JSON(http.StatusOK, gin.H{"network": network})
-			return
-		}
+	response := gin.H{"network": network}
+	if config, err := s.findNetworkConfig(network.Name); err == nil {
+		response["desired_config"] = config
 	}
 
-	c.JSON(http.StatusNotFound, gin.H{"error": "Network not found"})
+	c.JSON(http.StatusOK, response)
 }
 
 func (s *Server) removeNetwork(c *gin.Context) {
@@ -402,31 +470,61 @@ func (s *Server) createVolume(c *gin.Context) {
 		return
 	}
 
-	vol, err := s.dockerClient.CreateVolume(req.Name)
+	if req.Driver == "" {
+		req.Driver = "local"
+	}
+
+	opts := req.volumeOptions()
+	if err := validateVolumeOptions(opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	vol, err := s.dockerClient.CreateVolume(opts)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	if err := s.saveVolumeConfig(opts, currentUser(c)); err != nil {
+		s.logger.Error("Failed to persist volume config", err, map[string]interface{}{"volume": opts.Name})
+	}
+
 	c.JSON(http.StatusCreated, gin.H{"volume": vol, "message": "Volume created successfully"})
 }
 
 func (s *Server) getVolume(c *gin.Context) {
 	name := c.Param("name")
-	volumes, err := s.dockerClient.ListVolumes()
+	volume, err := s.dockerClient.GetVolume(name)
 	if err != nil {
+		if docker.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Volume not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	for _, volume := range volumes {
-		if volume.Name == name {
-			c.JSON(http.StatusOK, gin.H{"volume": volume})
-			return
-		}
+	response := gin.H{"volume": volume}
+
+	if usage, err := s.dockerClient.VolumeUsage(name); err == nil {
+		response["usage"] = usage
+	} else {
+		s.logger.Warn("Failed to read volume usage", map[string]interface{}{"volume": name, "error": err.Error()})
 	}
 
-	c.JSON(http.StatusNotFound, gin.H{"error": "Volume not found"})
+	users, err := s.volumeUsers(name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	response["containers"] = users
+
+	if config, err := s.findVolumeConfig(name); err == nil {
+		response["desired_config"] = config
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 func (s *Server) removeVolume(c *gin.Context) {
@@ -446,7 +544,10 @@ func (s *Server) removeVolume(c *gin.Context) {
 }
 
 func (s *Server) listTemplates(c *gin.Context) {
-	rows, err := s.db.GetDB().Query("SELECT id, name, description, image, config, created_at FROM container_templates ORDER BY created_at DESC")
+	rows, err := s.db.GetDB().Query(
+		"SELECT "+templateColumns+" FROM container_templates WHERE "+visibleTemplatesClause+" ORDER BY created_at DESC",
+		visibleTemplatesArgs(currentUser(c))...,
+	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -455,23 +556,12 @@ func (s *Server) listTemplates(c *gin.Context) {
 
 	var templates []map[string]interface{}
 	for rows.Next() {
-		var id int
-		var name, description, image, config string
-		var createdAt time.Time
-
-		err := rows.Scan(&id, &name, &description, &image, &config, &createdAt)
+		record, err := scanTemplate(rows)
 		if err != nil {
 			continue
 		}
 
-		templates = append(templates, map[string]interface{}{
-			"id":          id,
-			"name":        name,
-			"description": description,
-			"image":       image,
-			"config":      config,
-			"created_at":  createdAt,
-		})
+		templates = append(templates, record.toMap())
 	}
 
 	c.JSON(http.StatusOK, gin.H{"templates": templates})
@@ -484,9 +574,21 @@ func (s *Server) createTemplate(c *gin.Context) {
 		return
 	}
 
+	if req.Version == "" {
+		req.Version = "1.0.0"
+	}
+	if req.Visibility == "" {
+		req.Visibility = visibilityPrivate
+	}
+	if !isValidVisibility(req.Visibility) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Visibility must be private, team or public"})
+		return
+	}
+	schema, parameters := encodeTemplateExtras(req.Schema, req.Parameters)
+
 	result, err := s.db.GetDB().Exec(
-		"INSERT INTO container_templates (name, description, image, config) VALUES (?, ?, ?, ?)",
-		req.Name, req.Description, req.Image, req.Config,
+		"INSERT INTO container_templates (name, description, image, config, version, icon, schema, parameters, visibility, created_by) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		req.Name, req.Description, req.Image, req.Config, req.Version, req.Icon, schema, parameters, req.Visibility, currentUser(c).ownerID(),
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -498,41 +600,21 @@ func (s *Server) createTemplate(c *gin.Context) {
 }
 
 func (s *Server) getTemplate(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+	record := s.findVisibleTemplate(c)
+	if record == nil {
 		return
 	}
 
-	var name, description, image, config string
-	var createdAt time.Time
-
-	err = s.db.GetDB().QueryRow(
-		"SELECT name, description, image, config, created_at FROM container_templates WHERE id = ?",
-		id,
-	).Scan(&name, &description, &image, &config, &createdAt)
-
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"template": map[string]interface{}{
-			"id":          id,
-			"name":        name,
-			"description": description,
-			"image":       image,
-			"config":      config,
-			"created_at":  createdAt,
-		},
-	})
+	c.JSON(http.StatusOK, gin.H{"template": record.toMap()})
 }
 
 func (s *Server) updateTemplate(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+	record := s.findVisibleTemplate(c)
+	if record == nil {
+		return
+	}
+	if !record.canModify(currentUser(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the template owner can update it"})
 		return
 	}
 
@@ -542,9 +624,21 @@ func (s *Server) updateTemplate(c *gin.Context) {
 		return
 	}
 
-	_, err = s.db.GetDB().Exec(
-		"UPDATE container_templates SET name = ?, description = ?, image = ?, config = ? WHERE id = ?",
-		req.Name, req.Description, req.Image, req.Config, id,
+	if req.Version == "" {
+		req.Version = "1.0.0"
+	}
+	if req.Visibility == "" {
+		req.Visibility = record.Visibility
+	}
+	if !isValidVisibility(req.Visibility) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Visibility must be private, team or public"})
+		return
+	}
+	schema, parameters := encodeTemplateExtras(req.Schema, req.Parameters)
+
+	_, err := s.db.GetDB().Exec(
+		"UPDATE container_templates SET name = ?, description = ?, image = ?, config = ?, version = ?, icon = ?, schema = ?, parameters = ?, visibility = ? WHERE id = ?",
+		req.Name, req.Description, req.Image, req.Config, req.Version, req.Icon, schema, parameters, req.Visibility, record.ID,
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -555,17 +649,34 @@ func (s *Server) updateTemplate(c *gin.Context) {
 }
 
 func (s *Server) deleteTemplate(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+	record := s.findVisibleTemplate(c)
+	if record == nil {
+		return
+	}
+	if !record.canModify(currentUser(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the template owner can delete it"})
 		return
 	}
 
-	_, err = s.db.GetDB().Exec("DELETE FROM container_templates WHERE id = ?", id)
+	tx, err := s.db.GetDB().Begin()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM template_shares WHERE template_id = ?", record.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := tx.Exec("DELETE FROM container_templates WHERE id = ?", record.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Template deleted successfully"})
 }
@@ -616,16 +727,12 @@ func (s *Server) pullImage(c *gin.Context) {
 		return
 	}
 	
-	reader, err := s.dockerClient.PullImage(req.Image)
+	progress, err := s.pullAndCheck(req.Image)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondImageCheckError(c, err, progress)
 		return
 	}
-	defer reader.Close()
-	
-	// Read the pull progress
-	progress, _ := io.ReadAll(reader)
-	
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Image pulled successfully",
 		"progress": string(progress),
@@ -709,6 +816,46 @@ func sanitizeString(input string) string {
 	return input
 }
 
+// authUser is the authenticated caller, resolved from the JWT username.
+type authUser struct {
+	ID       int64
+	Username string
+	Role     string
+}
+
+func (u authUser) IsAdmin() bool {
+	return u.Role == "admin"
+}
+
+// ownerID returns the value stored in created_by columns. A caller without a
+// users row, such as a deleted user whose token has not expired, records its
+// resources without an owner.
+func (u authUser) ownerID() interface{} {
+	if u.ID == 0 {
+		return nil
+	}
+	return u.ID
+}
+
+// currentUser returns the caller set by authMiddleware.
+func currentUser(c *gin.Context) authUser {
+	if user, ok := c.Get("user"); ok {
+		return user.(authUser)
+	}
+	return authUser{}
+}
+
+// lookupUser resolves a username to its users row; the role is the one
+// stored there.
+func (s *Server) lookupUser(username string) authUser {
+	user := authUser{Username: username, Role: "user"}
+	var role sql.NullString
+	if err := s.db.GetDB().QueryRow("SELECT id, role FROM users WHERE username = ?", username).Scan(&user.ID, &role); err == nil && role.Valid {
+		user.Role = role.String
+	}
+	return user
+}
+
 func (s *Server) authMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
@@ -732,6 +879,24 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		claims, _ := token.Claims.(jwt.MapClaims)
+		username, _ := claims["username"].(string)
+		c.Set("user", s.lookupUser(username))
+
+		c.Next()
+	}
+}
+
+// requireAdmin rejects callers that are not administrators. It must run after
+// authMiddleware.
+func (s *Server) requireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !currentUser(c).IsAdmin() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Administrator access required"})
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -3,31 +3,175 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"sync"
 	"testing"
+	"time"
+
+	"cyber-container-platform/internal/config"
+	"cyber-container-platform/internal/database"
+	"cyber-container-platform/internal/docker"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestHealthEndpoint(t *testing.T) {
+// newTestServer returns a server with a fresh database and no Docker
+// client, for handlers that answer before they reach Docker.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	return newTestServerWith(t, nil)
+}
+
+// newTestServerWith returns a server with a fresh database that talks to
+// dockerClient, usually one of newFakeDocker.
+func newTestServerWith(t *testing.T, dockerClient *docker.Client) *Server {
+	t.Helper()
 	gin.SetMode(gin.TestMode)
-	
-	// Create a test server
-	server := &Server{}
-	server.setupRouter()
-	
+
+	dir := t.TempDir()
+	db, err := database.Init(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	cfg := &config.Config{
+		JWTSecret:         "test-secret",
+		BackupDir:         filepath.Join(dir, "backups"),
+		VulnDBPath:        filepath.Join(dir, "vulndb.json"),
+		MaxImageLoadBytes: 1 << 20,
+	}
+	return NewServer(cfg, db, dockerClient, nil)
+}
+
+var apiVersionPrefix = regexp.MustCompile(`^/v[0-9.]+/`)
+
+// fakeDocker is a Docker API server for handler tests. Requests are passed
+// to handle as "METHOD /path", without the API version; it answers those it
+// knows and returns false for the rest, which get a 404.
+type fakeDocker struct {
+	handle func(route string, w http.ResponseWriter, r *http.Request) bool
+
+	mu     sync.Mutex
+	routes []string
+}
+
+func newFakeDocker(t *testing.T, handle func(route string, w http.ResponseWriter, r *http.Request) bool) (*docker.Client, *fakeDocker) {
+	t.Helper()
+	fake := &fakeDocker{handle: handle}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	t.Setenv("DOCKER_HOST", "tcp://"+srv.Listener.Addr().String())
+	client, err := docker.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, fake
+}
+
+func (f *fakeDocker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/_ping" {
+		w.Header().Set("API-Version", "1.44")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	route := r.Method + " " + apiVersionPrefix.ReplaceAllString(r.URL.Path, "/")
+	f.mu.Lock()
+	f.routes = append(f.routes, route)
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !f.handle(route, w, r) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"message": "no such object: %s"}`, r.URL.Path)
+	}
+}
+
+// requested reports whether a request for route was received.
+func (f *fakeDocker) requested(route string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range f.routes {
+		if r == route {
+			return true
+		}
+	}
+	return false
+}
+
+// serve sends a JSON request as the built-in admin and returns the
+// recorded response.
+func serve(t *testing.T, s *Server, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	return serveAs(t, s, "admin", method, path, body)
+}
+
+// serveAs sends a JSON request as username.
+func serveAs(t *testing.T, s *Server, username, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+testToken(t, s, username))
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// registerUser creates a user with the default role and returns its ID.
+func registerUser(t *testing.T, s *Server, username string) int64 {
+	t.Helper()
+	w := serve(t, s, "POST", "/api/v1/auth/register", map[string]string{
+		"username": username, "password": "secret", "email": username + "@example.com",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register %s: %d %s", username, w.Code, w.Body.String())
+	}
+	var id int64
+	if err := s.db.GetDB().QueryRow("SELECT id FROM users WHERE username = ?", username).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func testToken(t *testing.T, s *Server, username string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"username": username,
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(s.config.JWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestHealthEndpoint(t *testing.T) {
+	server := newTestServer(t)
+
 	// Create a request to the health endpoint
 	req, _ := http.NewRequest("GET", "/health", nil)
 	w := httptest.NewRecorder()
-	
+
 	// Perform the request
 	server.router.ServeHTTP(w, req)
-	
+
 	// Assert the response
 	assert.Equal(t, http.StatusOK, w.Code)
-	
+
 	var response map[string]interface{}
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
@@ -35,26 +179,21 @@ func TestHealthEndpoint(t *testing.T) {
 }
 
 func TestCreateContainerValidation(t *testing.T) {
-	gin.SetMode(gin.TestMode)
-	
-	server := &Server{}
-	server.setupRouter()
-	
+	server := newTestServer(t)
+
 	// Test with empty container name
-	reqBody := map[string]interface{}{
+	w := serve(t, server, "POST", "/api/v1/containers", map[string]interface{}{
 		"name":  "",
 		"image": "nginx:alpine",
-	}
-	
-	jsonBody, _ := json.Marshal(reqBody)
-	req, _ := http.NewRequest("POST", "/api/v1/containers", bytes.NewBuffer(jsonBody))
-	req.Header.Set("Content-Type", "application/json")
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Without a valid token the request does not reach the handler
+	req, _ := http.NewRequest("POST", "/api/v1/containers", bytes.NewBufferString(`{}`))
 	req.Header.Set("Authorization", "Bearer test-token")
-	
-	w := httptest.NewRecorder()
+	w = httptest.NewRecorder()
 	server.router.ServeHTTP(w, req)
-	
-	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
 }
 
 func TestValidationHelpers(t *testing.T) {
@@ -80,3 +219,80 @@ func TestValidationHelpers(t *testing.T) {
 	assert.Equal(t, "clean", sanitizeString("clean\r\n\t"))
 	assert.Equal(t, "clean", sanitizeString("clean\x00"))
 }
+
+func TestNetworkValidation(t *testing.T) {
+	validate := func(req CreateNetworkRequest) error {
+		return validateNetworkOptions(req.networkOptions())
+	}
+
+	assert.NoError(t, validate(CreateNetworkRequest{Name: "backend"}))
+	assert.NoError(t, validate(CreateNetworkRequest{Name: "backend", Subnet: "10.10.0.0/16", Gateway: "10.10.0.1", IPRange: "10.10.5.0/24"}))
+	assert.NoError(t, validate(CreateNetworkRequest{Name: "backend", EnableIPv6: true, IPv6Subnet: "fd00:10::/64", IPv6Gateway: "fd00:10::1"}))
+
+	assert.Error(t, validate(CreateNetworkRequest{Name: "-backend"}))
+	assert.Error(t, validate(CreateNetworkRequest{Name: "backend", Gateway: "10.10.0.1"}))
+	assert.Error(t, validate(CreateNetworkRequest{Name: "backend", Subnet: "10.10.0.0"}))
+	assert.Error(t, validate(CreateNetworkRequest{Name: "backend", Subnet: "10.10.0.0/16", Gateway: "10.20.0.1"}))
+	assert.Error(t, validate(CreateNetworkRequest{Name: "backend", Subnet: "10.10.0.0/16", IPRange: "10.0.0.0/8"}))
+	assert.Error(t, validate(CreateNetworkRequest{Name: "backend", IPv6Subnet: "fd00:10::/64"}))
+	assert.Error(t, validate(CreateNetworkRequest{Name: "backend", EnableIPv6: true, IPv6Subnet: "10.10.0.0/16"}))
+}
+
+func TestVolumeValidation(t *testing.T) {
+	validate := func(req CreateVolumeRequest) error {
+		return validateVolumeOptions(req.volumeOptions())
+	}
+
+	assert.NoError(t, validate(CreateVolumeRequest{Name: "pgdata", Driver: "local"}))
+	assert.NoError(t, validate(CreateVolumeRequest{Name: "nfs-share", Driver: "local", Options: map[string]string{"type": "nfs", "device": ":/exports"}, Labels: map[string]string{"team": "db"}}))
+
+	assert.Error(t, validate(CreateVolumeRequest{Name: "-pgdata"}))
+	assert.Error(t, validate(CreateVolumeRequest{Name: "pg/data"}))
+	assert.Error(t, validate(CreateVolumeRequest{Name: "pgdata", Labels: map[string]string{"": "x"}}))
+}
+
+func TestAdminRoleComesFromUsersTable(t *testing.T) {
+	server := newTestServer(t)
+
+	for _, name := range []string{"admin", "Admin", " ADMIN "} {
+		w := serve(t, server, "POST", "/api/v1/auth/register", map[string]string{
+			"username": name, "password": "secret", "email": "x@example.com",
+		})
+		assert.Equal(t, http.StatusConflict, w.Code, name)
+	}
+
+	assert.True(t, server.lookupUser("admin").IsAdmin())
+	assert.False(t, server.lookupUser("nobody").IsAdmin())
+
+	password := server.db.InitialAdminPassword()
+	require.NotEmpty(t, password)
+	assert.NotEqual(t, "admin", password)
+	w := serve(t, server, "POST", "/api/v1/auth/login", map[string]string{"username": "admin", "password": password})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serve(t, server, "POST", "/api/v1/auth/login", map[string]string{"username": "admin", "password": "admin"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAdminSeededWhenNoUserIsAdmin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := database.Init(path)
+	require.NoError(t, err)
+	// A regular user registered the name before it was reserved.
+	_, err = db.GetDB().Exec("UPDATE users SET role = 'user' WHERE username = ?", database.AdminUsername)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	db, err = database.Init(path)
+	require.NoError(t, err)
+	var role string
+	require.NoError(t, db.GetDB().QueryRow("SELECT role FROM users WHERE username = ?", database.AdminUsername).Scan(&role))
+	assert.Equal(t, "admin", role)
+	assert.NotEmpty(t, db.InitialAdminPassword())
+
+	// Once an administrator exists, nothing is reset.
+	require.NoError(t, db.Close())
+	db, err = database.Init(path)
+	require.NoError(t, err)
+	defer db.Close()
+	assert.Empty(t, db.InitialAdminPassword())
+}
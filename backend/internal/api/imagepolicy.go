@@ -0,0 +1,152 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"cyber-container-platform/internal/docker"
+	"cyber-container-platform/internal/imagepolicy"
+
+	"github.com/gin-gonic/gin"
+)
+
+const imagePolicySetting = "image_policy"
+
+func (s *Server) loadImagePolicy() (imagepolicy.Policy, error) {
+	var policy imagepolicy.Policy
+	value, err := s.db.GetSetting(imagePolicySetting)
+	if err != nil {
+		return policy, err
+	}
+	if value != "" {
+		if err := json.Unmarshal([]byte(value), &policy); err != nil {
+			return policy, fmt.Errorf("corrupt image policy: %w", err)
+		}
+	}
+	return policy, policy.Validate()
+}
+
+// checkImagePolicy evaluates the image policy for ref. detail is the local
+// image, or nil before a pull; label rules can only be checked once the
+// image is present, and the signature is then checked for the exact digest
+// that was pulled.
+func (s *Server) checkImagePolicy(ref string, detail *docker.ImageDetail) error {
+	policy, err := s.loadImagePolicy()
+	if err != nil {
+		return err
+	}
+
+	if err := policy.CheckReference(ref); err != nil {
+		return err
+	}
+	if detail != nil {
+		if err := policy.CheckOrigin(ref, detail.RepoDigests); err != nil {
+			return err
+		}
+		if err := policy.CheckLabels(ref, detail.Config.Labels); err != nil {
+			return err
+		}
+	}
+	if !policy.RequireSignature {
+		return nil
+	}
+
+	auth, err := s.registries.AuthFor(ref)
+	if err != nil {
+		return err
+	}
+
+	var digest string
+	if detail != nil {
+		digest = imagepolicy.Digest(ref, detail.RepoDigests)
+	} else if digest = imagepolicy.Digest(ref, nil); digest == "" {
+		if digest, err = s.dockerClient.RemoteDigest(ref, auth); err != nil {
+			return &imagepolicy.Violation{
+				Rule:   imagepolicy.RuleRequireSignature,
+				Image:  ref,
+				Detail: fmt.Sprintf("cannot resolve the image digest: %v", err),
+			}
+		}
+	}
+	if digest == "" {
+		return &imagepolicy.Violation{
+			Rule:   imagepolicy.RuleRequireSignature,
+			Image:  ref,
+			Detail: "the image has no registry digest to verify, it was not pulled from a registry",
+		}
+	}
+
+	signature, err := imagepolicy.SignatureRef(ref, digest)
+	if err != nil {
+		return err
+	}
+	if _, err := s.dockerClient.RemoteDigest(signature, auth); err != nil {
+		return &imagepolicy.Violation{
+			Rule:   imagepolicy.RuleRequireSignature,
+			Image:  ref,
+			Detail: fmt.Sprintf("no signature found for %s: %v", digest, err),
+		}
+	}
+	return nil
+}
+
+// checkReferencePolicy evaluates the reference rules for a name an image is
+// about to get by tagging, building or committing, so that local images
+// cannot be named around the denied tags and registries.
+func (s *Server) checkReferencePolicy(ref string) error {
+	policy, err := s.loadImagePolicy()
+	if err != nil {
+		return err
+	}
+	return policy.CheckReference(ref)
+}
+
+// respondImagePolicyError writes the response for a checkImagePolicy error.
+func respondImagePolicyError(c *gin.Context, err error) {
+	var violation *imagepolicy.Violation
+	switch {
+	case errors.As(err, &violation):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "violation": violation})
+	case errors.Is(err, imagepolicy.ErrInvalidReference):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (s *Server) getImagePolicy(c *gin.Context) {
+	policy, err := s.loadImagePolicy()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policy": policy})
+}
+
+func (s *Server) updateImagePolicy(c *gin.Context) {
+	var policy imagepolicy.Policy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := policy.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	encoded, err := json.Marshal(policy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.db.SetSetting(imagePolicySetting, string(encoded)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.logger.Info("Image policy updated", map[string]interface{}{"user": currentUser(c).Username})
+	c.JSON(http.StatusOK, gin.H{"policy": policy, "message": "Image policy updated successfully"})
+}
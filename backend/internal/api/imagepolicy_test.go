@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTemplateDeployChecksImageOrigin deploys a template the way the
+// dashboard does, through POST /containers, after its image was given an
+// allowed name locally.
+func TestTemplateDeployChecksImageOrigin(t *testing.T) {
+	const image = "ghcr.io/acme/app:1.0"
+	for _, tt := range []struct {
+		name        string
+		repoDigests string
+		status      int
+	}{
+		{"built locally", `[]`, http.StatusForbidden},
+		{"pulled from the registry", `["ghcr.io/acme/app@sha256:2222222222222222222222222222222222222222222222222222222222222222"]`, http.StatusCreated},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			client, fake := newFakeDocker(t, func(route string, w http.ResponseWriter, r *http.Request) bool {
+				switch route {
+				case "GET /images/" + image + "/json":
+					fmt.Fprintf(w, `{"Id": "sha256:1111", "RepoTags": [%q], "RepoDigests": %s, "Config": {}}`, image, tt.repoDigests)
+				case "GET /images/sha256:1111/history":
+					io.WriteString(w, `[]`)
+				case "GET /containers/json":
+					io.WriteString(w, `[]`)
+				case "POST /containers/create":
+					w.WriteHeader(http.StatusCreated)
+					io.WriteString(w, `{"Id": "c-new", "Warnings": []}`)
+				case "POST /containers/c-new/start":
+					w.WriteHeader(http.StatusNoContent)
+				default:
+					return false
+				}
+				return true
+			})
+			server := newTestServerWith(t, client)
+			require.NoError(t, server.db.SetSetting(imagePolicySetting, `{"allowed_registries": ["ghcr.io"]}`))
+			registerUser(t, server, "dev")
+
+			w := serveAs(t, server, "dev", "POST", "/api/v1/templates", map[string]interface{}{
+				"name": "app", "image": image, "config": "{}",
+			})
+			require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+			w = serveAs(t, server, "dev", "POST", "/api/v1/containers", map[string]interface{}{
+				"name": "app-1", "image": image,
+			})
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			if tt.status == http.StatusForbidden {
+				var response struct {
+					Violation struct{ Rule string } `json:"violation"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, "allowed_registries", response.Violation.Rule)
+				assert.False(t, fake.requested("POST /containers/create"))
+			}
+		})
+	}
+}
+
+func TestTagChecksReferencePolicy(t *testing.T) {
+	client, fake := newFakeDocker(t, func(route string, w http.ResponseWriter, r *http.Request) bool {
+		if route == "POST /images/sha256:1111/tag" {
+			w.WriteHeader(http.StatusCreated)
+			return true
+		}
+		return false
+	})
+	server := newTestServerWith(t, client)
+	require.NoError(t, server.db.SetSetting(imagePolicySetting, `{"allowed_registries": ["ghcr.io"], "denied_tags": ["latest"]}`))
+	registerUser(t, server, "dev")
+
+	w := serveAs(t, server, "dev", "POST", "/api/v1/images/sha256:1111/tag", map[string]string{"repository": "ghcr.io/acme/app", "tag": "latest"})
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	w = serveAs(t, server, "dev", "POST", "/api/v1/images/sha256:1111/tag", map[string]string{"repository": "quay.io/acme/app", "tag": "1.0"})
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	assert.False(t, fake.requested("POST /images/sha256:1111/tag"))
+
+	w = serveAs(t, server, "dev", "POST", "/api/v1/images/sha256:1111/tag", map[string]string{"repository": "ghcr.io/acme/app", "tag": "1.0"})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
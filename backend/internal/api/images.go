@@ -0,0 +1,378 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"cyber-container-platform/internal/docker"
+	"cyber-container-platform/internal/imagepolicy"
+	"cyber-container-platform/internal/registry"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/gin-gonic/gin"
+)
+
+type TagImageRequest struct {
+	Repository string `json:"repository" binding:"required"`
+	Tag        string `json:"tag"`
+}
+
+type PushImageRequest struct {
+	Image string `json:"image" binding:"required"`
+}
+
+// ImageUser is a container created from an image.
+type ImageUser struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// imageUsers lists the containers, running or not, created from the image.
+func (s *Server) imageUsers(imageID string) ([]ImageUser, error) {
+	containers, err := s.dockerClient.ListContainers()
+	if err != nil {
+		return nil, err
+	}
+
+	users := []ImageUser{}
+	for _, container := range containers {
+		if container.ImageID == imageID {
+			users = append(users, ImageUser{ID: container.ID, Name: container.Name, State: container.State})
+		}
+	}
+	return users, nil
+}
+
+func (s *Server) getImage(c *gin.Context) {
+	detail, err := s.dockerClient.InspectImage(c.Param("id"))
+	if err != nil {
+		if docker.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	users, err := s.imageUsers(detail.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"image": detail, "containers": users})
+}
+
+// pullAndCheck pulls ref with the stored registry login and enforces the
+// image and scan policies on the result. A newly pulled image that the
+// policies block is removed again. The pull's progress output is returned
+// even on failure.
+func (s *Server) pullAndCheck(ref string) (string, error) {
+	if err := s.checkImagePolicy(ref, nil); err != nil {
+		return "", err
+	}
+
+	auth, err := s.registries.AuthFor(ref)
+	if err != nil {
+		return "", err
+	}
+
+	// Remembered so that a blocked pull only removes what it brought in.
+	var previousID string
+	if previous, err := s.dockerClient.InspectImage(ref); err == nil {
+		previousID = previous.ID
+	}
+
+	reader, err := s.dockerClient.PullImage(ref, auth)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	progress, err := io.ReadAll(reader)
+	if err != nil {
+		return string(progress), err
+	}
+	if err := pullStreamError(progress); err != nil {
+		return string(progress), err
+	}
+
+	pulled, err := s.dockerClient.InspectImage(ref)
+	if err != nil {
+		return string(progress), err
+	}
+	discard := func() {
+		if pulled.ID != previousID {
+			s.dockerClient.RemoveImage(ref)
+		}
+	}
+
+	if err := s.checkImagePolicy(ref, &pulled); err != nil {
+		discard()
+		return string(progress), err
+	}
+	if err := s.enforceScanPolicy(ref, "pull"); err != nil {
+		discard()
+		return string(progress), err
+	}
+	return string(progress), nil
+}
+
+// pullStreamError returns the error a pull reported in its JSON progress
+// stream. Docker answers a failing pull with a stream, not an error status,
+// and an older copy of the image may still be present locally.
+func pullStreamError(progress []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(progress))
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := dec.Decode(&msg); err != nil {
+			return nil
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+	}
+}
+
+// checkDeployPolicies enforces the image policy and the deploy stage of the
+// scan policy before a container is created from ref.
+func (s *Server) checkDeployPolicies(ref string) error {
+	// The label and signature rules need the image; if it is missing,
+	// only the reference rules apply and Docker reports the missing image.
+	var local *docker.ImageDetail
+	if detail, err := s.dockerClient.InspectImage(ref); err == nil {
+		local = &detail
+	}
+	if err := s.checkImagePolicy(ref, local); err != nil {
+		return err
+	}
+	return s.enforceScanPolicy(ref, "deploy")
+}
+
+// respondImageCheckError writes the response for an error of pullAndCheck
+// or checkDeployPolicies. progress is the pull output, if any.
+func respondImageCheckError(c *gin.Context, err error, progress string) {
+	var violation *imagepolicy.Violation
+	var blocked *scanPolicyError
+	switch {
+	case errors.As(err, &violation), errors.Is(err, imagepolicy.ErrInvalidReference):
+		respondImagePolicyError(c, err)
+	case errors.As(err, &blocked):
+		respondScanPolicyError(c, err)
+	case progress != "":
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "progress": progress})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (s *Server) tagImage(c *gin.Context) {
+	var req TagImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	target, err := registry.TagReference(req.Repository, req.Tag)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.checkReferencePolicy(target); err != nil {
+		respondImagePolicyError(c, err)
+		return
+	}
+
+	if err := s.dockerClient.TagImage(c.Param("id"), target); err != nil {
+		if docker.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"image": target, "message": "Image tagged successfully"})
+}
+
+// progressEvent is one line of a streamed image operation.
+type progressEvent struct {
+	ID      string `json:"id,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Current int64  `json:"current,omitempty"`
+	Total   int64  `json:"total,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// streamProgress relays Docker's JSON progress stream to the client as
+// newline-delimited JSON, flushing after every message. It returns the
+// error Docker reported in the stream, if any.
+func streamProgress(c *gin.Context, stream io.Reader) error {
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	decoder := json.NewDecoder(stream)
+	for {
+		var message jsonmessage.JSONMessage
+		if err := decoder.Decode(&message); err == io.EOF {
+			return nil
+		} else if err != nil {
+			encoder.Encode(progressEvent{Error: err.Error()})
+			return err
+		}
+
+		event := progressEvent{ID: message.ID, Status: message.Status}
+		if message.Progress != nil {
+			event.Current = message.Progress.Current
+			event.Total = message.Progress.Total
+		}
+		if message.Error != nil {
+			event.Error = message.Error.Message
+		}
+
+		encoder.Encode(event)
+		c.Writer.Flush()
+
+		if message.Error != nil {
+			return message.Error
+		}
+	}
+}
+
+// pushImage pushes an image with the stored credentials of its registry and
+// streams the progress. Once streaming has started a failure can only be
+// reported as a final {"error": ...} line.
+func (s *Server) pushImage(c *gin.Context) {
+	var req PushImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	auth, err := s.registries.AuthFor(req.Image)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reader, err := s.dockerClient.PushImage(req.Image, auth)
+	if err != nil {
+		if docker.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer reader.Close()
+
+	fields := map[string]interface{}{"image": req.Image, "user": currentUser(c).Username}
+	if err := streamProgress(c, reader); err != nil {
+		s.logger.Error("Image push failed", err, fields)
+		return
+	}
+	s.logger.Info("Image pushed", fields)
+}
+
+// saveImages streams the images named by the repeated ?image= parameter as
+// a single tar archive.
+func (s *Server) saveImages(c *gin.Context) {
+	refs := c.QueryArray("image")
+	if len(refs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one image parameter is required"})
+		return
+	}
+
+	// The archive is streamed, so check every image up front rather than
+	// failing halfway through the download.
+	for _, ref := range refs {
+		if _, err := s.dockerClient.InspectImage(ref); err != nil {
+			if docker.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Image %s not found", ref)})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	reader, err := s.dockerClient.SaveImages(refs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer reader.Close()
+
+	filename := "images.tar"
+	if len(refs) == 1 {
+		filename = strings.Trim(unsafeFilenameChars.ReplaceAllString(refs[0], "-"), "-") + ".tar"
+	}
+
+	c.DataFromReader(http.StatusOK, -1, "application/x-tar", reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", filename),
+	})
+}
+
+// uploadedArchive returns the archive sent either as the raw request body
+// or as the multipart field "file", limited to limit bytes. It writes the
+// error response itself and returns nil on failure.
+func uploadedArchive(c *gin.Context, limit int64) io.Reader {
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		return body
+	}
+
+	c.Request.Body = body
+	multipart, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil
+	}
+	for {
+		part, err := multipart.NextPart()
+		if err == io.EOF {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No \"file\" field in the upload"})
+			return nil
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil
+		}
+		if part.FormName() == "file" {
+			return part
+		}
+	}
+}
+
+// loadImages imports an image archive sent either as the raw request body
+// or as the multipart field "file". The upload is streamed straight into
+// Docker without being buffered on disk.
+func (s *Server) loadImages(c *gin.Context) {
+	archive := uploadedArchive(c, s.config.MaxImageLoadBytes)
+	if archive == nil {
+		return
+	}
+
+	loaded, err := s.dockerClient.LoadImages(archive)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("Archive exceeds the limit of %d bytes", s.config.MaxImageLoadBytes),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "loaded": loaded})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"loaded": loaded, "message": "Images loaded successfully"})
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cyber-container-platform/internal/scanner"
+	"cyber-container-platform/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullReportsStreamErrors(t *testing.T) {
+	client, _ := newFakeDocker(t, func(route string, w http.ResponseWriter, r *http.Request) bool {
+		switch route {
+		case "POST /images/create":
+			// Docker answers 200 and reports the failure in the stream.
+			io.WriteString(w, `{"status": "Pulling from library/nginx"}`+"\n")
+			io.WriteString(w, `{"errorDetail": {"message": "manifest unknown"}, "error": "manifest unknown"}`+"\n")
+		case "GET /images/nginx:1.25/json":
+			// An older copy of the image is still present.
+			io.WriteString(w, `{"Id": "sha256:1111", "RepoTags": ["nginx:1.25"], "Config": {}}`)
+		case "GET /images/sha256:1111/history":
+			io.WriteString(w, `[]`)
+		default:
+			return false
+		}
+		return true
+	})
+	server := newTestServerWith(t, client)
+
+	w := serve(t, server, "POST", "/api/v1/images/pull", map[string]string{"image": "nginx:1.25"})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	var response map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "manifest unknown", response["error"])
+	assert.Contains(t, response["progress"], "Pulling from library/nginx")
+}
+
+func TestRespondImageCheckErrorScanPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	blocked := &scanPolicyError{
+		Image:      "nginx:1.25",
+		Threshold:  scanner.SeverityHigh,
+		Violations: []scanner.Finding{{ID: "CVE-2024-5535", Package: "libssl3", Severity: scanner.SeverityCritical}},
+	}
+
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		violations bool
+	}{
+		{"blocked by findings", blocked, http.StatusForbidden, true},
+		{"scan failed", fmt.Errorf("%w: %v", errScanRequired, scanner.ErrNoDatabase), http.StatusInternalServerError, false},
+		{"other error", errors.New("daemon unavailable"), http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			respondImageCheckError(c, tt.err, "")
+
+			assert.Equal(t, tt.status, w.Code)
+			var response map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.err.Error(), response["error"])
+			_, hasViolations := response["violations"]
+			assert.Equal(t, tt.violations, hasViolations)
+		})
+	}
+}
+
+func TestRegistryLoginsAreForAdmins(t *testing.T) {
+	builds := make(chan map[string]interface{}, 2)
+	client, fake := newFakeDocker(t, func(route string, w http.ResponseWriter, r *http.Request) bool {
+		if route != "POST /build" {
+			return false
+		}
+		io.Copy(io.Discard, r.Body)
+		auth := map[string]interface{}{}
+		if header := r.Header.Get("X-Registry-Config"); header != "" {
+			data, err := base64.URLEncoding.DecodeString(header)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(data, &auth))
+		}
+		builds <- auth
+		io.WriteString(w, `{"aux": {"ID": "sha256:2222"}}`+"\n")
+		return true
+	})
+	server := newTestServerWith(t, client)
+	server.config.MaxBuildContextBytes = 1 << 20
+	server.wsHub = websocket.NewHub()
+	go server.wsHub.Run()
+	registerUser(t, server, "alice")
+	_, err := server.registries.Save("ghcr.io", "ci-bot", "secret", nil)
+	require.NoError(t, err)
+
+	w := serveAs(t, server, "alice", "POST", "/api/v1/images/push", map[string]string{"image": "ghcr.io/acme/api:1"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.False(t, fake.requested("POST /images/ghcr.io/acme/api/push"))
+
+	build := func(username string) map[string]interface{} {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		form.WriteField("dockerfile", "FROM ghcr.io/acme/base:1\n")
+		form.Close()
+		req := httptest.NewRequest("POST", "/api/v1/images/build", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+testToken(t, server, username))
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		select {
+		case auth := <-builds:
+			return auth
+		case <-time.After(5 * time.Second):
+			t.Fatal("the build did not reach Docker")
+			return nil
+		}
+	}
+
+	assert.Empty(t, build("alice"), "other users build without the stored logins")
+	assert.Contains(t, build("admin"), "ghcr.io")
+}
@@ -0,0 +1,355 @@
+package api
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"cyber-container-platform/internal/backup"
+	"cyber-container-platform/internal/cleanup"
+	"cyber-container-platform/internal/docker"
+	"cyber-container-platform/internal/scheduler"
+	"cyber-container-platform/internal/templates"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Job types handled by the scheduler.
+const (
+	jobVolumeBackup     = "volume_backup"
+	jobDatabaseSnapshot = "database_snapshot"
+	jobTemplateExport   = "template_export"
+	jobSystemPrune      = "system_prune"
+)
+
+type CreateJobRequest struct {
+	Name      string `json:"name" binding:"required"`
+	Type      string `json:"type" binding:"required"`
+	Schedule  string `json:"schedule" binding:"required"`
+	Target    string `json:"target"`
+	KeepLast  int    `json:"keep_last"`
+	KeepDaily int    `json:"keep_daily"`
+	Paused    bool   `json:"paused"`
+}
+
+// registerJobTasks wires the platform's job types into the scheduler.
+func (s *Server) registerJobTasks() {
+	s.scheduler.Register(jobVolumeBackup, scheduler.TaskFuncs{
+		RunFunc: func(volume string) (scheduler.Output, error) {
+			record, err := s.backups.Backup(volume, nil)
+			if err != nil {
+				return scheduler.Output{}, err
+			}
+			return scheduler.Output{Artifact: strconv.FormatInt(record.ID, 10)}, nil
+		},
+		RemoveFunc: func(artifact string) error {
+			id, err := strconv.ParseInt(artifact, 10, 64)
+			if err != nil {
+				return err
+			}
+			if err := s.backups.Delete(id); err != nil && !errors.Is(err, backup.ErrBackupNotFound) {
+				return err
+			}
+			return nil
+		},
+		ValidateFunc: func(volume string) error {
+			if volume == "" {
+				return fmt.Errorf("volume_backup jobs require the volume name as target")
+			}
+			if !docker.ValidVolumeName(volume) {
+				return fmt.Errorf("invalid volume name %q", volume)
+			}
+			return nil
+		},
+	})
+
+	s.scheduler.Register(jobDatabaseSnapshot, scheduler.TaskFuncs{
+		RunFunc: func(string) (scheduler.Output, error) {
+			path := filepath.Join(s.config.BackupDir, "database", "cyber-"+time.Now().UTC().Format("20060102T150405")+".db")
+			if err := s.db.Snapshot(path); err != nil {
+				return scheduler.Output{}, err
+			}
+			return scheduler.Output{Artifact: path}, nil
+		},
+		RemoveFunc: removeArtifactFile,
+	})
+
+	s.scheduler.Register(jobTemplateExport, scheduler.TaskFuncs{
+		RunFunc: func(string) (scheduler.Output, error) {
+			path := filepath.Join(s.config.BackupDir, "templates", "templates-"+time.Now().UTC().Format("20060102T150405")+".tar.gz")
+			if err := s.exportAllTemplates(path); err != nil {
+				return scheduler.Output{}, err
+			}
+			return scheduler.Output{Artifact: path}, nil
+		},
+		RemoveFunc: removeArtifactFile,
+	})
+
+	// Scheduled cleanup: the target is a prune request as accepted by
+	// POST /system/prune. Runs leave no artifact, only a summary.
+	s.scheduler.Register(jobSystemPrune, scheduler.TaskFuncs{
+		RunFunc: func(target string) (scheduler.Output, error) {
+			opts, err := parsePruneTarget(target)
+			if err != nil {
+				return scheduler.Output{}, err
+			}
+			report, err := cleanup.Prune(s.dockerClient, opts, s.backups.BusyVolumes())
+			if err != nil {
+				return scheduler.Output{}, err
+			}
+
+			output := scheduler.Output{
+				Summary: fmt.Sprintf("removed %d items, reclaimed %d bytes", len(report.Items)-report.Failed, report.SpaceReclaimed),
+			}
+			if report.Failed > 0 {
+				return output, fmt.Errorf("%d items could not be removed", report.Failed)
+			}
+			return output, nil
+		},
+		ValidateFunc: func(target string) error {
+			_, err := parsePruneTarget(target)
+			return err
+		},
+	})
+}
+
+func parsePruneTarget(target string) (cleanup.Options, error) {
+	var opts cleanup.Options
+	if err := json.Unmarshal([]byte(target), &opts); err != nil {
+		return opts, fmt.Errorf("system_prune jobs require a prune request as JSON target: %w", err)
+	}
+	return opts, opts.Validate()
+}
+
+func removeArtifactFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// exportAllTemplates writes every template as a YAML bundle into a
+// gzip-compressed tar at path. The archive is written next to path and only
+// renamed into place once it is complete.
+func (s *Server) exportAllTemplates(path string) error {
+	rows, err := s.db.GetDB().Query("SELECT " + templateColumns + " FROM container_templates ORDER BY id")
+	if err != nil {
+		return err
+	}
+	var records []*templateRecord
+	for rows.Next() {
+		record, err := scanTemplate(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		records = append(records, record)
+	}
+	rows.Close()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	partial := path + ".partial"
+	file, err := os.OpenFile(partial, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(partial)
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	for _, record := range records {
+		data, err := templates.Marshal(record.toBundle(), "yaml")
+		if err != nil {
+			return err
+		}
+
+		name := fmt.Sprintf("%d-%s.yaml", record.ID, unsafeFilenameChars.ReplaceAllString(record.Name, "-"))
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: now}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	return os.Rename(partial, path)
+}
+
+// jobErrorStatus maps scheduler errors to HTTP status codes.
+func jobErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, scheduler.ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, scheduler.ErrJobRunning):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func parseJobID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+func (s *Server) listJobs(c *gin.Context) {
+	jobs, err := s.scheduler.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs, "types": s.scheduler.Types()})
+}
+
+func (s *Server) createJob(c *gin.Context) {
+	var req CreateJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job := &scheduler.Job{
+		Name:      req.Name,
+		Type:      req.Type,
+		Schedule:  req.Schedule,
+		Target:    req.Target,
+		Retention: scheduler.Retention{KeepLast: req.KeepLast, KeepDaily: req.KeepDaily},
+		Paused:    req.Paused,
+		CreatedBy: currentUser(c).ID,
+	}
+
+	if err := s.scheduler.Create(job); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"job": job, "message": "Job created successfully"})
+}
+
+func (s *Server) getJob(c *gin.Context) {
+	id, ok := parseJobID(c)
+	if !ok {
+		return
+	}
+
+	job, err := s.scheduler.Get(id)
+	if err != nil {
+		c.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	runs, err := s.scheduler.Runs(id, 20)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job, "runs": runs})
+}
+
+func (s *Server) listJobRuns(c *gin.Context) {
+	id, ok := parseJobID(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	if _, err := s.scheduler.Get(id); err != nil {
+		c.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	runs, err := s.scheduler.Runs(id, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+func (s *Server) pauseJob(c *gin.Context) {
+	s.setJobPaused(c, true)
+}
+
+func (s *Server) resumeJob(c *gin.Context) {
+	s.setJobPaused(c, false)
+}
+
+func (s *Server) setJobPaused(c *gin.Context, paused bool) {
+	id, ok := parseJobID(c)
+	if !ok {
+		return
+	}
+
+	if err := s.scheduler.SetPaused(id, paused); err != nil {
+		c.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	message := "Job resumed successfully"
+	if paused {
+		message = "Job paused successfully"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+func (s *Server) triggerJob(c *gin.Context) {
+	id, ok := parseJobID(c)
+	if !ok {
+		return
+	}
+
+	runID, err := s.scheduler.Trigger(id)
+	if err != nil {
+		c.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"run_id": runID, "message": "Job triggered"})
+}
+
+func (s *Server) deleteJob(c *gin.Context) {
+	id, ok := parseJobID(c)
+	if !ok {
+		return
+	}
+
+	if err := s.scheduler.Delete(id); err != nil {
+		c.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Job deleted successfully"})
+}
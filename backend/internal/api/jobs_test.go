@@ -0,0 +1,53 @@
+package api
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportAllTemplates(t *testing.T) {
+	s := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "templates", "templates.tar.gz")
+	require.NoError(t, s.exportAllTemplates(path))
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "only the finished archive is left")
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	count := 0
+	for {
+		if _, err := tr.Next(); err != nil {
+			break
+		}
+		count++
+	}
+	assert.Equal(t, len(builtinTemplates(t, s)), count)
+
+}
+
+func TestVolumeBackupJobTarget(t *testing.T) {
+	s := newTestServer(t)
+	create := func(target string) int {
+		return serve(t, s, "POST", "/api/v1/jobs", map[string]interface{}{
+			"name": "backup " + target, "type": "volume_backup", "schedule": "0 3 * * *", "target": target, "paused": true,
+		}).Code
+	}
+
+	assert.Equal(t, http.StatusCreated, create("app-data"))
+	for _, target := range []string{"", "../etc", "a/b", "-data", "data;rm"} {
+		assert.Equal(t, http.StatusBadRequest, create(target), target)
+	}
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cyber-container-platform/internal/docker"
+	"cyber-container-platform/internal/labels"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxContainerPageSize = 500
+
+// containerListQuery holds the query parameters of GET /containers. The
+// embedded ContainerQuery is evaluated by Docker; the rest is applied to
+// its result.
+type containerListQuery struct {
+	docker.ContainerQuery
+	selector      labels.Selector
+	image         string
+	createdAfter  time.Time
+	createdBefore time.Time
+	sortBy        string
+	descending    bool
+	limit         int
+	cursor        *containerCursor
+}
+
+// containerCursor is the position after the last container of a page: its
+// sort key and ID, so pages stay consistent as containers come and go.
+type containerCursor struct {
+	Key string `json:"k"`
+	ID  string `json:"id"`
+}
+
+func (cur containerCursor) encode() string {
+	raw, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeContainerCursor(raw string) (*containerCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var cur containerCursor
+	if err := json.Unmarshal(data, &cur); err != nil || cur.ID == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &cur, nil
+}
+
+func splitQuery(values []string) []string {
+	var out []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+func parseContainerListQuery(c *gin.Context) (containerListQuery, error) {
+	q := containerListQuery{sortBy: "created", descending: true}
+
+	for _, state := range splitQuery(c.QueryArray("state")) {
+		if !contains(docker.ContainerStates, state) {
+			return q, fmt.Errorf("invalid state %q, expected one of %s", state, strings.Join(docker.ContainerStates, ", "))
+		}
+		q.States = append(q.States, state)
+	}
+
+	for _, health := range splitQuery(c.QueryArray("health")) {
+		if !contains(docker.ContainerHealthStates, health) {
+			return q, fmt.Errorf("invalid health %q, expected one of %s", health, strings.Join(docker.ContainerHealthStates, ", "))
+		}
+		q.Health = append(q.Health, health)
+	}
+
+	selector, err := labels.Parse(c.QueryArray("label"))
+	if err != nil {
+		return q, err
+	}
+	q.selector = selector
+	q.Labels = selector.DockerFilters()
+	q.Name = c.Query("name")
+	q.Network = c.Query("network")
+	q.image = c.Query("image")
+
+	for name, dst := range map[string]*time.Time{"created_after": &q.createdAfter, "created_before": &q.createdBefore} {
+		if raw := c.Query(name); raw != "" {
+			if *dst, err = time.Parse(time.RFC3339, raw); err != nil {
+				return q, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+			}
+		}
+	}
+
+	if raw := c.Query("sort"); raw != "" {
+		q.sortBy = strings.TrimPrefix(raw, "-")
+		q.descending = strings.HasPrefix(raw, "-")
+		switch q.sortBy {
+		case "name", "created", "state", "image":
+		default:
+			return q, fmt.Errorf("cannot sort by %q, expected name, created, state or image", q.sortBy)
+		}
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		if q.limit, err = strconv.Atoi(raw); err != nil || q.limit <= 0 || q.limit > maxContainerPageSize {
+			return q, fmt.Errorf("limit must be between 1 and %d", maxContainerPageSize)
+		}
+	}
+	if raw := c.Query("cursor"); raw != "" {
+		if q.cursor, err = decodeContainerCursor(raw); err != nil {
+			return q, err
+		}
+	}
+	return q, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (q containerListQuery) sortKey(cont docker.ContainerInfo) string {
+	switch q.sortBy {
+	case "name":
+		return cont.Name
+	case "state":
+		return cont.State
+	case "image":
+		return cont.Image
+	default:
+		// Fixed width, so keys compare in time order.
+		return fmt.Sprintf("%020d", cont.Created.UnixNano())
+	}
+}
+
+// before reports whether position a sorts before b, breaking ties by ID.
+func (q containerListQuery) before(a, b containerCursor) bool {
+	if a.Key == b.Key {
+		if a.ID == b.ID {
+			return false
+		}
+		return (a.ID < b.ID) != q.descending
+	}
+	return (a.Key < b.Key) != q.descending
+}
+
+// apply filters, sorts and pages the containers Docker returned. It
+// returns the page and the cursor of the next one, or "" on the last page.
+func (q containerListQuery) apply(containers []docker.ContainerInfo) ([]docker.ContainerInfo, string) {
+	matched := []docker.ContainerInfo{}
+	for _, cont := range containers {
+		if !q.selector.Matches(cont.Labels) {
+			continue
+		}
+		if q.image != "" && !strings.Contains(cont.Image, q.image) {
+			continue
+		}
+		if !q.createdAfter.IsZero() && !cont.Created.After(q.createdAfter) {
+			continue
+		}
+		if !q.createdBefore.IsZero() && !cont.Created.Before(q.createdBefore) {
+			continue
+		}
+		if q.cursor != nil && !q.before(*q.cursor, containerCursor{Key: q.sortKey(cont), ID: cont.ID}) {
+			continue
+		}
+		matched = append(matched, cont)
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return q.before(
+			containerCursor{Key: q.sortKey(matched[i]), ID: matched[i].ID},
+			containerCursor{Key: q.sortKey(matched[j]), ID: matched[j].ID},
+		)
+	})
+
+	if q.limit == 0 || len(matched) <= q.limit {
+		return matched, ""
+	}
+	page := matched[:q.limit]
+	last := page[len(page)-1]
+	return page, containerCursor{Key: q.sortKey(last), ID: last.ID}.encode()
+}
+
+// listContainers lists containers, narrowed and ordered by the query
+// parameters. Without ?limit every match is returned.
+func (s *Server) listContainers(c *gin.Context) {
+	q, err := parseContainerListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	containers, err := s.dockerClient.QueryContainers(q.ContainerQuery)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, next := q.apply(containers)
+	response := gin.H{"containers": page}
+	if next != "" {
+		response["next_cursor"] = next
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cyber-container-platform/internal/docker"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerListQuery(t *testing.T) {
+	parse := func(rawQuery string) (containerListQuery, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/api/v1/containers?"+rawQuery, nil)
+		return parseContainerListQuery(c)
+	}
+
+	q, err := parse("state=running,paused&label=tier=web&label=!pinned&name=web&network=front&health=unhealthy")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"running", "paused"}, q.States)
+	assert.Equal(t, []string{"unhealthy"}, q.Health)
+	assert.Equal(t, []string{"tier=web"}, q.Labels, "negated labels are not pushed down")
+	assert.Equal(t, "front", q.Network)
+
+	for _, raw := range []string{"state=sleeping", "health=sick", "sort=size", "limit=0", "limit=501", "created_after=yesterday", "cursor=!!", "label=!"} {
+		_, err := parse(raw)
+		assert.Error(t, err, raw)
+	}
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	containers := []docker.ContainerInfo{
+		{ID: "a", Name: "web-1", Image: "nginx:1.25", State: "running", Created: base},
+		{ID: "b", Name: "db", Image: "postgres:16", State: "exited", Created: base.Add(time.Hour)},
+		{ID: "c", Name: "web-2", Image: "nginx:1.25", State: "running", Created: base.Add(2 * time.Hour), Labels: map[string]string{"pinned": "true"}},
+		{ID: "d", Name: "cache", Image: "redis:7", State: "running", Created: base.Add(3 * time.Hour)},
+	}
+	names := func(page []docker.ContainerInfo) []string {
+		out := []string{}
+		for _, cont := range page {
+			out = append(out, cont.Name)
+		}
+		return out
+	}
+
+	q, _ = parse("")
+	page, next := q.apply(containers)
+	assert.Equal(t, []string{"cache", "web-2", "db", "web-1"}, names(page), "newest first by default")
+	assert.Empty(t, next)
+
+	q, _ = parse("image=nginx&label=!pinned")
+	page, _ = q.apply(containers)
+	assert.Equal(t, []string{"web-1"}, names(page))
+
+	q, _ = parse("created_after=2024-01-01T00:30:00Z&created_before=2024-01-01T02:30:00Z&sort=name")
+	page, _ = q.apply(containers)
+	assert.Equal(t, []string{"db", "web-2"}, names(page))
+
+	var all []string
+	cursor := ""
+	for {
+		q, err = parse("sort=-name&limit=3&cursor=" + cursor)
+		assert.NoError(t, err)
+		page, cursor = q.apply(containers)
+		all = append(all, names(page)...)
+		if cursor == "" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"web-2", "web-1", "db", "cache"}, all)
+}
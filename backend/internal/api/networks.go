@@ -0,0 +1,327 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cyber-container-platform/internal/docker"
+	"cyber-container-platform/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (r CreateNetworkRequest) networkOptions() docker.NetworkOptions {
+	return docker.NetworkOptions{
+		Name:        r.Name,
+		Driver:      r.Driver,
+		Subnet:      r.Subnet,
+		Gateway:     r.Gateway,
+		IPRange:     r.IPRange,
+		IPAMDriver:  r.IPAMDriver,
+		Internal:    r.Internal,
+		Attachable:  r.Attachable,
+		EnableIPv6:  r.EnableIPv6,
+		IPv6Subnet:  r.IPv6Subnet,
+		IPv6Gateway: r.IPv6Gateway,
+		Labels:      r.Labels,
+		Options:     r.Options,
+	}
+}
+
+type ConnectNetworkRequest struct {
+	Container   string   `json:"container" binding:"required"`
+	Aliases     []string `json:"aliases"`
+	IPv4Address string   `json:"ipv4_address"`
+	IPv6Address string   `json:"ipv6_address"`
+}
+
+type DisconnectNetworkRequest struct {
+	Container string `json:"container" binding:"required"`
+	Force     bool   `json:"force"`
+}
+
+// validateNetworkOptions checks addressing before it reaches Docker so users
+// get a precise error instead of a daemon-side IPAM failure.
+func validateNetworkOptions(opts docker.NetworkOptions) error {
+	if !isValidContainerName(opts.Name) {
+		return fmt.Errorf("invalid network name format")
+	}
+
+	if opts.Subnet == "" && (opts.Gateway != "" || opts.IPRange != "") {
+		return fmt.Errorf("gateway and ip_range require a subnet")
+	}
+	if opts.Subnet != "" {
+		if err := validateAddressing(opts.Subnet, opts.Gateway, opts.IPRange, false); err != nil {
+			return err
+		}
+	}
+
+	if opts.IPv6Subnet == "" && opts.IPv6Gateway != "" {
+		return fmt.Errorf("ipv6_gateway requires an ipv6_subnet")
+	}
+	if opts.IPv6Subnet != "" {
+		if !opts.EnableIPv6 {
+			return fmt.Errorf("ipv6_subnet requires enable_ipv6")
+		}
+		if err := validateAddressing(opts.IPv6Subnet, opts.IPv6Gateway, "", true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateAddressing(subnet, gateway, ipRange string, ipv6 bool) error {
+	_, subnetNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return fmt.Errorf("invalid subnet %q", subnet)
+	}
+	if (subnetNet.IP.To4() == nil) != ipv6 {
+		if ipv6 {
+			return fmt.Errorf("ipv6_subnet %q is not an IPv6 subnet", subnet)
+		}
+		return fmt.Errorf("subnet %q is not an IPv4 subnet", subnet)
+	}
+
+	if gateway != "" {
+		ip := net.ParseIP(gateway)
+		if ip == nil {
+			return fmt.Errorf("invalid gateway %q", gateway)
+		}
+		if !subnetNet.Contains(ip) {
+			return fmt.Errorf("gateway %s is outside subnet %s", gateway, subnet)
+		}
+	}
+
+	if ipRange != "" {
+		rangeIP, rangeNet, err := net.ParseCIDR(ipRange)
+		if err != nil {
+			return fmt.Errorf("invalid ip_range %q", ipRange)
+		}
+		subnetOnes, _ := subnetNet.Mask.Size()
+		rangeOnes, _ := rangeNet.Mask.Size()
+		if !subnetNet.Contains(rangeIP) || rangeOnes < subnetOnes {
+			return fmt.Errorf("ip_range %s is outside subnet %s", ipRange, subnet)
+		}
+	}
+
+	return nil
+}
+
+// saveNetworkConfig records the desired configuration of a network, replacing
+// any earlier configuration stored under the same name.
+func (s *Server) saveNetworkConfig(opts docker.NetworkOptions, user authUser) error {
+	config, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.GetDB().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM network_configs WHERE name = ?", opts.Name); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO network_configs (name, driver, config, created_by) VALUES (?, ?, ?, ?)",
+		opts.Name, opts.Driver, string(config), user.ownerID(),
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type networkConfigRecord struct {
+	ID        int64                 `json:"id"`
+	Name      string                `json:"name"`
+	Driver    string                `json:"driver"`
+	Config    docker.NetworkOptions `json:"config"`
+	CreatedBy int64                 `json:"created_by"`
+	CreatedAt time.Time             `json:"created_at"`
+}
+
+const networkConfigColumns = "id, name, driver, config, created_by, created_at"
+
+func scanNetworkConfig(row rowScanner) (*networkConfigRecord, error) {
+	var record networkConfigRecord
+	var config string
+	var createdBy sql.NullInt64
+
+	if err := row.Scan(&record.ID, &record.Name, &record.Driver, &config, &createdBy, &record.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(config), &record.Config); err != nil {
+		return nil, fmt.Errorf("corrupt network config %d: %w", record.ID, err)
+	}
+	record.CreatedBy = createdBy.Int64
+
+	return &record, nil
+}
+
+func (s *Server) findNetworkConfig(name string) (*networkConfigRecord, error) {
+	return scanNetworkConfig(s.db.GetDB().QueryRow(
+		"SELECT "+networkConfigColumns+" FROM network_configs WHERE name = ?", name,
+	))
+}
+
+func (s *Server) listNetworkConfigs(c *gin.Context) {
+	rows, err := s.db.GetDB().Query("SELECT " + networkConfigColumns + " FROM network_configs ORDER BY name")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var configs []*networkConfigRecord
+	for rows.Next() {
+		record, err := scanNetworkConfig(rows)
+		if err != nil {
+			continue
+		}
+		configs = append(configs, record)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"configs": configs})
+}
+
+// recreateNetwork creates a network from its stored desired configuration,
+// for example after it was removed or on a freshly reset Docker host.
+func (s *Server) recreateNetwork(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid network config ID"})
+		return
+	}
+
+	record, err := scanNetworkConfig(s.db.GetDB().QueryRow(
+		"SELECT "+networkConfigColumns+" FROM network_configs WHERE id = ?", id,
+	))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Network config not found"})
+		return
+	}
+
+	if _, err := s.dockerClient.InspectNetwork(record.Name); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Network " + record.Name + " already exists"})
+		return
+	}
+
+	networkID, err := s.dockerClient.CreateNetwork(record.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": networkID, "message": "Network recreated successfully"})
+}
+
+func (s *Server) deleteNetworkConfig(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid network config ID"})
+		return
+	}
+
+	result, err := s.db.GetDB().Exec("DELETE FROM network_configs WHERE id = ?", id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Network config not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Network config deleted successfully"})
+}
+
+func (s *Server) connectNetwork(c *gin.Context) {
+	networkID := c.Param("id")
+
+	var req ConnectNetworkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.IPv4Address != "" {
+		if ip := net.ParseIP(req.IPv4Address); ip == nil || ip.To4() == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ipv4_address"})
+			return
+		}
+	}
+	if req.IPv6Address != "" {
+		if ip := net.ParseIP(req.IPv6Address); ip == nil || ip.To4() != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ipv6_address"})
+			return
+		}
+	}
+	for _, alias := range req.Aliases {
+		if !isValidContainerName(alias) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alias " + alias})
+			return
+		}
+	}
+
+	err := s.dockerClient.ConnectNetwork(networkID, req.Container, docker.EndpointOptions{
+		Aliases:     req.Aliases,
+		IPv4Address: req.IPv4Address,
+		IPv6Address: req.IPv6Address,
+	})
+	if err != nil {
+		if docker.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.broadcastNetworkChange("network_connected", networkID, req.Container)
+	c.JSON(http.StatusOK, gin.H{"message": "Container connected to network successfully"})
+}
+
+func (s *Server) disconnectNetwork(c *gin.Context) {
+	networkID := c.Param("id")
+
+	var req DisconnectNetworkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.dockerClient.DisconnectNetwork(networkID, req.Container, req.Force); err != nil {
+		if docker.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.broadcastNetworkChange("network_disconnected", networkID, req.Container)
+	c.JSON(http.StatusOK, gin.H{"message": "Container disconnected from network successfully"})
+}
+
+// broadcastNetworkChange tells WebSocket clients that a container's network
+// membership changed. The refreshed network is included when it can be read.
+func (s *Server) broadcastNetworkChange(event, networkID, containerID string) {
+	data := map[string]interface{}{
+		"network_id": networkID,
+		"container":  containerID,
+	}
+	if network, err := s.dockerClient.InspectNetwork(networkID); err == nil {
+		data["network"] = network
+	}
+
+	s.wsHub.Broadcast(websocket.Message{Type: event, Data: data})
+}
@@ -0,0 +1,326 @@
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"cyber-container-platform/internal/bundle"
+	"cyber-container-platform/internal/cleanup"
+	"cyber-container-platform/internal/docker"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/gin-gonic/gin"
+)
+
+// errImportConflict is returned when an imported container or volume
+// would replace an existing one.
+var errImportConflict = errors.New("import conflict")
+
+var errInvalidVolumeSelection = errors.New("invalid volume selection")
+
+// exportVolumes returns the volumes of cont selected by ?volumes: a comma
+// separated list of volume names, or "all" for every named volume.
+func (s *Server) exportVolumes(cont types.ContainerJSON, spec string) ([]bundle.Volume, error) {
+	if spec == "" {
+		return []bundle.Volume{}, nil
+	}
+	requested := make(map[string]bool)
+	for _, name := range splitQuery([]string{spec}) {
+		requested[name] = true
+	}
+	all := requested["all"]
+
+	volumes := []bundle.Volume{}
+	for _, m := range cont.Mounts {
+		if m.Type != mount.TypeVolume || m.Name == "" {
+			continue
+		}
+		if !all && !requested[m.Name] {
+			continue
+		}
+		delete(requested, m.Name)
+
+		vol, err := s.dockerClient.InspectVolume(m.Name)
+		if err != nil {
+			return nil, err
+		}
+		if all && cleanup.IsAnonymousVolume(vol.Name, vol.Labels) {
+			continue
+		}
+		volumes = append(volumes, bundle.Volume{
+			Name:        vol.Name,
+			Driver:      vol.Driver,
+			Labels:      vol.Labels,
+			Destination: m.Destination,
+		})
+	}
+
+	delete(requested, "all")
+	if len(requested) > 0 {
+		missing := make([]string, 0, len(requested))
+		for name := range requested {
+			missing = append(missing, name)
+		}
+		sort.Strings(missing)
+		return nil, fmt.Errorf("%w: volumes not mounted by the container: %s", errInvalidVolumeSelection, strings.Join(missing, ", "))
+	}
+	return volumes, nil
+}
+
+// exportContainer streams a gzip compressed archive of the container's
+// configuration, its image and the volumes selected by ?volumes, which
+// importContainer turns back into a container on another host.
+func (s *Server) exportContainer(c *gin.Context) {
+	cont, err := s.dockerClient.InspectContainer(c.Param("id"))
+	if err != nil {
+		c.JSON(containerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	name := strings.TrimPrefix(cont.Name, "/")
+
+	volumes, err := s.exportVolumes(cont, c.Query("volumes"))
+	if errors.Is(err, errInvalidVolumeSelection) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Save the image by its tag so that it keeps it on import, unless the
+	// tag has moved on to another image since the container was created.
+	image := cont.Config.Image
+	if detail, err := s.dockerClient.InspectImage(image); err != nil || detail.ID != cont.Image {
+		image = cont.Image
+	}
+	imageArchive, err := s.dockerClient.SaveImages([]string{image})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer imageArchive.Close()
+
+	filename := strings.Trim(unsafeFilenameChars.ReplaceAllString(name, "-"), "-") + ".tar.gz"
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	gz := gzip.NewWriter(c.Writer)
+	w := bundle.NewWriter(gz)
+	err = func() error {
+		manifest := bundle.Manifest{
+			ExportedAt: time.Now().UTC(),
+			Name:       name,
+			Image:      image,
+			ImageID:    cont.Image,
+			Volumes:    volumes,
+		}
+		if err := w.WriteManifest(manifest); err != nil {
+			return err
+		}
+		if err := w.WriteContainer(cont); err != nil {
+			return err
+		}
+		if err := w.WriteImage(imageArchive); err != nil {
+			return fmt.Errorf("image: %w", err)
+		}
+		for _, vol := range volumes {
+			archive, err := s.dockerClient.ExportVolume(vol.Name, s.config.BackupHelperImage)
+			if err != nil {
+				return fmt.Errorf("volume %s: %w", vol.Name, err)
+			}
+			err = w.WriteVolume(vol.Name, archive)
+			archive.Close()
+			if err != nil {
+				return fmt.Errorf("volume %s: %w", vol.Name, err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		return gz.Close()
+	}()
+	if err != nil {
+		// The response has started; leaving the archive incomplete is the
+		// only way left to report the failure.
+		s.logger.Error("Container export failed", err, map[string]interface{}{"container": name})
+		c.Abort()
+		return
+	}
+
+	s.logger.Info("Container exported", map[string]interface{}{
+		"container": name, "image": image, "volumes": len(volumes), "user": currentUser(c).Username,
+	})
+}
+
+// importContainer creates a container from an archive written by
+// exportContainer, sent as the raw request body or the multipart field
+// "file". ?name overrides the container name, ?start starts it and
+// ?replace_volumes overwrites existing volumes of the same names.
+func (s *Server) importContainer(c *gin.Context) {
+	start, ok := boolQuery(c, "start", false)
+	if !ok {
+		return
+	}
+	replaceVolumes, ok := boolQuery(c, "replace_volumes", false)
+	if !ok {
+		return
+	}
+	name := strings.TrimSpace(c.Query("name"))
+	if name != "" && !isValidContainerName(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid container name format"})
+		return
+	}
+
+	archive := uploadedArchive(c, s.config.MaxImageLoadBytes)
+	if archive == nil {
+		return
+	}
+
+	var manifest bundle.Manifest
+	var inspect types.ContainerJSON
+	var policyErr error
+	imported := []string{}
+	err := bundle.Read(archive, bundle.Handlers{
+		Manifest: func(m bundle.Manifest) error {
+			manifest = m
+			if name == "" {
+				name = m.Name
+			}
+			if !isValidContainerName(name) {
+				return fmt.Errorf("%w: invalid container name %q", bundle.ErrInvalidArchive, name)
+			}
+			// Check the reference rules before anything is loaded or
+			// replaced; the image rules follow once the image is loaded.
+			if policyErr = s.checkDeployPolicies(m.Image); policyErr != nil {
+				return policyErr
+			}
+			if _, err := s.dockerClient.InspectContainer(name); err == nil {
+				return fmt.Errorf("%w: container %s already exists", errImportConflict, name)
+			}
+			if replaceVolumes {
+				return nil
+			}
+			for _, vol := range m.Volumes {
+				if _, err := s.dockerClient.InspectVolume(vol.Name); err == nil {
+					return fmt.Errorf("%w: volume %s already exists", errImportConflict, vol.Name)
+				}
+			}
+			return nil
+		},
+		Container: func(raw json.RawMessage) error {
+			if err := json.Unmarshal(raw, &inspect); err != nil || inspect.ContainerJSONBase == nil ||
+				inspect.Config == nil || inspect.HostConfig == nil {
+				return fmt.Errorf("%w: container configuration is incomplete", bundle.ErrInvalidArchive)
+			}
+			return nil
+		},
+		Image: func(r io.Reader) error {
+			if _, err := s.dockerClient.LoadImages(r); err != nil {
+				return err
+			}
+			policyErr = s.checkDeployPolicies(manifest.Image)
+			return policyErr
+		},
+		Volume: func(vol bundle.Volume, r io.Reader) error {
+			if _, err := s.dockerClient.InspectVolume(vol.Name); err == nil {
+				if err := s.dockerClient.RemoveVolume(vol.Name); err != nil {
+					return fmt.Errorf("replace volume %s: %w", vol.Name, err)
+				}
+			}
+			if _, err := s.dockerClient.CreateVolume(docker.VolumeOptions{Name: vol.Name, Driver: vol.Driver, Labels: vol.Labels}); err != nil {
+				return fmt.Errorf("create volume %s: %w", vol.Name, err)
+			}
+			if err := s.dockerClient.ImportVolume(vol.Name, s.config.BackupHelperImage, r); err != nil {
+				return fmt.Errorf("import volume %s: %w", vol.Name, err)
+			}
+			imported = append(imported, vol.Name)
+			return nil
+		},
+	})
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case policyErr != nil:
+			respondImageCheckError(c, policyErr, "")
+		case errors.As(err, &tooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("Archive exceeds the limit of %d bytes", s.config.MaxImageLoadBytes),
+			})
+		case errors.Is(err, bundle.ErrInvalidArchive):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, errImportConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "volumes": imported})
+		}
+		return
+	}
+
+	spec := docker.SpecFromInspect(inspect)
+	spec.Config.Image = manifest.Image
+	warnings := []string{}
+	for netName := range spec.Networks {
+		if _, err := s.dockerClient.InspectNetwork(netName); err == nil {
+			continue
+		}
+		delete(spec.Networks, netName)
+		warnings = append(warnings, fmt.Sprintf("Network %s does not exist on this host and was skipped", netName))
+		if string(spec.HostConfig.NetworkMode) == netName {
+			spec.HostConfig.NetworkMode = container.NetworkMode("default")
+		}
+	}
+	for _, bind := range spec.HostConfig.Binds {
+		if strings.HasPrefix(bind, "/") {
+			warnings = append(warnings, fmt.Sprintf("Bind mount %s refers to a host path that is not part of the archive", bind))
+		}
+	}
+	for _, m := range spec.HostConfig.Mounts {
+		if m.Type == mount.TypeBind {
+			warnings = append(warnings, fmt.Sprintf("Bind mount %s refers to a host path that is not part of the archive", m.Source))
+		}
+	}
+
+	// Hold the port lock until the container has bound its ports, like
+	// createContainer; the archive's fixed host ports may be taken here.
+	s.portMu.Lock()
+	defer s.portMu.Unlock()
+	if err := s.checkPortBindings(spec.HostConfig.PortBindings, ""); err != nil {
+		respondPortError(c, err)
+		return
+	}
+
+	id, err := s.dockerClient.CreateFromSpec(spec, name)
+	if err != nil {
+		c.JSON(containerErrorStatus(err), gin.H{"error": err.Error(), "volumes": imported})
+		return
+	}
+	if start {
+		if err := s.dockerClient.StartContainer(id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": fmt.Sprintf("Container %s imported but failed to start: %v", name, err), "id": id,
+			})
+			return
+		}
+	}
+
+	s.logger.Info("Container imported", map[string]interface{}{
+		"container": name, "image": manifest.Image, "volumes": len(imported), "user": currentUser(c).Username,
+	})
+	c.JSON(http.StatusCreated, gin.H{
+		"container": gin.H{"id": id, "name": name, "image": manifest.Image},
+		"volumes":   imported,
+		"warnings":  warnings,
+		"message":   "Container imported successfully",
+	})
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cyber-container-platform/internal/bundle"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tarFiles(t *testing.T, files ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range files {
+		if strings.HasSuffix(name, "/") {
+			if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Typeflag: tar.TypeDir}); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 4, Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte("data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func containerArchive(t *testing.T, image string, hostConfig map[string]interface{}) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := bundle.NewWriter(&buf)
+	if err := w.WriteManifest(bundle.Manifest{
+		Name:    "web",
+		Image:   image,
+		Volumes: []bundle.Volume{{Name: "web-data", Driver: "local", Destination: "/data"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteContainer(map[string]interface{}{
+		"Id": "abc", "Name": "/web", "Config": map[string]interface{}{"Image": image}, "HostConfig": hostConfig,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteImage(bytes.NewReader(tarFiles(t, "manifest.json"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteVolume("web-data", bytes.NewReader(tarFiles(t, "volume/", "volume/file"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImportBlockedByPolicyKeepsVolumes(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     string
+		wantLoaded bool
+	}{
+		{"reference rule", `{"allowed_registries": ["ghcr.io"]}`, false},
+		{"image rule", `{"required_labels": {"org.opencontainers.image.source": ""}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loaded := false
+			client, fake := newFakeDocker(t, func(route string, w http.ResponseWriter, r *http.Request) bool {
+				io.Copy(io.Discard, r.Body)
+				switch {
+				case route == "GET /volumes/web-data":
+					io.WriteString(w, `{"Name": "web-data", "Driver": "local"}`)
+				case route == "POST /images/load":
+					loaded = true
+					io.WriteString(w, `{"stream": "Loaded image: nginx:1.25\n"}`)
+				case route == "GET /images/nginx:1.25/json" && loaded:
+					io.WriteString(w, `{"Id": "sha256:1111", "RepoTags": ["nginx:1.25"], "Config": {"Labels": {}}}`)
+				case route == "GET /images/sha256:1111/history":
+					io.WriteString(w, `[]`)
+				default:
+					return false
+				}
+				return true
+			})
+			server := newTestServerWith(t, client)
+			if err := server.db.SetSetting(imagePolicySetting, tt.policy); err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest("POST", "/api/v1/containers/import?replace_volumes=true", bytes.NewReader(containerArchive(t, "nginx:1.25", map[string]interface{}{})))
+			req.Header.Set("Content-Type", "application/x-tar")
+			req.Header.Set("Authorization", "Bearer "+testToken(t, server, "admin"))
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+			assert.Equal(t, tt.wantLoaded, fake.requested("POST /images/load"))
+			assert.False(t, fake.requested("DELETE /volumes/web-data"), "existing volume was removed")
+			assert.False(t, fake.requested("POST /volumes/create"), "volume was created")
+			assert.False(t, fake.requested("POST /containers/create"), "container was created")
+		})
+	}
+}
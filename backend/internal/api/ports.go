@@ -0,0 +1,196 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cyber-container-platform/internal/ports"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/gin-gonic/gin"
+)
+
+var errInvalidPortMapping = errors.New("invalid port mapping")
+
+// autoPortPattern matches the host ports that ask for allocation: "auto",
+// or "auto-<n>" ("auto-1", "auto-2") to allocate several in one request.
+var autoPortPattern = regexp.MustCompile(`^auto(-[0-9]+)?$`)
+
+// isAutoPort reports whether a requested host port asks for allocation.
+func isAutoPort(hostPort string) bool {
+	return autoPortPattern.MatchString(hostPort)
+}
+
+// resolvePortBindings turns the ports of a create request, host port to
+// container port ("80" or "53/udp"), into Docker port bindings. Requested
+// host ports are checked against those claimed by all containers and bound
+// on the host; auto ports are allocated from the configured range. The
+// caller must hold portMu until the container has started.
+func (s *Server) resolvePortBindings(requested map[string]string) (nat.PortMap, nat.PortSet, error) {
+	bindings := make(nat.PortMap)
+	exposed := make(nat.PortSet)
+	if len(requested) == 0 {
+		return bindings, exposed, nil
+	}
+
+	hostPorts := make([]string, 0, len(requested))
+	for hostPort := range requested {
+		hostPorts = append(hostPorts, hostPort)
+	}
+	// Claim the fixed ports before allocating, in a stable order.
+	sort.Slice(hostPorts, func(i, j int) bool {
+		if isAutoPort(hostPorts[i]) != isAutoPort(hostPorts[j]) {
+			return !isAutoPort(hostPorts[i])
+		}
+		return hostPorts[i] < hostPorts[j]
+	})
+
+	targets := make(map[string]nat.Port, len(requested))
+	for _, hostPort := range hostPorts {
+		proto, port := nat.SplitProtoPort(strings.TrimSpace(requested[hostPort]))
+		if n, err := nat.ParsePort(port); err != nil || n == 0 || (proto != "tcp" && proto != "udp" && proto != "sctp") {
+			return nil, nil, fmt.Errorf("%w: container port %q", errInvalidPortMapping, requested[hostPort])
+		}
+		if !isAutoPort(hostPort) {
+			if n, err := strconv.Atoi(hostPort); err != nil || n < 1 || n > 65535 {
+				return nil, nil, fmt.Errorf("%w: host port %q must be a port number, \"auto\" or \"auto-<n>\"", errInvalidPortMapping, hostPort)
+			}
+		}
+		targets[hostPort] = nat.Port(port + "/" + proto)
+	}
+
+	allocator, err := s.portAllocator("")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Fixed ports come first, so all their conflicts are known before the
+	// range can run out.
+	var conflicts []ports.Use
+	var allocErr error
+	for _, hostPort := range hostPorts {
+		target := targets[hostPort]
+		var port int
+		if isAutoPort(hostPort) {
+			if port, allocErr = allocator.Allocate(target.Proto()); allocErr != nil {
+				break
+			}
+		} else {
+			port, _ = strconv.Atoi(hostPort)
+			if conflict := allocator.Claim(port, target.Proto()); conflict != nil {
+				conflicts = append(conflicts, *conflict)
+				continue
+			}
+		}
+		bindings[target] = append(bindings[target], nat.PortBinding{HostPort: strconv.Itoa(port)})
+		exposed[target] = struct{}{}
+	}
+	if len(conflicts) > 0 {
+		conflictErr := &ports.ConflictError{Conflicts: conflicts}
+		if allocErr != nil {
+			return nil, nil, fmt.Errorf("%w; %w", conflictErr, allocErr)
+		}
+		return nil, nil, conflictErr
+	}
+	if allocErr != nil {
+		return nil, nil, allocErr
+	}
+	return bindings, exposed, nil
+}
+
+// portAllocator returns an allocator that knows the host ports claimed by
+// all containers except replacing (a full container ID, or ""). The ports of
+// replacing are handed over to its replacement, so they count as free even
+// while it still binds them.
+func (s *Server) portAllocator(replacing string) (*ports.Allocator, error) {
+	published, err := s.dockerClient.PublishedPorts()
+	if err != nil {
+		return nil, err
+	}
+	used := make([]ports.Use, 0, len(published))
+	handedOver := make(map[string]bool)
+	for _, p := range published {
+		if replacing != "" && p.ContainerID == replacing {
+			handedOver[strconv.Itoa(p.HostPort)+"/"+p.Protocol] = true
+			continue
+		}
+		used = append(used, ports.Use{
+			HostPort: p.HostPort, Protocol: p.Protocol,
+			ContainerID: p.ContainerID, ContainerName: p.ContainerName, State: p.State,
+		})
+	}
+	hostFree := func(protocol string, port int) bool {
+		return handedOver[strconv.Itoa(port)+"/"+protocol] || ports.HostPortFree(protocol, port)
+	}
+	return ports.NewAllocator(s.config.AutoPortRangeStart, s.config.AutoPortRangeEnd, used, hostFree), nil
+}
+
+// checkPortBindings checks the fixed host ports of bindings copied from an
+// existing container configuration, as recreate and import do, against
+// those claimed by all containers but replacing and bound on the host. The
+// caller must hold portMu until the container has started.
+func (s *Server) checkPortBindings(bindings nat.PortMap, replacing string) error {
+	targets := make([]nat.Port, 0, len(bindings))
+	for target := range bindings {
+		targets = append(targets, target)
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i] < targets[j] })
+
+	allocator, err := s.portAllocator(replacing)
+	if err != nil {
+		return err
+	}
+	var conflicts []ports.Use
+	for _, target := range targets {
+		for _, binding := range bindings[target] {
+			// An empty host port is picked by Docker when the container starts.
+			port, err := strconv.Atoi(binding.HostPort)
+			if err != nil {
+				continue
+			}
+			if conflict := allocator.Claim(port, target.Proto()); conflict != nil {
+				conflicts = append(conflicts, *conflict)
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return &ports.ConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// respondPortError writes the response for an error of resolvePortBindings
+// or checkPortBindings.
+func respondPortError(c *gin.Context, err error) {
+	var conflict *ports.ConflictError
+	switch {
+	case errors.As(err, &conflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "conflicts": conflict.Conflicts})
+	case errors.Is(err, ports.ErrRangeExhausted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, errInvalidPortMapping):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// publishedPorts describes port bindings in the shape of a create request:
+// host port to container port.
+func publishedPorts(bindings nat.PortMap) map[string]string {
+	out := make(map[string]string)
+	for target, list := range bindings {
+		for _, binding := range list {
+			out[binding.HostPort] = string(target)
+		}
+	}
+	return out
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsAutoPort(t *testing.T) {
+	for _, hostPort := range []string{"auto", "auto-1", "auto-12"} {
+		assert.True(t, isAutoPort(hostPort), hostPort)
+	}
+	for _, hostPort := range []string{"automatic", "auto-", "auto-x", "auto1", "8080", ""} {
+		assert.False(t, isAutoPort(hostPort), hostPort)
+	}
+}
+
+// portsServer returns a server whose Docker host runs "web" publishing
+// host ports 8080 and 47000, and records the host config of created
+// containers in created.
+func portsServer(t *testing.T, rangeStart, rangeEnd int, created *container.HostConfig) (*Server, *fakeDocker) {
+	t.Helper()
+	client, fake := newFakeDocker(t, func(route string, w http.ResponseWriter, r *http.Request) bool {
+		switch route {
+		case "GET /containers/json":
+			io.WriteString(w, `[{"Id": "c-web", "Names": ["/web"], "State": "running",
+				"Ports": [{"PrivatePort": 80, "PublicPort": 8080, "Type": "tcp"}, {"PrivatePort": 443, "PublicPort": 47000, "Type": "tcp"}]}]`)
+		case "POST /containers/create":
+			var body struct{ HostConfig container.HostConfig }
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			*created = body.HostConfig
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"Id": "c-new", "Warnings": []}`)
+		case "POST /containers/c-new/start":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			return false
+		}
+		return true
+	})
+	server := newTestServerWith(t, client)
+	server.config.AutoPortRangeStart = rangeStart
+	server.config.AutoPortRangeEnd = rangeEnd
+	return server, fake
+}
+
+func TestCreateContainerPortConflicts(t *testing.T) {
+	var created container.HostConfig
+	// The only port of the range is taken as well.
+	server, fake := portsServer(t, 47000, 47000, &created)
+
+	w := serve(t, server, "POST", "/api/v1/containers", map[string]interface{}{
+		"name": "api", "image": "nginx:1.25",
+		"ports": map[string]string{"8080": "80", "auto": "443"},
+	})
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+
+	var response struct {
+		Error     string `json:"error"`
+		Conflicts []struct {
+			HostPort      int    `json:"host_port"`
+			Protocol      string `json:"protocol"`
+			ContainerName string `json:"container_name"`
+		} `json:"conflicts"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Conflicts, 1)
+	assert.Equal(t, 8080, response.Conflicts[0].HostPort)
+	assert.Equal(t, "tcp", response.Conflicts[0].Protocol)
+	assert.Equal(t, "web", response.Conflicts[0].ContainerName)
+	assert.Contains(t, response.Error, "no free host port")
+	assert.False(t, fake.requested("POST /containers/create"))
+}
+
+func TestCreateContainerAllocatesAutoPorts(t *testing.T) {
+	var created container.HostConfig
+	server, _ := portsServer(t, 47000, 47010, &created)
+
+	w := serve(t, server, "POST", "/api/v1/containers", map[string]interface{}{
+		"name": "api", "image": "nginx:1.25",
+		"ports": map[string]string{"auto": "80", "auto-2": "53/udp"},
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var response struct {
+		Ports map[string]string `json:"ports"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	// 47000 is taken for tcp only.
+	assert.Equal(t, map[string]string{"47001": "80/tcp", "47000": "53/udp"}, response.Ports)
+	assert.Equal(t, "47001", created.PortBindings["80/tcp"][0].HostPort)
+	assert.Equal(t, "47000", created.PortBindings["53/udp"][0].HostPort)
+
+	w = serve(t, server, "POST", "/api/v1/containers", map[string]interface{}{
+		"name": "api", "image": "nginx:1.25",
+		"ports": map[string]string{"automatic": "80"},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+}
+
+// copiedPortsDocker is a Docker host where "web" (c0ffee000001) publishes
+// host port 8080 and "other" host port 9090. It records the images of the
+// containers created.
+func copiedPortsDocker(t *testing.T, bindings string, created *[]string) (*Server, *fakeDocker) {
+	t.Helper()
+	client, fake := newFakeDocker(t, func(route string, w http.ResponseWriter, r *http.Request) bool {
+		switch route {
+		case "GET /containers/json":
+			io.WriteString(w, `[{"Id": "c0ffee000001", "Names": ["/web"], "State": "running", "Ports": [{"PrivatePort": 80, "PublicPort": 8080, "Type": "tcp"}]},
+				{"Id": "c0ffee000002", "Names": ["/other"], "State": "running", "Ports": [{"PrivatePort": 80, "PublicPort": 9090, "Type": "tcp"}]}]`)
+		case "GET /containers/web/json", "GET /containers/c0ffee000001/json":
+			io.WriteString(w, `{"Id": "c0ffee000001", "Name": "/web", "Image": "sha256:1111", "State": {"Running": true},
+				"Config": {"Image": "nginx:1.25"}, "HostConfig": {"PortBindings": `+bindings+`}}`)
+		case "GET /images/nginx:1.25/json", "GET /images/busybox:stable/json":
+			io.WriteString(w, `{"Id": "sha256:1111", "RepoTags": ["nginx:1.25"], "Config": {}}`)
+		case "GET /images/sha256:1111/history":
+			io.WriteString(w, `[]`)
+		case "POST /images/load":
+			io.Copy(io.Discard, r.Body)
+			io.WriteString(w, `{"stream": "Loaded image: nginx:1.25\n"}`)
+		case "POST /volumes/create":
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"Name": "web-data", "Driver": "local"}`)
+		case "POST /containers/create":
+			var body struct{ Image string }
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			*created = append(*created, body.Image)
+			if body.Image != "busybox:stable" {
+				// Far enough: the ports were found free.
+				w.WriteHeader(http.StatusInternalServerError)
+				io.WriteString(w, `{"message": "stop here"}`)
+				return true
+			}
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"Id": "h1", "Warnings": []}`)
+		case "PUT /containers/h1/archive":
+			io.Copy(io.Discard, r.Body)
+		case "DELETE /containers/h1":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			return false
+		}
+		return true
+	})
+	server := newTestServerWith(t, client)
+	server.config.BackupHelperImage = "busybox:stable"
+	return server, fake
+}
+
+func TestRecreateChecksCopiedPorts(t *testing.T) {
+	var created []string
+	server, _ := copiedPortsDocker(t, `{"80/tcp": [{"HostPort": "8080"}], "443/tcp": [{"HostPort": "9090"}]}`, &created)
+
+	w := serve(t, server, "POST", "/api/v1/containers/web/recreate", nil)
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "9090/tcp is used by container other")
+	assert.NotContains(t, w.Body.String(), "8080", "the container's own ports are handed over")
+	assert.Empty(t, created)
+
+	server, _ = copiedPortsDocker(t, `{"80/tcp": [{"HostPort": "8080"}], "443/tcp": [{"HostPort": ""}]}`, &created)
+	serve(t, server, "POST", "/api/v1/containers/web/recreate", nil)
+	assert.Equal(t, []string{"nginx:1.25"}, created)
+}
+
+func TestImportChecksCopiedPorts(t *testing.T) {
+	var created []string
+	server, _ := copiedPortsDocker(t, `{}`, &created)
+
+	archive := containerArchive(t, "nginx:1.25", map[string]interface{}{
+		"PortBindings": map[string]interface{}{"80/tcp": []map[string]string{{"HostPort": "9090"}}},
+	})
+	req := httptest.NewRequest("POST", "/api/v1/containers/import?name=web-copy", bytes.NewReader(archive))
+	req.Header.Set("Content-Type", "application/x-tar")
+	req.Header.Set("Authorization", "Bearer "+testToken(t, server, "admin"))
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "9090/tcp is used by container other")
+	assert.Equal(t, []string{"busybox:stable"}, created, "only the volume helper was created")
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"cyber-container-platform/internal/docker"
+
+	"github.com/gin-gonic/gin"
+)
+
+// psArgsPattern limits ps arguments to options and column lists, such as
+// "aux" or "-eo pid,user,pcpu,rss,args". Docker passes them to ps on the
+// host.
+var psArgsPattern = regexp.MustCompile(`^[A-Za-z0-9 ,=%+_-]{0,128}$`)
+
+// getContainerTop lists the processes of a running container with their
+// host PIDs. ?ps_args selects the columns, "-ef" by default.
+func (s *Server) getContainerTop(c *gin.Context) {
+	psArgs := c.Query("ps_args")
+	if !psArgsPattern.MatchString(psArgs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ps_args"})
+		return
+	}
+
+	list, err := s.dockerClient.ContainerTop(c.Param("id"), psArgs)
+	if err != nil {
+		c.JSON(containerErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// signalProcess sends ?signal= (default SIGTERM) to a process of the
+// container, identified by its host PID as listed by getContainerTop.
+func (s *Server) signalProcess(c *gin.Context) {
+	pid, err := strconv.Atoi(c.Param("pid"))
+	if err != nil || pid <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid PID"})
+		return
+	}
+	raw := c.Query("signal")
+	if raw == "" {
+		raw = "SIGTERM"
+	}
+	signal, err := parseSignal(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id := c.Param("id")
+	if err := s.dockerClient.SignalProcess(id, pid, signal, s.config.BackupHelperImage); err != nil {
+		status := containerErrorStatus(err)
+		if errors.Is(err, docker.ErrNoSuchProcess) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.logger.Info("Process signalled", map[string]interface{}{
+		"container": id, "pid": pid, "signal": signal, "user": currentUser(c).Username,
+	})
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Sent %s to process %d", signal, pid)})
+}
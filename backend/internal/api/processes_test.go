@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignalProcessUsesHostPIDs(t *testing.T) {
+	var helper struct {
+		Cmd        []string
+		HostConfig container.HostConfig
+	}
+	client, fake := newFakeDocker(t, func(route string, w http.ResponseWriter, r *http.Request) bool {
+		switch route {
+		case "GET /containers/web/top":
+			io.WriteString(w, `{"Titles": ["UID", "PID", "PPID", "C", "STIME", "TTY", "TIME", "CMD"],
+				"Processes": [["root", "4242", "4200", "0", "09:12", "?", "00:00:00", "nginx: master process"]]}`)
+		case "GET /images/busybox:stable/json":
+			io.WriteString(w, `{"Id": "sha256:b0b0"}`)
+		case "POST /containers/create":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&helper))
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"Id": "h1", "Warnings": []}`)
+		case "POST /containers/h1/wait":
+			io.WriteString(w, `{"StatusCode": 0}`)
+		case "POST /containers/h1/start", "DELETE /containers/h1":
+			w.WriteHeader(http.StatusNoContent)
+		case "GET /containers/h1/logs":
+		default:
+			return false
+		}
+		return true
+	})
+	server := newTestServerWith(t, client)
+	server.config.BackupHelperImage = "busybox:stable"
+
+	w := serve(t, server, "GET", "/api/v1/containers/web/top", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"pid":4242`)
+	assert.False(t, fake.requested("POST /containers/web/exec"), "listing needs no ps in the container")
+
+	w = serve(t, server, "POST", "/api/v1/containers/web/top/1/signal?signal=SIGTERM", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code, "PID 1 of another namespace is not listed")
+	assert.False(t, fake.requested("POST /containers/create"))
+
+	w = serve(t, server, "POST", "/api/v1/containers/web/top/4242/signal?signal=SIGHUP", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"kill", "-s", "HUP", "4242"}, helper.Cmd)
+	assert.Equal(t, container.PidMode("host"), helper.HostConfig.PidMode)
+	assert.True(t, fake.requested("DELETE /containers/h1"), "the helper is removed")
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+
+	"cyber-container-platform/internal/cleanup"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pruneSystem removes unused containers, images, volumes, networks and build
+// cache, or with dry_run only reports what would be removed.
+func (s *Server) pruneSystem(c *gin.Context) {
+	var opts cleanup.Options
+	if err := c.ShouldBindJSON(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := opts.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := cleanup.Prune(s.dockerClient, opts, s.backups.BusyVolumes())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !opts.DryRun {
+		user := currentUser(c)
+		s.logger.Info("System pruned", map[string]interface{}{
+			"user":            user.Username,
+			"removed":         len(report.Items) - report.Failed,
+			"failed":          report.Failed,
+			"space_reclaimed": report.SpaceReclaimed,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"cyber-container-platform/internal/docker"
+	"cyber-container-platform/internal/ports"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultHealthTimeout = 60 * time.Second
+
+// RecreateContainerRequest replaces a container with a copy running Image,
+// or its current image if empty. Pull defaults to true when Image is given.
+// HealthTimeout is in seconds.
+type RecreateContainerRequest struct {
+	Image         string `json:"image"`
+	Pull          *bool  `json:"pull"`
+	Timeout       *int   `json:"timeout"`
+	HealthTimeout *int   `json:"health_timeout"`
+	KeepOld       bool   `json:"keep_old"`
+}
+
+// recreate pulls opts.Image if pull is set, checks it against the
+// deploy policies and replaces the container with a copy running it. An
+// empty opts.Image keeps the container's current image. The pull output is
+// returned alongside pull errors.
+func (s *Server) recreate(id string, opts docker.RecreateOptions, pull bool) (docker.RecreateResult, string, error) {
+	current, err := s.dockerClient.InspectContainer(id)
+	if err != nil {
+		return docker.RecreateResult{}, "", err
+	}
+	if opts.Image == "" {
+		opts.Image = current.Config.Image
+	}
+
+	if pull {
+		if progress, err := s.pullAndCheck(opts.Image); err != nil {
+			return docker.RecreateResult{}, progress, err
+		}
+	}
+	if err := s.checkDeployPolicies(opts.Image); err != nil {
+		return docker.RecreateResult{}, "", err
+	}
+
+	// The copy takes over the container's fixed host ports; others may have
+	// been claimed meanwhile, by a container created outside the platform.
+	s.portMu.Lock()
+	defer s.portMu.Unlock()
+	var bindings nat.PortMap
+	if current.HostConfig != nil {
+		bindings = current.HostConfig.PortBindings
+	}
+	if err := s.checkPortBindings(bindings, current.ID); err != nil {
+		return docker.RecreateResult{}, "", err
+	}
+
+	result, err := s.dockerClient.Recreate(current.ID, opts)
+	if err != nil {
+		return result, "", err
+	}
+
+	s.logger.Info("Container recreated", map[string]interface{}{
+		"name": result.Name, "image": result.Image, "id": result.ID, "previous_id": result.PreviousID,
+	})
+	return result, "", nil
+}
+
+func (s *Server) recreateContainer(c *gin.Context) {
+	var req RecreateContainerRequest
+	// The body is optional; without one the container is recreated as is.
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.Image = strings.TrimSpace(req.Image)
+	if req.Image != "" && !isValidImageName(req.Image) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image name format"})
+		return
+	}
+	opts := docker.RecreateOptions{
+		Image:         req.Image,
+		StopTimeout:   s.config.StopTimeout,
+		HealthTimeout: defaultHealthTimeout,
+		KeepOld:       req.KeepOld,
+	}
+	if req.Timeout != nil {
+		if *req.Timeout < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "timeout must be a non-negative number of seconds"})
+			return
+		}
+		opts.StopTimeout = *req.Timeout
+	}
+	if req.HealthTimeout != nil {
+		if *req.HealthTimeout <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "health_timeout must be a positive number of seconds"})
+			return
+		}
+		opts.HealthTimeout = time.Duration(*req.HealthTimeout) * time.Second
+	}
+	pull := req.Image != ""
+	if req.Pull != nil {
+		pull = *req.Pull
+	}
+
+	result, progress, err := s.recreate(c.Param("id"), opts, pull)
+	if err != nil {
+		var failed *docker.RecreateError
+		var conflict *ports.ConflictError
+		switch {
+		case errors.As(err, &conflict):
+			respondPortError(c, err)
+		case errors.As(err, &failed):
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "rolled_back": failed.RolledBack})
+		case docker.IsNotFound(err) && progress == "":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			respondImageCheckError(c, err, progress)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"container": result,
+		"message":   fmt.Sprintf("Container %s recreated with %s", result.Name, result.Image),
+	})
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"cyber-container-platform/internal/registry"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SaveRegistryRequest struct {
+	Registry string `json:"registry" binding:"required"`
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+func (s *Server) listRegistries(c *gin.Context) {
+	credentials, err := s.registries.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"registries": credentials})
+}
+
+// saveRegistry stores the login for a registry, replacing an earlier one.
+func (s *Server) saveRegistry(c *gin.Context) {
+	var req SaveRegistryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	credential, err := s.registries.Save(req.Registry, req.Username, req.Password, currentUser(c).ownerID())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"registry": credential, "message": "Registry credentials saved"})
+}
+
+func (s *Server) deleteRegistry(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid registry ID"})
+		return
+	}
+
+	if err := s.registries.Delete(id); err != nil {
+		if errors.Is(err, registry.ErrCredentialNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Registry credentials deleted"})
+}
@@ -0,0 +1,256 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cyber-container-platform/internal/docker"
+	"cyber-container-platform/internal/scanner"
+
+	"github.com/gin-gonic/gin"
+)
+
+const scanPolicySetting = "image_scan_policy"
+
+// errScanRequired is returned when the severity policy requires a scan
+// that could not be completed.
+var errScanRequired = errors.New("image scan required by policy failed")
+
+// ImageScan is a stored scan result. Results are keyed by the image digest
+// (its content-addressed ID), so every tag of an image shares one scan.
+type ImageScan struct {
+	Digest    string    `json:"digest"`
+	ScannedAt time.Time `json:"scanned_at"`
+	*scanner.Result
+}
+
+// scanPolicyError is returned when an image has findings the severity
+// policy blocks.
+type scanPolicyError struct {
+	Image      string
+	Threshold  scanner.Severity
+	Violations []scanner.Finding
+}
+
+func (e *scanPolicyError) Error() string {
+	return fmt.Sprintf("image %s has %d vulnerabilities at or above %s severity (first: %s in %s)",
+		e.Image, len(e.Violations), e.Threshold, e.Violations[0].ID, e.Violations[0].Package)
+}
+
+func (s *Server) loadScanPolicy() (scanner.Policy, error) {
+	policy := scanner.Policy{Allowlist: []string{}}
+	value, err := s.db.GetSetting(scanPolicySetting)
+	if err != nil || value == "" {
+		return policy, err
+	}
+	if err := json.Unmarshal([]byte(value), &policy); err != nil {
+		return policy, fmt.Errorf("corrupt image scan policy: %w", err)
+	}
+	return policy, nil
+}
+
+func (s *Server) findImageScan(digest string) (*ImageScan, error) {
+	var scan ImageScan
+	var result string
+	err := s.db.GetDB().QueryRow(
+		"SELECT digest, result, scanned_at FROM image_scans WHERE digest = ?", digest,
+	).Scan(&scan.Digest, &result, &scan.ScannedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(result), &scan.Result); err != nil {
+		return nil, fmt.Errorf("corrupt image scan %s: %w", digest, err)
+	}
+	return &scan, nil
+}
+
+// scanImage returns the scan of ref, reusing the stored result unless it
+// came from another scanner or database version, or refresh is set.
+func (s *Server) scanImage(ref string, refresh bool) (*ImageScan, error) {
+	detail, err := s.dockerClient.InspectImage(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if !refresh {
+		scan, err := s.findImageScan(detail.ID)
+		if err == nil && scan.Scanner == s.imageScanner.Name() && scan.DatabaseVersion == s.imageScanner.DatabaseVersion() {
+			return scan, nil
+		}
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+
+	archive, err := s.dockerClient.SaveImages([]string{detail.ID})
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	result, err := s.imageScanner.Scan(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	scan := &ImageScan{Digest: detail.ID, ScannedAt: time.Now().UTC(), Result: result}
+	if _, err := s.db.GetDB().Exec(
+		`INSERT INTO image_scans (digest, scanner, database_version, result, scanned_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(digest) DO UPDATE SET scanner = excluded.scanner, database_version = excluded.database_version,
+			result = excluded.result, scanned_at = excluded.scanned_at`,
+		scan.Digest, result.Scanner, result.DatabaseVersion, string(encoded), scan.ScannedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Image scanned", map[string]interface{}{
+		"image": ref, "digest": scan.Digest, "findings": len(result.Findings),
+	})
+	return scan, nil
+}
+
+// enforceScanPolicy scans ref if the severity policy applies at stage
+// ("pull" or "deploy") and returns a *scanPolicyError if it is blocked.
+// The policy fails closed: an image that cannot be scanned is blocked too.
+func (s *Server) enforceScanPolicy(ref, stage string) error {
+	policy, err := s.loadScanPolicy()
+	if err != nil {
+		return err
+	}
+	if !policy.Enabled(stage) {
+		return nil
+	}
+
+	scan, err := s.scanImage(ref, false)
+	if err != nil {
+		if docker.IsNotFound(err) {
+			// Nothing to scan yet; Docker reports the missing image itself.
+			return nil
+		}
+		return fmt.Errorf("%w: %v", errScanRequired, err)
+	}
+
+	if violations := policy.Violations(scan.Result); len(violations) > 0 {
+		return &scanPolicyError{Image: ref, Threshold: policy.BlockSeverity, Violations: violations}
+	}
+	return nil
+}
+
+// respondScanPolicyError writes the response for an enforceScanPolicy error.
+// Only findings the policy blocks are a 403; a scan that could not be
+// completed still blocks the request, but as a server error.
+func respondScanPolicyError(c *gin.Context, err error) {
+	var blocked *scanPolicyError
+	if errors.As(err, &blocked) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "violations": blocked.Violations})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+func scanErrorStatus(err error) int {
+	switch {
+	case docker.IsNotFound(err):
+		return http.StatusNotFound
+	case errors.Is(err, scanner.ErrNoDatabase):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// getImageScan returns the vulnerability scan of an image, scanning it
+// first when there is no current result or ?refresh=true is given.
+func (s *Server) getImageScan(c *gin.Context) {
+	scan, err := s.scanImage(c.Param("id"), c.Query("refresh") == "true")
+	if err != nil {
+		c.JSON(scanErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"scan": scan})
+}
+
+func (s *Server) getScanPolicy(c *gin.Context) {
+	policy, err := s.loadScanPolicy()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policy": policy})
+}
+
+func (s *Server) updateScanPolicy(c *gin.Context) {
+	var policy scanner.Policy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := policy.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	encoded, err := json.Marshal(policy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.db.SetSetting(scanPolicySetting, string(encoded)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.logger.Info("Image scan policy updated", map[string]interface{}{"user": currentUser(c).Username})
+	c.JSON(http.StatusOK, gin.H{"policy": policy, "message": "Scan policy updated successfully"})
+}
+
+func (s *Server) getVulnDatabase(c *gin.Context) {
+	info := gin.H{"scanner": s.imageScanner.Name(), "version": s.imageScanner.DatabaseVersion()}
+	if offline, ok := s.imageScanner.(*scanner.Offline); ok {
+		info["vulnerabilities"] = offline.Advisories()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"database": info})
+}
+
+// importVulnDatabase replaces the offline scanner's vulnerability database
+// with the JSON file sent as the request body.
+func (s *Server) importVulnDatabase(c *gin.Context) {
+	offline, ok := s.imageScanner.(*scanner.Offline)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "The configured scanner has no importable database"})
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, s.config.MaxUploadBytes)
+	db, err := offline.Import(body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("Database exceeds the limit of %d bytes", s.config.MaxUploadBytes),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.logger.Info("Vulnerability database imported", map[string]interface{}{
+		"version": db.Version, "vulnerabilities": len(db.Advisories), "user": currentUser(c).Username,
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"database": gin.H{"scanner": offline.Name(), "version": db.Version, "vulnerabilities": len(db.Advisories)},
+		"message":  "Vulnerability database imported successfully",
+	})
+}
@@ -3,13 +3,19 @@ package api
 import (
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
+	"cyber-container-platform/internal/autoheal"
+	"cyber-container-platform/internal/backup"
 	"cyber-container-platform/internal/config"
 	"cyber-container-platform/internal/database"
 	"cyber-container-platform/internal/docker"
 	"cyber-container-platform/internal/websocket"
 	"cyber-container-platform/internal/middleware"
+	"cyber-container-platform/internal/registry"
+	"cyber-container-platform/internal/scanner"
+	"cyber-container-platform/internal/scheduler"
 	"cyber-container-platform/internal/monitoring"
 	"cyber-container-platform/internal/logger"
 
@@ -25,6 +31,16 @@ type Server struct {
 	router       *gin.Engine
 	logger       *logger.Logger
 	metrics      *monitoring.Metrics
+	backups      *backup.Manager
+	scheduler    *scheduler.Scheduler
+	registries   *registry.Store
+	imageScanner scanner.Scanner
+	// updateMu serializes image update checks and automatic updates
+	updateMu sync.Mutex
+	healer   *autoheal.Tracker
+	// portMu serializes host port checks and allocation with the start of
+	// the container that binds them
+	portMu sync.Mutex
 }
 
 func NewServer(cfg *config.Config, db *database.Database, dockerClient *docker.Client, wsHub *websocket.Hub) *Server {
@@ -35,6 +51,21 @@ func NewServer(cfg *config.Config, db *database.Database, dockerClient *docker.C
 		wsHub:        wsHub,
 		logger:       logger.New("api", logger.INFO),
 		metrics:      monitoring.GlobalMetrics,
+		backups:      backup.NewManager(db.GetDB(), dockerClient, cfg.BackupDir, cfg.BackupHelperImage),
+		scheduler:    scheduler.New(db.GetDB()),
+		registries:   registry.NewStore(db.GetDB(), cfg.JWTSecret),
+		healer:       autoheal.NewTracker(time.Duration(cfg.AutohealBackoffSeconds) * time.Second),
+	}
+	server.registerJobTasks()
+
+	imageScanner, err := scanner.NewOffline(cfg.VulnDBPath)
+	if err != nil {
+		server.logger.Error("Failed to load vulnerability database", err)
+	}
+	server.imageScanner = imageScanner
+
+	if err := server.seedTemplateCatalog(); err != nil {
+		server.logger.Error("Failed to seed template catalog", err)
 	}
 
 	server.setupRouter()
@@ -118,13 +149,34 @@ func (s *Server) setupRouter() {
 		{
 			containers.GET("", s.listContainers)
 			containers.POST("", s.createContainer)
+			containers.POST("/bulk", s.bulkContainers)
+			// An archive can carry any host configuration, privileged included.
+			containers.POST("/import", s.requireAdmin(), s.importContainer)
 			containers.GET("/:id", s.getContainer)
 			containers.POST("/:id/start", s.startContainer)
 			containers.POST("/:id/stop", s.stopContainer)
+			containers.POST("/:id/restart", s.restartContainer)
+			containers.POST("/:id/pause", s.pauseContainer)
+			containers.POST("/:id/unpause", s.unpauseContainer)
+			containers.POST("/:id/kill", s.killContainer)
+			containers.POST("/:id/rename", s.renameContainer)
+			containers.POST("/:id/update", s.updateContainer)
+			containers.POST("/:id/recreate", s.recreateContainer)
+			containers.GET("/:id/changes", s.getContainerChanges)
+			containers.POST("/:id/commit", s.commitContainer)
+			containers.GET("/:id/autoheal", s.getContainerAutoheal)
+			containers.GET("/:id/export", s.exportContainer)
 			containers.DELETE("/:id", s.removeContainer)
 			containers.GET("/:id/logs", s.getContainerLogs)
 			containers.GET("/:id/stats", s.getContainerStats)
+			containers.GET("/:id/top", s.getContainerTop)
+			containers.POST("/:id/top/:pid/signal", s.signalProcess)
 			containers.POST("/:id/exec", s.execContainer)
+			containers.GET("/:id/files", s.listFiles)
+			containers.GET("/:id/files/stat", s.statFile)
+			containers.GET("/:id/files/download", s.downloadFile)
+			containers.POST("/:id/files", s.uploadFiles)
+			containers.DELETE("/:id/files", s.deleteFile)
 		}
 
 		// Networks
@@ -133,8 +185,13 @@ func (s *Server) setupRouter() {
 		{
 			networks.GET("", s.listNetworks)
 			networks.POST("", s.createNetwork)
+			networks.GET("/configs", s.listNetworkConfigs)
+			networks.POST("/configs/:id/recreate", s.recreateNetwork)
+			networks.DELETE("/configs/:id", s.deleteNetworkConfig)
 			networks.GET("/:id", s.getNetwork)
 			networks.DELETE("/:id", s.removeNetwork)
+			networks.POST("/:id/connect", s.connectNetwork)
+			networks.POST("/:id/disconnect", s.disconnectNetwork)
 		}
 
 		// Volumes
@@ -143,8 +200,22 @@ func (s *Server) setupRouter() {
 		{
 			volumes.GET("", s.listVolumes)
 			volumes.POST("", s.createVolume)
+			volumes.GET("/configs", s.listVolumeConfigs)
+			volumes.POST("/configs/:id/recreate", s.recreateVolume)
+			volumes.DELETE("/configs/:id", s.deleteVolumeConfig)
+			volumes.GET("/backups", s.listVolumeBackups)
+			volumes.GET("/backups/:id/download", s.downloadVolumeBackup)
+			volumes.DELETE("/backups/:id", s.deleteVolumeBackup)
 			volumes.GET("/:name", s.getVolume)
 			volumes.DELETE("/:name", s.removeVolume)
+			volumes.POST("/:name/backup", s.backupVolume)
+			volumes.GET("/:name/backups", s.listVolumeBackups)
+			volumes.POST("/:name/restore", s.restoreVolume)
+			volumes.GET("/:name/files", s.listFiles)
+			volumes.GET("/:name/files/stat", s.statFile)
+			volumes.GET("/:name/files/download", s.downloadFile)
+			volumes.POST("/:name/files", s.uploadFiles)
+			volumes.DELETE("/:name/files", s.deleteFile)
 		}
 
 		// Templates
@@ -153,9 +224,25 @@ func (s *Server) setupRouter() {
 		{
 			templates.GET("", s.listTemplates)
 			templates.POST("", s.createTemplate)
+			templates.POST("/import", s.importTemplate)
 			templates.GET("/:id", s.getTemplate)
+			templates.GET("/:id/export", s.exportTemplate)
 			templates.PUT("/:id", s.updateTemplate)
 			templates.DELETE("/:id", s.deleteTemplate)
+			templates.GET("/:id/shares", s.listTemplateShares)
+			templates.POST("/:id/shares", s.shareTemplate)
+			templates.DELETE("/:id/shares/:shareId", s.unshareTemplate)
+		}
+
+		// Groups
+		groups := api.Group("/groups")
+		groups.Use(s.authMiddleware())
+		{
+			groups.GET("", s.listGroups)
+			groups.POST("", s.createGroup)
+			groups.GET("/:id/members", s.listGroupMembers)
+			groups.POST("/:id/members", s.requireAdmin(), s.addGroupMember)
+			groups.DELETE("/:id/members/:userId", s.removeGroupMember)
 		}
 
 		// Images
@@ -163,15 +250,63 @@ func (s *Server) setupRouter() {
 		images.Use(s.authMiddleware())
 		{
 			images.GET("", s.listImages)
+			images.GET("/:id", s.getImage)
+			images.GET("/:id/scan", s.getImageScan)
+			images.GET("/policy", s.getImagePolicy)
+			images.PUT("/policy", s.requireAdmin(), s.updateImagePolicy)
+			images.GET("/scan/policy", s.getScanPolicy)
+			images.PUT("/scan/policy", s.requireAdmin(), s.updateScanPolicy)
+			images.GET("/scan/database", s.getVulnDatabase)
+			images.POST("/scan/database", s.requireAdmin(), s.importVulnDatabase)
 			images.POST("/pull", s.pullImage)
+			// Pushes authenticate with the stored registry logins.
+			images.POST("/push", s.requireAdmin(), s.pushImage)
+			images.GET("/save", s.saveImages)
+			images.POST("/load", s.loadImages)
+			images.POST("/build", s.buildImage)
+			images.GET("/builds", s.listImageBuilds)
+			images.GET("/builds/:id", s.getImageBuild)
+			images.GET("/commits", s.listImageCommits)
+			images.GET("/updates", s.getImageUpdates)
+			images.POST("/updates/check", s.requireAdmin(), s.runImageUpdateCheck)
+			images.GET("/updates/settings", s.getUpdateSettings)
+			images.PUT("/updates/settings", s.requireAdmin(), s.updateUpdateSettings)
+			images.POST("/:id/tag", s.tagImage)
 			images.DELETE("/:id", s.removeImage)
 		}
 
+		// Topology
+		api.GET("/topology", s.authMiddleware(), s.getTopology)
+
+		// Registry credentials
+		registries := api.Group("/registries")
+		registries.Use(s.authMiddleware(), s.requireAdmin())
+		{
+			registries.GET("", s.listRegistries)
+			registries.POST("", s.saveRegistry)
+			registries.DELETE("/:id", s.deleteRegistry)
+		}
+
+		// Scheduled jobs
+		jobs := api.Group("/jobs")
+		jobs.Use(s.authMiddleware(), s.requireAdmin())
+		{
+			jobs.GET("", s.listJobs)
+			jobs.POST("", s.createJob)
+			jobs.GET("/:id", s.getJob)
+			jobs.GET("/:id/runs", s.listJobRuns)
+			jobs.POST("/:id/pause", s.pauseJob)
+			jobs.POST("/:id/resume", s.resumeJob)
+			jobs.POST("/:id/trigger", s.triggerJob)
+			jobs.DELETE("/:id", s.deleteJob)
+		}
+
 		// System
 		system := api.Group("/system")
 		system.Use(s.authMiddleware())
 		{
 			system.GET("/info", s.getSystemInfo)
+			system.POST("/prune", s.requireAdmin(), s.pruneSystem)
 		}
 	}
 
@@ -185,6 +320,15 @@ func (s *Server) Start() error {
 	// Start metrics collection goroutine
 	go s.collectMetrics()
 
+	// Start scheduled jobs
+	go s.scheduler.Run()
+
+	go s.watchImageUpdates()
+
+	go s.superviseHealth()
+
+	s.failInterruptedBuilds()
+
 	if s.config.SSLEnabled {
 		return s.router.RunTLS(":"+s.config.Port, s.config.CertPath, s.config.KeyPath)
 	}
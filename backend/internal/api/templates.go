@@ -0,0 +1,489 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"cyber-container-platform/internal/database"
+	"cyber-container-platform/internal/templates"
+
+	"github.com/gin-gonic/gin"
+)
+
+const templateColumns = "id, name, description, image, config, version, icon, schema, parameters, source, visibility, created_by, created_at"
+
+// Template visibility levels. Shares grant access on top of visibility.
+const (
+	visibilityPrivate = "private" // creator only
+	visibilityTeam    = "team"    // creator and members of the creator's groups
+	visibilityPublic  = "public"  // every user
+)
+
+func isValidVisibility(visibility string) bool {
+	return visibility == visibilityPrivate || visibility == visibilityTeam || visibility == visibilityPublic
+}
+
+// visibleTemplatesClause restricts container_templates to rows the user may
+// read. Administrators see everything.
+const visibleTemplatesClause = `(? OR visibility = 'public' OR created_by = ?
+	OR (visibility = 'team' AND created_by IN (
+		SELECT peer.user_id FROM group_members self
+		JOIN group_members peer ON peer.group_id = self.group_id
+		WHERE self.user_id = ?))
+	OR id IN (
+		SELECT template_id FROM template_shares
+		WHERE user_id = ? OR group_id IN (SELECT group_id FROM group_members WHERE user_id = ?)))`
+
+func visibleTemplatesArgs(user authUser) []interface{} {
+	return []interface{}{user.IsAdmin(), user.ID, user.ID, user.ID, user.ID}
+}
+
+// maxBundleSize bounds template bundle uploads; bundles are small documents.
+const maxBundleSize = 1 << 20
+
+// templateRecord is a container_templates row.
+type templateRecord struct {
+	ID          int64
+	Name        string
+	Description string
+	Image       string
+	Config      string
+	Version     string
+	Icon        string
+	Schema      map[string]interface{}
+	Parameters  []templates.Parameter
+	Source      string
+	Visibility  string
+	CreatedBy   int64
+	CreatedAt   time.Time
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTemplate(row rowScanner) (*templateRecord, error) {
+	var t templateRecord
+	var schema, parameters string
+	var createdBy sql.NullInt64
+
+	err := row.Scan(&t.ID, &t.Name, &t.Description, &t.Image, &t.Config,
+		&t.Version, &t.Icon, &schema, &parameters, &t.Source, &t.Visibility, &createdBy, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	t.CreatedBy = createdBy.Int64
+
+	if schema != "" {
+		json.Unmarshal([]byte(schema), &t.Schema)
+	}
+	if parameters != "" {
+		json.Unmarshal([]byte(parameters), &t.Parameters)
+	}
+
+	return &t, nil
+}
+
+func (t *templateRecord) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":          t.ID,
+		"name":        t.Name,
+		"description": t.Description,
+		"image":       t.Image,
+		"config":      t.Config,
+		"version":     t.Version,
+		"icon":        t.Icon,
+		"schema":      t.Schema,
+		"parameters":  t.Parameters,
+		"source":      t.Source,
+		"visibility":  t.Visibility,
+		"created_by":  t.CreatedBy,
+		"created_at":  t.CreatedAt,
+	}
+}
+
+// canModify reports whether user may update, delete or share the template.
+// Templates without a creator (built-in and legacy ones) are admin-only.
+func (t *templateRecord) canModify(user authUser) bool {
+	return user.IsAdmin() || (t.CreatedBy != 0 && t.CreatedBy == user.ID)
+}
+
+// findVisibleTemplate loads template id if the caller may see it, writing a
+// 400 or 404 response and returning nil otherwise. Templates the caller cannot
+// see are reported as missing so their existence is not disclosed.
+func (s *Server) findVisibleTemplate(c *gin.Context) *templateRecord {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return nil
+	}
+
+	args := append([]interface{}{id}, visibleTemplatesArgs(currentUser(c))...)
+	record, err := scanTemplate(s.db.GetDB().QueryRow(
+		"SELECT "+templateColumns+" FROM container_templates WHERE id = ? AND "+visibleTemplatesClause,
+		args...,
+	))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return nil
+	}
+
+	return record
+}
+
+func (t *templateRecord) toBundle() *templates.Bundle {
+	return &templates.Bundle{
+		APIVersion: templates.BundleAPIVersion,
+		Kind:       templates.BundleKind,
+		Metadata: templates.Metadata{
+			Name:        t.Name,
+			Description: t.Description,
+			Version:     t.Version,
+			Icon:        t.Icon,
+		},
+		Image:      t.Image,
+		Parameters: t.Parameters,
+		Schema:     t.Schema,
+		Config:     templates.DecodeConfig(t.Config),
+	}
+}
+
+// encodeTemplateExtras serializes the optional structured template fields to
+// the JSON text stored in SQLite. Empty values are stored as "".
+func encodeTemplateExtras(schema map[string]interface{}, parameters []templates.Parameter) (string, string) {
+	var schemaText, parametersText string
+	if len(schema) > 0 {
+		data, _ := json.Marshal(schema)
+		schemaText = string(data)
+	}
+	if len(parameters) > 0 {
+		data, _ := json.Marshal(parameters)
+		parametersText = string(data)
+	}
+	return schemaText, parametersText
+}
+
+func (s *Server) insertTemplateBundle(bundle *templates.Bundle, source string, owner interface{}, visibility string) (int64, error) {
+	return insertTemplate(s.db.GetDB(), bundle, source, owner, visibility)
+}
+
+// sqlExecer is satisfied by both *sql.DB and *sql.Tx.
+type sqlExecer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func insertTemplate(db sqlExecer, bundle *templates.Bundle, source string, owner interface{}, visibility string) (int64, error) {
+	config, err := bundle.ConfigString()
+	if err != nil {
+		return 0, err
+	}
+	schema, parameters := encodeTemplateExtras(bundle.Schema, bundle.Parameters)
+
+	result, err := db.Exec(
+		`INSERT INTO container_templates (name, description, image, config, version, icon, schema, parameters, source, created_by, visibility)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		bundle.Metadata.Name, bundle.Metadata.Description, bundle.Image, config,
+		bundle.Metadata.Version, bundle.Metadata.Icon, schema, parameters, source, owner, visibility,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// upsertBuiltinTemplate updates the built-in template with the bundle's name
+// to the bundle's content, inserting it if it does not exist.
+func upsertBuiltinTemplate(db sqlExecer, bundle *templates.Bundle) error {
+	config, err := bundle.ConfigString()
+	if err != nil {
+		return err
+	}
+	schema, parameters := encodeTemplateExtras(bundle.Schema, bundle.Parameters)
+
+	result, err := db.Exec(
+		`UPDATE container_templates SET description = ?, image = ?, config = ?, version = ?, icon = ?, schema = ?, parameters = ?
+		WHERE source = 'builtin' AND name = ?`,
+		bundle.Metadata.Description, bundle.Image, config,
+		bundle.Metadata.Version, bundle.Metadata.Icon, schema, parameters, bundle.Metadata.Name,
+	)
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated > 0 {
+		return err
+	}
+
+	_, err = insertTemplate(db, bundle, "builtin", nil, visibilityPublic)
+	return err
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func (s *Server) exportTemplate(c *gin.Context) {
+	format := c.DefaultQuery("format", "yaml")
+	if format != "yaml" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be yaml or json"})
+		return
+	}
+
+	record := s.findVisibleTemplate(c)
+	if record == nil {
+		return
+	}
+
+	data, err := templates.Marshal(record.toBundle(), format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	contentType := "application/x-yaml"
+	if format == "json" {
+		contentType = "application/json"
+	}
+	filename := strings.Trim(unsafeFilenameChars.ReplaceAllString(strings.ToLower(record.Name), "-"), "-")
+	if filename == "" {
+		filename = "template"
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=\""+filename+"."+format+"\"")
+	c.Data(http.StatusOK, contentType, data)
+}
+
+func (s *Server) importTemplate(c *gin.Context) {
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBundleSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read bundle"})
+		return
+	}
+	if len(data) > maxBundleSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Bundle exceeds 1 MiB"})
+		return
+	}
+
+	bundle, err := templates.Parse(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !isValidImageName(bundle.Image) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image name format"})
+		return
+	}
+
+	visibility := c.DefaultQuery("visibility", visibilityPrivate)
+	if !isValidVisibility(visibility) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Visibility must be private, team or public"})
+		return
+	}
+
+	id, err := s.insertTemplateBundle(bundle, "import", currentUser(c).ownerID(), visibility)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": id, "message": "Template imported successfully"})
+}
+
+// templateCatalogSetting records which catalog revision has been seeded so
+// that templates deleted by users are not recreated on every restart.
+const templateCatalogSetting = "template_catalog_version"
+
+// seedTemplateCatalog brings the built-in templates in container_templates
+// up to templates.CatalogVersion. It does nothing once that revision has been
+// seeded; otherwise every catalog entry is updated in place or inserted, and
+// the revision is recorded in the same transaction.
+func (s *Server) seedTemplateCatalog() error {
+	seeded, err := s.db.GetSetting(templateCatalogSetting)
+	if err != nil {
+		return err
+	}
+	if seeded == templates.CatalogVersion {
+		return nil
+	}
+
+	bundles, err := templates.Catalog()
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.GetDB().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, bundle := range bundles {
+		if err := upsertBuiltinTemplate(tx, bundle); err != nil {
+			return fmt.Errorf("failed to seed template %s: %w", bundle.Metadata.Name, err)
+		}
+	}
+	if err := database.SetSettingTx(tx, templateCatalogSetting, templates.CatalogVersion); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.logger.Info("Seeded built-in template catalog", map[string]interface{}{
+		"templates": len(bundles),
+		"version":   templates.CatalogVersion,
+		"previous":  seeded,
+	})
+	return nil
+}
+
+// ShareTemplateRequest grants a user (by username) or a group access to a template.
+type ShareTemplateRequest struct {
+	Username string `json:"username"`
+	GroupID  int64  `json:"group_id"`
+}
+
+func (s *Server) listTemplateShares(c *gin.Context) {
+	record := s.findVisibleTemplate(c)
+	if record == nil {
+		return
+	}
+	if !record.canModify(currentUser(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the template owner can view its shares"})
+		return
+	}
+
+	rows, err := s.db.GetDB().Query(
+		`SELECT s.id, s.user_id, u.username, s.group_id, g.name, s.created_at
+		FROM template_shares s
+		LEFT JOIN users u ON u.id = s.user_id
+		LEFT JOIN user_groups g ON g.id = s.group_id
+		WHERE s.template_id = ? ORDER BY s.created_at`,
+		record.ID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var shares []map[string]interface{}
+	for rows.Next() {
+		var id int64
+		var userID, groupID sql.NullInt64
+		var username, groupName sql.NullString
+		var createdAt time.Time
+
+		if err := rows.Scan(&id, &userID, &username, &groupID, &groupName, &createdAt); err != nil {
+			continue
+		}
+
+		share := map[string]interface{}{"id": id, "created_at": createdAt}
+		if userID.Valid {
+			share["user_id"] = userID.Int64
+			share["username"] = username.String
+		}
+		if groupID.Valid {
+			share["group_id"] = groupID.Int64
+			share["group"] = groupName.String
+		}
+		shares = append(shares, share)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"shares": shares})
+}
+
+func (s *Server) shareTemplate(c *gin.Context) {
+	record := s.findVisibleTemplate(c)
+	if record == nil {
+		return
+	}
+	user := currentUser(c)
+	if !record.canModify(user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the template owner can share it"})
+		return
+	}
+
+	var req ShareTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (req.Username == "") == (req.GroupID == 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Specify exactly one of username or group_id"})
+		return
+	}
+
+	var userID, groupID interface{}
+	if req.Username != "" {
+		var id int64
+		if err := s.db.GetDB().QueryRow("SELECT id FROM users WHERE username = ?", req.Username).Scan(&id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		userID = id
+	} else {
+		var id int64
+		if err := s.db.GetDB().QueryRow("SELECT id FROM user_groups WHERE id = ?", req.GroupID).Scan(&id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+			return
+		}
+		groupID = id
+	}
+
+	var existing int64
+	err := s.db.GetDB().QueryRow(
+		"SELECT id FROM template_shares WHERE template_id = ? AND user_id IS ? AND group_id IS ?",
+		record.ID, userID, groupID,
+	).Scan(&existing)
+	if err == nil {
+		c.JSON(http.StatusOK, gin.H{"id": existing, "message": "Template already shared"})
+		return
+	}
+
+	result, err := s.db.GetDB().Exec(
+		"INSERT INTO template_shares (template_id, user_id, group_id, created_by) VALUES (?, ?, ?, ?)",
+		record.ID, userID, groupID, user.ownerID(),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	c.JSON(http.StatusCreated, gin.H{"id": id, "message": "Template shared successfully"})
+}
+
+func (s *Server) unshareTemplate(c *gin.Context) {
+	record := s.findVisibleTemplate(c)
+	if record == nil {
+		return
+	}
+	if !record.canModify(currentUser(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the template owner can change its shares"})
+		return
+	}
+
+	shareID, err := strconv.ParseInt(c.Param("shareId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid share ID"})
+		return
+	}
+
+	result, err := s.db.GetDB().Exec("DELETE FROM template_shares WHERE id = ? AND template_id = ?", shareID, record.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Share removed successfully"})
+}
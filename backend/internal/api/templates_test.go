@@ -0,0 +1,59 @@
+package api
+
+import (
+	"testing"
+
+	"cyber-container-platform/internal/templates"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func builtinTemplates(t *testing.T, s *Server) map[string]string {
+	t.Helper()
+	rows, err := s.db.GetDB().Query("SELECT name, description FROM container_templates WHERE source = 'builtin'")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	found := map[string]string{}
+	for rows.Next() {
+		var name, description string
+		require.NoError(t, rows.Scan(&name, &description))
+		_, duplicate := found[name]
+		assert.False(t, duplicate, "%s seeded twice", name)
+		found[name] = description
+	}
+	require.NoError(t, rows.Err())
+	return found
+}
+
+func TestSeedTemplateCatalog(t *testing.T) {
+	s := newTestServer(t)
+	bundles, err := templates.Catalog()
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(bundles), 2)
+	first, second := bundles[0].Metadata, bundles[1].Metadata
+
+	seeded := builtinTemplates(t, s)
+	assert.Len(t, seeded, len(bundles))
+
+	// A deleted template stays deleted while the revision is unchanged.
+	_, err = s.db.GetDB().Exec("DELETE FROM container_templates WHERE source = 'builtin' AND name = ?", first.Name)
+	require.NoError(t, err)
+	_, err = s.db.GetDB().Exec("UPDATE container_templates SET description = 'stale' WHERE source = 'builtin' AND name = ?", second.Name)
+	require.NoError(t, err)
+	require.NoError(t, s.seedTemplateCatalog())
+	assert.NotContains(t, builtinTemplates(t, s), first.Name)
+
+	// A different revision updates the catalog in place and restores it.
+	require.NoError(t, s.db.SetSetting(templateCatalogSetting, "0"))
+	require.NoError(t, s.seedTemplateCatalog())
+	upgraded := builtinTemplates(t, s)
+	assert.Len(t, upgraded, len(bundles))
+	assert.Equal(t, first.Description, upgraded[first.Name])
+	assert.Equal(t, second.Description, upgraded[second.Name])
+
+	version, err := s.db.GetSetting(templateCatalogSetting)
+	require.NoError(t, err)
+	assert.Equal(t, templates.CatalogVersion, version)
+}
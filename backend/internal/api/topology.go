@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net/http"
+
+	"cyber-container-platform/internal/topology"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) getTopology(c *gin.Context) {
+	networks, err := s.dockerClient.ListNetworks()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	containers, err := s.dockerClient.ListContainers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"topology": topology.Build(networks, containers)})
+}
@@ -0,0 +1,320 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cyber-container-platform/internal/docker"
+	"cyber-container-platform/internal/imagepolicy"
+	"cyber-container-platform/internal/updates"
+	"cyber-container-platform/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+const updateSettingsSetting = "image_update_settings"
+
+// ImageUpdate is the last update check of a watched container.
+type ImageUpdate struct {
+	ContainerID     string    `json:"container_id"`
+	ContainerName   string    `json:"container_name"`
+	Image           string    `json:"image"`
+	Mode            string    `json:"mode"`
+	CurrentDigest   string    `json:"current_digest"`
+	LatestDigest    string    `json:"latest_digest"`
+	UpdateAvailable bool      `json:"update_available"`
+	Error           string    `json:"error,omitempty"`
+	CheckedAt       time.Time `json:"checked_at"`
+}
+
+const imageUpdateColumns = "container_id, container_name, image, mode, current_digest, latest_digest, update_available, error, checked_at"
+
+func scanImageUpdate(row rowScanner) (*ImageUpdate, error) {
+	var u ImageUpdate
+	err := row.Scan(&u.ContainerID, &u.ContainerName, &u.Image, &u.Mode, &u.CurrentDigest, &u.LatestDigest,
+		&u.UpdateAvailable, &u.Error, &u.CheckedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (s *Server) listImageUpdates(availableOnly bool) ([]*ImageUpdate, error) {
+	query := "SELECT " + imageUpdateColumns + " FROM image_updates"
+	if availableOnly {
+		query += " WHERE update_available = 1"
+	}
+	rows, err := s.db.GetDB().Query(query + " ORDER BY container_name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*ImageUpdate{}
+	for rows.Next() {
+		u, err := scanImageUpdate(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, u)
+	}
+	return list, rows.Err()
+}
+
+func (s *Server) saveImageUpdate(u *ImageUpdate) error {
+	_, err := s.db.GetDB().Exec(
+		`INSERT INTO image_updates (`+imageUpdateColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(container_id) DO UPDATE SET container_name = excluded.container_name, image = excluded.image,
+			mode = excluded.mode, current_digest = excluded.current_digest, latest_digest = excluded.latest_digest,
+			update_available = excluded.update_available, error = excluded.error, checked_at = excluded.checked_at`,
+		u.ContainerID, u.ContainerName, u.Image, u.Mode, u.CurrentDigest, u.LatestDigest, u.UpdateAvailable, u.Error, u.CheckedAt,
+	)
+	return err
+}
+
+func (s *Server) loadUpdateSettings() (updates.Settings, error) {
+	var settings updates.Settings
+	value, err := s.db.GetSetting(updateSettingsSetting)
+	if err != nil || value == "" {
+		return settings, err
+	}
+	if err := json.Unmarshal([]byte(value), &settings); err != nil {
+		return settings, fmt.Errorf("corrupt image update settings: %w", err)
+	}
+	return settings, nil
+}
+
+// checkContainerImage compares the digest a container's image tag has in
+// its registry with the digest the container runs.
+func (s *Server) checkContainerImage(cont docker.ContainerInfo, mode string) *ImageUpdate {
+	u := &ImageUpdate{
+		ContainerID:   cont.ID,
+		ContainerName: cont.Name,
+		Image:         cont.Image,
+		Mode:          mode,
+		CheckedAt:     time.Now().UTC(),
+	}
+	fail := func(err error) *ImageUpdate {
+		u.Error = err.Error()
+		return u
+	}
+
+	// The listing shows the image ID once the tag has moved on locally;
+	// the configured reference is still the tag.
+	inspect, err := s.dockerClient.InspectContainer(cont.ID)
+	if err != nil {
+		return fail(err)
+	}
+	u.Image = inspect.Config.Image
+	if err := updates.Trackable(u.Image); err != nil {
+		return fail(err)
+	}
+
+	current, err := s.dockerClient.InspectImage(inspect.Image)
+	if err != nil {
+		return fail(err)
+	}
+	if u.CurrentDigest = imagepolicy.Digest(u.Image, current.RepoDigests); u.CurrentDigest == "" {
+		return fail(fmt.Errorf("the image was not pulled from a registry"))
+	}
+
+	auth, err := s.registries.AuthFor(u.Image)
+	if err != nil {
+		return fail(err)
+	}
+	if u.LatestDigest, err = s.dockerClient.RemoteDigest(u.Image, auth); err != nil {
+		return fail(err)
+	}
+	u.UpdateAvailable = u.LatestDigest != u.CurrentDigest
+	return u
+}
+
+// checkImageUpdates checks every watched container and replaces the
+// stored results. An update seen for the first time is broadcast as an
+// image_update_available event.
+func (s *Server) checkImageUpdates() ([]*ImageUpdate, error) {
+	containers, err := s.dockerClient.ListContainers()
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.listImageUpdates(false)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]*ImageUpdate, len(previous))
+	for _, u := range previous {
+		known[u.ContainerID] = u
+	}
+
+	checked := []*ImageUpdate{}
+	for _, cont := range containers {
+		mode := updates.ModeOf(cont.Labels)
+		if mode == "" {
+			continue
+		}
+
+		u := s.checkContainerImage(cont, mode)
+		if err := s.saveImageUpdate(u); err != nil {
+			return nil, err
+		}
+		checked = append(checked, u)
+
+		if prev := known[u.ContainerID]; u.UpdateAvailable && (prev == nil || !prev.UpdateAvailable || prev.LatestDigest != u.LatestDigest) {
+			s.logger.Info("Image update available", map[string]interface{}{
+				"container": u.ContainerName, "image": u.Image, "digest": u.LatestDigest,
+			})
+			s.wsHub.Broadcast(websocket.Message{Type: "image_update_available", Data: u})
+		}
+		delete(known, u.ContainerID)
+	}
+
+	// Containers that are gone or no longer watched.
+	for id := range known {
+		if _, err := s.db.GetDB().Exec("DELETE FROM image_updates WHERE container_id = ?", id); err != nil {
+			return nil, err
+		}
+	}
+	return checked, nil
+}
+
+// applyImageUpdates recreates containers in auto mode that have an update,
+// if the maintenance window is open. A failed update is not retried until
+// the next check clears its error.
+func (s *Server) applyImageUpdates(now time.Time) {
+	settings, err := s.loadUpdateSettings()
+	if err != nil {
+		s.logger.Error("Failed to load image update settings", err)
+		return
+	}
+	if !settings.CanApply(now) {
+		return
+	}
+
+	pending, err := s.listImageUpdates(true)
+	if err != nil {
+		s.logger.Error("Failed to list image updates", err)
+		return
+	}
+	for _, u := range pending {
+		if u.Mode != updates.ModeAuto || u.Error != "" {
+			continue
+		}
+
+		result, _, err := s.recreate(u.ContainerID, docker.RecreateOptions{
+			Image:         u.Image,
+			StopTimeout:   s.config.StopTimeout,
+			HealthTimeout: defaultHealthTimeout,
+		}, true)
+		if err != nil {
+			s.logger.Error("Automatic image update failed", err, map[string]interface{}{"container": u.ContainerName})
+			u.Error = "automatic update failed: " + err.Error()
+			s.saveImageUpdate(u)
+			s.wsHub.Broadcast(websocket.Message{Type: "image_update_failed", Data: u})
+			continue
+		}
+
+		// The replacement has a new ID; the next check picks it up.
+		s.db.GetDB().Exec("DELETE FROM image_updates WHERE container_id = ?", u.ContainerID)
+		s.wsHub.Broadcast(websocket.Message{Type: "image_update_applied", Data: gin.H{
+			"previous": u, "container": result,
+		}})
+	}
+}
+
+// watchImageUpdates checks watched containers every configured interval
+// and applies automatic updates whenever the maintenance window is open.
+func (s *Server) watchImageUpdates() {
+	if s.config.ImageUpdateCheckMinutes <= 0 {
+		return
+	}
+	interval := time.Duration(s.config.ImageUpdateCheckMinutes) * time.Minute
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	var lastCheck time.Time
+	for now := range ticker.C {
+		s.updateMu.Lock()
+		if now.Sub(lastCheck) >= interval {
+			lastCheck = now
+			if _, err := s.checkImageUpdates(); err != nil {
+				s.logger.Error("Image update check failed", err)
+			}
+		}
+		s.applyImageUpdates(now)
+		s.updateMu.Unlock()
+	}
+}
+
+func (s *Server) getImageUpdates(c *gin.Context) {
+	availableOnly, ok := boolQuery(c, "available", false)
+	if !ok {
+		return
+	}
+
+	list, err := s.listImageUpdates(availableOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updates": list})
+}
+
+// runImageUpdateCheck checks all watched containers now.
+func (s *Server) runImageUpdateCheck(c *gin.Context) {
+	if !s.updateMu.TryLock() {
+		c.JSON(http.StatusConflict, gin.H{"error": "An update check is already running"})
+		return
+	}
+	defer s.updateMu.Unlock()
+
+	list, err := s.checkImageUpdates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updates": list, "message": "Update check completed"})
+}
+
+func (s *Server) getUpdateSettings(c *gin.Context) {
+	settings, err := s.loadUpdateSettings()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"settings": settings, "check_interval_minutes": s.config.ImageUpdateCheckMinutes}
+	if settings.MaintenanceWindow != nil {
+		response["window_open"] = settings.MaintenanceWindow.Open(time.Now())
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (s *Server) updateUpdateSettings(c *gin.Context) {
+	var settings updates.Settings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := settings.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	encoded, err := json.Marshal(settings)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.db.SetSetting(updateSettingsSetting, string(encoded)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.logger.Info("Image update settings changed", map[string]interface{}{"user": currentUser(c).Username})
+	c.JSON(http.StatusOK, gin.H{"settings": settings, "message": "Update settings saved successfully"})
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cyber-container-platform/internal/docker"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (r CreateVolumeRequest) volumeOptions() docker.VolumeOptions {
+	return docker.VolumeOptions{
+		Name:    r.Name,
+		Driver:  r.Driver,
+		Options: r.Options,
+		Labels:  r.Labels,
+	}
+}
+
+func validateVolumeOptions(opts docker.VolumeOptions) error {
+	if !docker.ValidVolumeName(opts.Name) {
+		return fmt.Errorf("invalid volume name %q", opts.Name)
+	}
+	for key := range opts.Options {
+		if key == "" {
+			return fmt.Errorf("driver option names must not be empty")
+		}
+	}
+	for key := range opts.Labels {
+		if key == "" {
+			return fmt.Errorf("label keys must not be empty")
+		}
+	}
+	return nil
+}
+
+// VolumeUser is a container that mounts a volume.
+type VolumeUser struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	State       string `json:"state"`
+	Destination string `json:"destination"`
+	ReadWrite   bool   `json:"read_write"`
+}
+
+// volumeUsers lists the containers, running or not, that mount the volume.
+func (s *Server) volumeUsers(name string) ([]VolumeUser, error) {
+	containers, err := s.dockerClient.ListContainers()
+	if err != nil {
+		return nil, err
+	}
+
+	users := []VolumeUser{}
+	for _, container := range containers {
+		for _, mount := range container.Mounts {
+			if mount.Type == "volume" && mount.Name == name {
+				users = append(users, VolumeUser{
+					ID:          container.ID,
+					Name:        container.Name,
+					State:       container.State,
+					Destination: mount.Destination,
+					ReadWrite:   mount.ReadWrite,
+				})
+			}
+		}
+	}
+	return users, nil
+}
+
+// saveVolumeConfig records the desired configuration of a volume, replacing
+// any earlier record of the same name.
+func (s *Server) saveVolumeConfig(opts docker.VolumeOptions, user authUser) error {
+	config, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.GetDB().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM volume_configs WHERE name = ?", opts.Name); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO volume_configs (name, driver, config, created_by) VALUES (?, ?, ?, ?)",
+		opts.Name, opts.Driver, string(config), user.ownerID(),
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+const volumeConfigColumns = "id, name, driver, config, created_by, created_at"
+
+type volumeConfigRecord struct {
+	ID        int64                `json:"id"`
+	Name      string               `json:"name"`
+	Driver    string               `json:"driver"`
+	Config    docker.VolumeOptions `json:"config"`
+	CreatedBy int64                `json:"created_by"`
+	CreatedAt time.Time            `json:"created_at"`
+}
+
+func scanVolumeConfig(row rowScanner) (*volumeConfigRecord, error) {
+	var record volumeConfigRecord
+	var config string
+	var createdBy sql.NullInt64
+
+	if err := row.Scan(&record.ID, &record.Name, &record.Driver, &config, &createdBy, &record.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(config), &record.Config); err != nil {
+		return nil, fmt.Errorf("corrupt volume config %d: %w", record.ID, err)
+	}
+	record.CreatedBy = createdBy.Int64
+
+	return &record, nil
+}
+
+func (s *Server) findVolumeConfig(name string) (*volumeConfigRecord, error) {
+	return scanVolumeConfig(s.db.GetDB().QueryRow(
+		"SELECT "+volumeConfigColumns+" FROM volume_configs WHERE name = ?", name,
+	))
+}
+
+func (s *Server) listVolumeConfigs(c *gin.Context) {
+	rows, err := s.db.GetDB().Query("SELECT " + volumeConfigColumns + " FROM volume_configs ORDER BY name")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	var configs []*volumeConfigRecord
+	for rows.Next() {
+		record, err := scanVolumeConfig(rows)
+		if err != nil {
+			continue
+		}
+		configs = append(configs, record)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"configs": configs})
+}
+
+// recreateVolume creates an (empty) volume from its stored desired
+// configuration. Use a backup restore to bring back its contents.
+func (s *Server) recreateVolume(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid volume config ID"})
+		return
+	}
+
+	record, err := scanVolumeConfig(s.db.GetDB().QueryRow(
+		"SELECT "+volumeConfigColumns+" FROM volume_configs WHERE id = ?", id,
+	))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Volume config not found"})
+		return
+	}
+
+	if _, err := s.dockerClient.InspectVolume(record.Name); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Volume " + record.Name + " already exists"})
+		return
+	}
+
+	vol, err := s.dockerClient.CreateVolume(record.Config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"volume": vol, "message": "Volume recreated successfully"})
+}
+
+func (s *Server) deleteVolumeConfig(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid volume config ID"})
+		return
+	}
+
+	result, err := s.db.GetDB().Exec("DELETE FROM volume_configs WHERE id = ?", id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Volume config not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Volume config deleted successfully"})
+}
@@ -0,0 +1,155 @@
+// Package autoheal decides when the health supervisor restarts containers
+// whose healthcheck keeps failing, and how long it backs off between
+// restarts that do not help.
+package autoheal
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Label opts a container into auto-heal when set to "true".
+	Label = "cyber.platform.autoheal"
+	// ThresholdLabel overrides the number of failed probes in a row after
+	// which the container is restarted.
+	ThresholdLabel = "cyber.platform.autoheal.threshold"
+)
+
+// MaxBackoff caps the wait between restarts, unless the base backoff is
+// longer.
+const MaxBackoff = 30 * time.Minute
+
+// Enabled reports whether a container's labels opt it into auto-heal.
+func Enabled(labels map[string]string) bool {
+	enabled, err := strconv.ParseBool(strings.TrimSpace(labels[Label]))
+	return err == nil && enabled
+}
+
+// Threshold returns the failing streak at which a container is restarted:
+// its ThresholdLabel if that is a positive number, otherwise def.
+func Threshold(labels map[string]string, def int) int {
+	if n, err := strconv.Atoi(strings.TrimSpace(labels[ThresholdLabel])); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+// Action is what the supervisor should do after a health report.
+type Action int
+
+const (
+	// None leaves the container alone.
+	None Action = iota
+	// Restart restarts the container now.
+	Restart
+	// Recovered reports that a restarted container became healthy.
+	Recovered
+)
+
+// State is what the tracker remembers of a container it restarted.
+type State struct {
+	// Restarts counts the restarts since the container was last healthy.
+	Restarts    int       `json:"restarts"`
+	LastRestart time.Time `json:"last_restart"`
+	// NextAttempt is the earliest time of the next restart.
+	NextAttempt time.Time `json:"next_attempt"`
+}
+
+// Tracker follows the health reports of containers. It is safe for
+// concurrent use.
+type Tracker struct {
+	mu         sync.Mutex
+	backoff    time.Duration
+	maxBackoff time.Duration
+	states     map[string]*State
+}
+
+// NewTracker returns a tracker that waits backoff after a first restart,
+// doubling the wait with every further restart up to MaxBackoff.
+func NewTracker(backoff time.Duration) *Tracker {
+	maxBackoff := MaxBackoff
+	if backoff > maxBackoff {
+		maxBackoff = backoff
+	}
+	return &Tracker{backoff: backoff, maxBackoff: maxBackoff, states: make(map[string]*State)}
+}
+
+// Observe takes a health report of a container: its health status and the
+// number of probes that failed in a row.
+func (t *Tracker) Observe(id, status string, failingStreak, threshold int, now time.Time) Action {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	st := t.states[id]
+	switch status {
+	case "healthy":
+		if st == nil {
+			return None
+		}
+		delete(t.states, id)
+		return Recovered
+	case "unhealthy":
+		if failingStreak < threshold {
+			return None
+		}
+		if st != nil && now.Before(st.NextAttempt) {
+			return None
+		}
+		return Restart
+	default:
+		// Still starting, possibly after a restart; keep the backoff.
+		return None
+	}
+}
+
+// Restarted records a restart attempt, successful or not, and schedules
+// the earliest next one.
+func (t *Tracker) Restarted(id string, now time.Time) State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	st := t.states[id]
+	if st == nil {
+		st = &State{}
+		t.states[id] = st
+	}
+	st.Restarts++
+
+	delay := t.backoff
+	for i := 1; i < st.Restarts && delay < t.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > t.maxBackoff {
+		delay = t.maxBackoff
+	}
+	st.LastRestart = now
+	st.NextAttempt = now.Add(delay)
+	return *st
+}
+
+// State returns what the tracker remembers of a container, if anything.
+func (t *Tracker) State(id string) (State, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	st, ok := t.states[id]
+	if !ok {
+		return State{}, false
+	}
+	return *st, true
+}
+
+// Retain forgets containers that are not in ids, such as removed ones.
+func (t *Tracker) Retain(ids map[string]bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id := range t.states {
+		if !ids[id] {
+			delete(t.states, id)
+		}
+	}
+}
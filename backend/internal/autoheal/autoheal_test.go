@@ -0,0 +1,54 @@
+package autoheal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabels(t *testing.T) {
+	assert.True(t, Enabled(map[string]string{Label: "true"}))
+	assert.False(t, Enabled(map[string]string{Label: "no"}))
+	assert.False(t, Enabled(nil))
+
+	assert.Equal(t, 5, Threshold(map[string]string{ThresholdLabel: "5"}, 3))
+	assert.Equal(t, 3, Threshold(map[string]string{ThresholdLabel: "0"}, 3))
+	assert.Equal(t, 3, Threshold(nil, 3))
+}
+
+func TestTrackerBacksOff(t *testing.T) {
+	tr := NewTracker(30 * time.Second)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, None, tr.Observe("a", "unhealthy", 2, 3, now), "below the threshold")
+	assert.Equal(t, Restart, tr.Observe("a", "unhealthy", 3, 3, now))
+	st := tr.Restarted("a", now)
+	assert.Equal(t, now.Add(30*time.Second), st.NextAttempt)
+
+	assert.Equal(t, None, tr.Observe("a", "starting", 0, 3, now.Add(10*time.Second)))
+	assert.Equal(t, None, tr.Observe("a", "unhealthy", 3, 3, now.Add(20*time.Second)), "backing off")
+	assert.Equal(t, Restart, tr.Observe("a", "unhealthy", 3, 3, now.Add(30*time.Second)))
+	st = tr.Restarted("a", now.Add(30*time.Second))
+	assert.Equal(t, 2, st.Restarts)
+	assert.Equal(t, now.Add(90*time.Second), st.NextAttempt, "the wait doubles")
+
+	assert.Equal(t, Recovered, tr.Observe("a", "healthy", 0, 3, now.Add(2*time.Minute)))
+	_, tracked := tr.State("a")
+	assert.False(t, tracked, "recovery resets the backoff")
+	assert.Equal(t, None, tr.Observe("a", "healthy", 0, 3, now.Add(3*time.Minute)))
+}
+
+func TestTrackerCapsBackoff(t *testing.T) {
+	tr := NewTracker(time.Minute)
+	now := time.Now()
+	var st State
+	for i := 0; i < 10; i++ {
+		st = tr.Restarted("a", now)
+	}
+	assert.Equal(t, now.Add(MaxBackoff), st.NextAttempt)
+
+	tr.Retain(map[string]bool{"b": true})
+	_, tracked := tr.State("a")
+	assert.False(t, tracked)
+}
@@ -0,0 +1,427 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"cyber-container-platform/internal/docker"
+)
+
+var (
+	ErrBackupNotFound = errors.New("backup not found")
+	ErrVolumeExists   = errors.New("volume already exists")
+	ErrChecksum       = errors.New("backup file does not match its recorded checksum")
+	ErrCorrupt        = errors.New("backup file is not a readable archive")
+	ErrBusy           = errors.New("another backup or restore of this volume is in progress")
+)
+
+// Backup statuses.
+const (
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Record is a row of volume_backups.
+type Record struct {
+	ID         int64             `json:"id"`
+	Volume     string            `json:"volume"`
+	File       string            `json:"file"`
+	SizeBytes  int64             `json:"size_bytes"`
+	Checksum   string            `json:"checksum"`
+	Driver     string            `json:"driver"`
+	DriverOpts map[string]string `json:"driver_options,omitempty"`
+	Labels     map[string]string `json:"labels"`
+	Status     string            `json:"status"`
+	Error      string            `json:"error,omitempty"`
+	DurationMs int64             `json:"duration_ms"`
+	CreatedBy  int64             `json:"created_by"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// Manager writes volume backups as gzip-compressed tar files under dir and
+// records their metadata in SQLite.
+type Manager struct {
+	db          *sql.DB
+	docker      *docker.Client
+	dir         string
+	helperImage string
+
+	mu     sync.Mutex
+	active map[string]bool
+}
+
+func NewManager(db *sql.DB, dockerClient *docker.Client, dir, helperImage string) *Manager {
+	return &Manager{
+		db:          db,
+		docker:      dockerClient,
+		dir:         dir,
+		helperImage: helperImage,
+		active:      make(map[string]bool),
+	}
+}
+
+// lock marks volume as busy so a backup and a restore never overlap.
+func (m *Manager) lock(volume string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.active[volume] {
+		return false
+	}
+	m.active[volume] = true
+	return true
+}
+
+func (m *Manager) unlock(volume string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.active, volume)
+}
+
+// BusyVolumes returns the volumes a backup or restore is currently working on.
+func (m *Manager) BusyVolumes() map[string]bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	busy := make(map[string]bool, len(m.active))
+	for volume := range m.active {
+		busy[volume] = true
+	}
+	return busy
+}
+
+// Backup archives the volume into the backup directory. Failed attempts are
+// recorded too, so the history shows them.
+func (m *Manager) Backup(volume string, createdBy interface{}) (*Record, error) {
+	if !docker.ValidVolumeName(volume) {
+		return nil, fmt.Errorf("invalid volume name %q", volume)
+	}
+	if !m.lock(volume) {
+		return nil, ErrBusy
+	}
+	defer m.unlock(volume)
+
+	vol, err := m.docker.InspectVolume(volume)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	record := &Record{
+		Volume:     volume,
+		Driver:     vol.Driver,
+		DriverOpts: vol.Options,
+		Labels:     vol.Labels,
+		Status:     StatusCompleted,
+	}
+
+	path, size, checksum, err := m.writeArchive(volume, start)
+	if err != nil {
+		record.Status = StatusFailed
+		record.Error = err.Error()
+	} else {
+		record.File = path
+		record.SizeBytes = size
+		record.Checksum = checksum
+	}
+	record.DurationMs = time.Since(start).Milliseconds()
+
+	if insertErr := m.insert(record, createdBy); insertErr != nil {
+		return nil, insertErr
+	}
+	if err != nil {
+		return record, err
+	}
+
+	return record, nil
+}
+
+func (m *Manager) writeArchive(volume string, started time.Time) (string, int64, string, error) {
+	volumeDir := filepath.Join(m.dir, volume)
+	if err := os.MkdirAll(volumeDir, 0750); err != nil {
+		return "", 0, "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	path := filepath.Join(volumeDir, fmt.Sprintf("%s-%s.tar.gz", volume, started.UTC().Format("20060102T150405.000")))
+	partial := path + ".partial"
+
+	file, err := os.OpenFile(partial, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0640)
+	if err != nil {
+		return "", 0, "", fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer os.Remove(partial)
+	defer file.Close()
+
+	archive, err := m.docker.ExportVolume(volume, m.helperImage)
+	if err != nil {
+		return "", 0, "", err
+	}
+	defer archive.Close()
+
+	hasher := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(file, hasher)}
+	gz := gzip.NewWriter(counter)
+
+	if _, err := io.Copy(gz, archive); err != nil {
+		return "", 0, "", fmt.Errorf("failed to archive volume: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return "", 0, "", err
+	}
+	if err := file.Sync(); err != nil {
+		return "", 0, "", err
+	}
+	if err := os.Rename(partial, path); err != nil {
+		return "", 0, "", err
+	}
+
+	return path, counter.n, hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// Restore recreates volume from a backup. An existing volume is only replaced
+// when replace is set, and replacing fails while containers still use it.
+// The backup is verified and extracted into a staging volume before the
+// existing volume is touched, so a bad archive never costs its contents.
+func (m *Manager) Restore(volume string, backupID int64, replace bool) error {
+	if !docker.ValidVolumeName(volume) {
+		return fmt.Errorf("invalid volume name %q", volume)
+	}
+
+	record, err := m.Get(backupID)
+	if err != nil {
+		return err
+	}
+	if record.Status != StatusCompleted {
+		return fmt.Errorf("backup %d did not complete", backupID)
+	}
+
+	if !m.lock(volume) {
+		return ErrBusy
+	}
+	defer m.unlock(volume)
+
+	if err := verifyArchive(record.File, record.Checksum); err != nil {
+		return err
+	}
+
+	// Recreate the volume with the driver, options and labels it was backed
+	// up with.
+	opts := docker.VolumeOptions{Name: volume, Driver: record.Driver, Options: record.DriverOpts, Labels: record.Labels}
+
+	if _, err := m.docker.InspectVolume(volume); err != nil {
+		if !docker.IsNotFound(err) {
+			return err
+		}
+		return m.createAndImport(opts, record.File)
+	}
+	if !replace {
+		return ErrVolumeExists
+	}
+
+	// The staging volume uses the default driver: the recorded driver options
+	// may point at the storage of the volume being replaced.
+	staging := fmt.Sprintf("%s-restore-%d", volume, time.Now().UnixNano())
+	if err := m.createAndImport(docker.VolumeOptions{Name: staging}, record.File); err != nil {
+		return err
+	}
+
+	if err := m.docker.RemoveVolume(volume); err != nil {
+		m.docker.RemoveVolume(staging)
+		return fmt.Errorf("failed to remove existing volume: %w", err)
+	}
+	if err := m.copyVolume(staging, opts); err != nil {
+		return fmt.Errorf("%w; the restored contents are kept in volume %s", err, staging)
+	}
+	m.docker.RemoveVolume(staging)
+	return nil
+}
+
+// createAndImport creates a volume and extracts a backup file into it. The
+// volume is removed again if the extraction fails.
+func (m *Manager) createAndImport(opts docker.VolumeOptions, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := m.docker.CreateVolume(opts); err != nil {
+		return err
+	}
+	if err := m.docker.ImportVolume(opts.Name, m.helperImage, file); err != nil {
+		m.docker.RemoveVolume(opts.Name)
+		return err
+	}
+	return nil
+}
+
+// copyVolume creates a volume and copies the contents of source into it.
+func (m *Manager) copyVolume(source string, opts docker.VolumeOptions) error {
+	if _, err := m.docker.CreateVolume(opts); err != nil {
+		return err
+	}
+
+	archive, err := m.docker.ExportVolume(source, m.helperImage)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	return m.docker.ImportVolume(opts.Name, m.helperImage, archive)
+}
+
+// verifyArchive checks a backup file against its checksum and reads it
+// through as a gzip-compressed tar.
+func verifyArchive(path, expected string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open backup file: %w", err)
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	content := io.TeeReader(file, hasher)
+	readErr := readArchive(content)
+	if _, err := io.Copy(io.Discard, content); err != nil {
+		return err
+	}
+	if hex.EncodeToString(hasher.Sum(nil)) != expected {
+		return ErrChecksum
+	}
+	if readErr != nil {
+		return fmt.Errorf("%w: %v", ErrCorrupt, readErr)
+	}
+	return nil
+}
+
+func readArchive(r io.Reader) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		if _, err := tr.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if _, err := io.Copy(io.Discard, tr); err != nil {
+			return err
+		}
+	}
+	_, err = io.Copy(io.Discard, gz)
+	return err
+}
+
+const recordColumns = "id, volume_name, file_path, size_bytes, checksum, driver, driver_options, labels, status, error, duration_ms, created_by, created_at"
+
+func (m *Manager) insert(record *Record, createdBy interface{}) error {
+	labels, _ := json.Marshal(record.Labels)
+	driverOpts, _ := json.Marshal(record.DriverOpts)
+
+	result, err := m.db.Exec(
+		`INSERT INTO volume_backups (volume_name, file_path, size_bytes, checksum, driver, driver_options, labels, status, error, duration_ms, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.Volume, record.File, record.SizeBytes, record.Checksum, record.Driver, string(driverOpts), string(labels),
+		record.Status, record.Error, record.DurationMs, createdBy,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record backup: %w", err)
+	}
+
+	record.ID, _ = result.LastInsertId()
+	record.CreatedAt = time.Now()
+	if id, ok := createdBy.(int64); ok {
+		record.CreatedBy = id
+	}
+	return nil
+}
+
+func scanRecord(row interface{ Scan(...interface{}) error }) (*Record, error) {
+	var record Record
+	var labels, driverOpts string
+	var createdBy sql.NullInt64
+
+	err := row.Scan(&record.ID, &record.Volume, &record.File, &record.SizeBytes, &record.Checksum,
+		&record.Driver, &driverOpts, &labels, &record.Status, &record.Error, &record.DurationMs, &createdBy, &record.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal([]byte(labels), &record.Labels)
+	json.Unmarshal([]byte(driverOpts), &record.DriverOpts)
+	record.CreatedBy = createdBy.Int64
+	return &record, nil
+}
+
+// Get returns a single backup record.
+func (m *Manager) Get(id int64) (*Record, error) {
+	record, err := scanRecord(m.db.QueryRow("SELECT "+recordColumns+" FROM volume_backups WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrBackupNotFound
+	}
+	return record, err
+}
+
+// List returns the backups of volume, newest first. An empty volume lists all.
+func (m *Manager) List(volume string) ([]*Record, error) {
+	rows, err := m.db.Query(
+		"SELECT "+recordColumns+" FROM volume_backups WHERE ? = '' OR volume_name = ? ORDER BY created_at DESC, id DESC",
+		volume, volume,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*Record
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// Delete removes a backup's file and its record.
+func (m *Manager) Delete(id int64) error {
+	record, err := m.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if record.File != "" {
+		if err := os.Remove(record.File); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove backup file: %w", err)
+		}
+	}
+
+	_, err = m.db.Exec("DELETE FROM volume_backups WHERE id = ?", id)
+	return err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,48 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeBackupFile(t *testing.T, content []byte) (string, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "backup.tar.gz")
+	if err := os.WriteFile(path, content, 0640); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	return path, hex.EncodeToString(sum[:])
+}
+
+func TestVerifyArchive(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "volume/data", Mode: 0644, Size: 4, Typeflag: tar.TypeReg})
+	tw.Write([]byte("rows"))
+	tw.Close()
+	gz.Close()
+	archive := buf.Bytes()
+
+	path, checksum := writeBackupFile(t, archive)
+	if err := verifyArchive(path, checksum); err != nil {
+		t.Fatalf("valid archive: %v", err)
+	}
+	if err := verifyArchive(path, "0000"); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("wrong checksum: got %v", err)
+	}
+
+	// A file damaged before its checksum was taken still fails.
+	path, checksum = writeBackupFile(t, archive[:len(archive)/2])
+	if err := verifyArchive(path, checksum); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("truncated archive: got %v", err)
+	}
+}
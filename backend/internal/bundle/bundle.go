@@ -0,0 +1,345 @@
+// Package bundle reads and writes container archives: a container's
+// inspect data, its image and some of its volumes in one tar stream, for
+// moving a workload to another host without a registry.
+//
+// An archive holds, in this order:
+//
+//	manifest.json           the Manifest
+//	container.json          the container's Docker inspect data
+//	image/...               the image as written by "docker save"
+//	volumes/<name>/volume/  the contents of each volume in the manifest
+//
+// The image and volumes are nested tar streams whose entries are re-rooted
+// under their prefix, so neither writing nor reading needs temporary files.
+package bundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Version is the archive format version written by this package.
+const Version = 1
+
+const (
+	manifestFile  = "manifest.json"
+	containerFile = "container.json"
+	imagePrefix   = "image/"
+	volumesPrefix = "volumes/"
+	// volumeRoot is the directory volume contents are stored under, as
+	// produced by docker.ExportVolume and expected by docker.ImportVolume.
+	volumeRoot = "volume"
+)
+
+// ErrInvalidArchive is returned for archives that are not container
+// bundles or are damaged.
+var ErrInvalidArchive = errors.New("invalid container archive")
+
+// Volume is a volume stored in the archive.
+type Volume struct {
+	Name        string            `json:"name"`
+	Driver      string            `json:"driver"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Destination string            `json:"destination"`
+}
+
+type Manifest struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Name       string    `json:"name"`
+	// Image is the reference the image was saved as: its tag, or its ID
+	// when the container's tag has moved on to another image.
+	Image   string   `json:"image"`
+	ImageID string   `json:"image_id"`
+	Volumes []Volume `json:"volumes"`
+}
+
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidArchive, fmt.Sprintf(format, args...))
+}
+
+// archiveReader marks read errors of the archive as ErrInvalidArchive,
+// keeping the cause (such as an exceeded upload limit) inspectable.
+type archiveReader struct {
+	r io.Reader
+}
+
+func (a archiveReader) Read(p []byte) (int, error) {
+	n, err := a.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+	return n, err
+}
+
+// Writer writes an archive. Parts must be written in archive order.
+type Writer struct {
+	tw *tar.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{tw: tar.NewWriter(w)}
+}
+
+func (w *Writer) writeJSON(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now(), Typeflag: tar.TypeReg}
+	if err := w.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = w.tw.Write(data)
+	return err
+}
+
+func (w *Writer) WriteManifest(m Manifest) error {
+	m.Version = Version
+	return w.writeJSON(manifestFile, m)
+}
+
+// WriteContainer writes the container's inspect data.
+func (w *Writer) WriteContainer(inspect interface{}) error {
+	return w.writeJSON(containerFile, inspect)
+}
+
+// WriteImage copies an image archive from "docker save".
+func (w *Writer) WriteImage(archive io.Reader) error {
+	return w.nest(imagePrefix, archive)
+}
+
+// WriteVolume copies a volume archive from docker.ExportVolume.
+func (w *Writer) WriteVolume(name string, archive io.Reader) error {
+	return w.nest(volumesPrefix+name+"/", archive)
+}
+
+func (w *Writer) nest(prefix string, archive io.Reader) error {
+	tr := tar.NewReader(archive)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		hdr.Name = prefix + hdr.Name
+		if err := w.tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(w.tw, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func (w *Writer) Close() error {
+	return w.tw.Close()
+}
+
+// Handlers receive the parts of an archive as Read reaches them. Image and
+// Volume get the nested archive as a stream that ends with its section.
+type Handlers struct {
+	Manifest  func(Manifest) error
+	Container func(inspect json.RawMessage) error
+	Image     func(archive io.Reader) error
+	Volume    func(v Volume, archive io.Reader) error
+}
+
+// section streams the entries of one nested archive to a handler running
+// in its own goroutine.
+type section struct {
+	prefix string
+	pw     *io.PipeWriter
+	tw     *tar.Writer
+	done   chan error
+}
+
+// errSectionClosed fails writes to a section whose handler has returned.
+var errSectionClosed = errors.New("section handler returned")
+
+func startSection(prefix string, handle func(io.Reader) error) *section {
+	pr, pw := io.Pipe()
+	s := &section{prefix: prefix, pw: pw, tw: tar.NewWriter(pw), done: make(chan error, 1)}
+	go func() {
+		// Hide Close: an HTTP client sending the stream as a request body
+		// closes it, which would fail the rest of the section.
+		err := handle(struct{ io.Reader }{pr})
+		if err == nil {
+			// Let the writer finish even if the handler stopped early.
+			_, err = io.Copy(io.Discard, pr)
+		}
+		pr.CloseWithError(errSectionClosed)
+		s.done <- err
+	}()
+	return s
+}
+
+func (s *section) write(hdr *tar.Header, content io.Reader) error {
+	if err := s.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.Copy(s.tw, content)
+	return err
+}
+
+// finish ends the section and returns its first error: a failure to read
+// the archive, or else the handler's result.
+func (s *section) finish(writeErr error) error {
+	if writeErr == nil {
+		writeErr = s.tw.Close()
+	}
+	s.pw.CloseWithError(writeErr)
+	handlerErr := <-s.done
+	if writeErr != nil && !errors.Is(writeErr, errSectionClosed) {
+		return writeErr
+	}
+	return handlerErr
+}
+
+// inVolumeRoot reports whether a volume section entry is inside the
+// volume directory, so that importing it cannot write anywhere else.
+func inVolumeRoot(name string) bool {
+	if strings.Contains("/"+name+"/", "/../") {
+		return false
+	}
+	return name == volumeRoot || strings.HasPrefix(name, volumeRoot+"/")
+}
+
+// Read reads an archive, plain or gzip compressed, and passes its parts to
+// h in order. It stops at the first error a handler returns.
+func Read(r io.Reader, h Handlers) error {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return invalid("%v", err)
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	tr := tar.NewReader(archiveReader{r})
+	var manifest *Manifest
+	var sawContainer, sawImage bool
+	var current *section
+	volumes := make(map[string]Volume)
+	done := make(map[string]bool)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if !errors.Is(err, ErrInvalidArchive) {
+				err = fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+			}
+			if current != nil {
+				current.finish(err)
+			}
+			return err
+		}
+		name := strings.TrimPrefix(hdr.Name, "./")
+
+		if current != nil && !strings.HasPrefix(name, current.prefix) {
+			if err := current.finish(nil); err != nil {
+				return err
+			}
+			current = nil
+		}
+
+		switch {
+		case current != nil:
+			hdr.Name = strings.TrimPrefix(name, current.prefix)
+			if current.prefix != imagePrefix && !inVolumeRoot(hdr.Name) {
+				err := invalid("unexpected entry %s", name)
+				current.finish(err)
+				return err
+			}
+			if err := current.write(hdr, tr); err != nil {
+				return current.finish(err)
+			}
+
+		case name == manifestFile && manifest == nil:
+			manifest = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return invalid("manifest: %v", err)
+			}
+			if manifest.Version != Version {
+				return invalid("unsupported version %d", manifest.Version)
+			}
+			for _, v := range manifest.Volumes {
+				volumes[v.Name] = v
+			}
+			if err := h.Manifest(*manifest); err != nil {
+				return err
+			}
+
+		case name == containerFile && manifest != nil && !sawContainer:
+			sawContainer = true
+			var inspect json.RawMessage
+			if err := json.NewDecoder(tr).Decode(&inspect); err != nil {
+				return invalid("container: %v", err)
+			}
+			if err := h.Container(inspect); err != nil {
+				return err
+			}
+
+		case strings.HasPrefix(name, imagePrefix) && sawContainer && !sawImage:
+			sawImage = true
+			current = startSection(imagePrefix, h.Image)
+			hdr.Name = strings.TrimPrefix(name, imagePrefix)
+			if err := current.write(hdr, tr); err != nil {
+				return current.finish(err)
+			}
+
+		case strings.HasPrefix(name, volumesPrefix) && sawImage:
+			volName, _, _ := strings.Cut(strings.TrimPrefix(name, volumesPrefix), "/")
+			v, ok := volumes[volName]
+			if !ok || done[volName] {
+				return invalid("unexpected volume %s", volName)
+			}
+			done[volName] = true
+			prefix := volumesPrefix + volName + "/"
+			current = startSection(prefix, func(archive io.Reader) error { return h.Volume(v, archive) })
+			hdr.Name = strings.TrimPrefix(name, prefix)
+			if !inVolumeRoot(hdr.Name) {
+				err := invalid("unexpected entry %s", name)
+				current.finish(err)
+				return err
+			}
+			if err := current.write(hdr, tr); err != nil {
+				return current.finish(err)
+			}
+
+		default:
+			return invalid("unexpected entry %s", name)
+		}
+	}
+
+	if current != nil {
+		if err := current.finish(nil); err != nil {
+			return err
+		}
+	}
+	if !sawImage {
+		return invalid("missing image")
+	}
+	for name := range volumes {
+		if !done[name] {
+			return invalid("missing volume %s", name)
+		}
+	}
+	return nil
+}
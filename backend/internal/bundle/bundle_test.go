@@ -0,0 +1,166 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tarOf(t *testing.T, files map[string]string, order ...string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range order {
+		content := files[name]
+		if strings.HasSuffix(name, "/") {
+			require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Typeflag: tar.TypeDir}))
+			continue
+		}
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func entries(t *testing.T, archive io.Reader) map[string]string {
+	files := map[string]string{}
+	tr := tar.NewReader(archive)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[hdr.Name] = string(content)
+	}
+}
+
+func writeBundle(t *testing.T, w io.Writer, volumes map[string][]byte) {
+	bw := NewWriter(w)
+	manifest := Manifest{Name: "web", Image: "nginx:1.27", ImageID: "sha256:abc"}
+	for _, name := range []string{"data", "logs"} {
+		if _, ok := volumes[name]; ok {
+			manifest.Volumes = append(manifest.Volumes, Volume{Name: name, Driver: "local", Destination: "/" + name})
+		}
+	}
+	require.NoError(t, bw.WriteManifest(manifest))
+	require.NoError(t, bw.WriteContainer(map[string]string{"Name": "/web"}))
+	require.NoError(t, bw.WriteImage(bytes.NewReader(tarOf(t,
+		map[string]string{"manifest.json": "[]", "abc/layer.tar": "layer"}, "manifest.json", "abc/layer.tar"))))
+	for _, name := range []string{"data", "logs"} {
+		if archive, ok := volumes[name]; ok {
+			require.NoError(t, bw.WriteVolume(name, bytes.NewReader(archive)))
+		}
+	}
+	require.NoError(t, bw.Close())
+}
+
+func TestRoundTrip(t *testing.T) {
+	volumes := map[string][]byte{
+		"data": tarOf(t, map[string]string{"volume/": "", "volume/db.sqlite": "rows"}, "volume/", "volume/db.sqlite"),
+		"logs": tarOf(t, map[string]string{"volume/app.log": "line"}, "volume/app.log"),
+	}
+
+	for _, compress := range []bool{false, true} {
+		var buf bytes.Buffer
+		if compress {
+			gz := gzip.NewWriter(&buf)
+			writeBundle(t, gz, volumes)
+			require.NoError(t, gz.Close())
+		} else {
+			writeBundle(t, &buf, volumes)
+		}
+
+		var manifest Manifest
+		var inspect map[string]string
+		var image map[string]string
+		restored := map[string]map[string]string{}
+		err := Read(&buf, Handlers{
+			Manifest: func(m Manifest) error { manifest = m; return nil },
+			Container: func(raw json.RawMessage) error {
+				return json.Unmarshal(raw, &inspect)
+			},
+			Image: func(archive io.Reader) error { image = entries(t, archive); return nil },
+			Volume: func(v Volume, archive io.Reader) error {
+				restored[v.Name] = entries(t, archive)
+				return nil
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, Version, manifest.Version)
+		assert.Equal(t, "nginx:1.27", manifest.Image)
+		assert.Equal(t, "/web", inspect["Name"])
+		assert.Equal(t, map[string]string{"manifest.json": "[]", "abc/layer.tar": "layer"}, image)
+		assert.Equal(t, map[string]string{"volume/": "", "volume/db.sqlite": "rows"}, restored["data"])
+		assert.Equal(t, map[string]string{"volume/app.log": "line"}, restored["logs"])
+	}
+}
+
+func TestReadSurvivesHandlersClosingTheStream(t *testing.T) {
+	var buf bytes.Buffer
+	writeBundle(t, &buf, nil)
+
+	// An HTTP request body is closed by the client once it is sent.
+	err := Read(&buf, Handlers{
+		Manifest:  func(Manifest) error { return nil },
+		Container: func(json.RawMessage) error { return nil },
+		Image: func(archive io.Reader) error {
+			if _, err := io.Copy(io.Discard, archive); err != nil {
+				return err
+			}
+			if closer, ok := archive.(io.Closer); ok {
+				return closer.Close()
+			}
+			return nil
+		},
+		Volume: func(Volume, io.Reader) error { return nil },
+	})
+	assert.NoError(t, err)
+}
+
+func TestReadRejectsInvalidArchives(t *testing.T) {
+	noop := Handlers{
+		Manifest:  func(Manifest) error { return nil },
+		Container: func(json.RawMessage) error { return nil },
+		Image:     func(archive io.Reader) error { _, err := io.Copy(io.Discard, archive); return err },
+		Volume:    func(_ Volume, archive io.Reader) error { _, err := io.Copy(io.Discard, archive); return err },
+	}
+
+	escape := tarOf(t, map[string]string{"volume/../etc/passwd": "x"}, "volume/../etc/passwd")
+	var buf bytes.Buffer
+	writeBundle(t, &buf, map[string][]byte{"data": escape})
+	assert.True(t, errors.Is(Read(&buf, noop), ErrInvalidArchive), "volume entries must stay inside the volume")
+
+	outside := tarOf(t, map[string]string{"etc/passwd": "x"}, "etc/passwd")
+	buf.Reset()
+	writeBundle(t, &buf, map[string][]byte{"data": outside})
+	assert.True(t, errors.Is(Read(&buf, noop), ErrInvalidArchive))
+
+	buf.Reset()
+	writeBundle(t, &buf, nil)
+	// Cut into the header of the last entry.
+	truncated := buf.Bytes()[:buf.Len()-1024-512-256]
+	err := Read(bytes.NewReader(truncated), noop)
+	assert.True(t, errors.Is(err, ErrInvalidArchive), "%v", err)
+
+	plain := tarOf(t, map[string]string{"container.json": "{}"}, "container.json")
+	assert.True(t, errors.Is(Read(bytes.NewReader(plain), noop), ErrInvalidArchive), "the manifest comes first")
+
+	buf.Reset()
+	writeBundle(t, &buf, nil)
+	failing := noop
+	failing.Image = func(io.Reader) error { return errors.New("docker refused the image") }
+	assert.EqualError(t, Read(&buf, failing), "docker refused the image")
+}
@@ -0,0 +1,335 @@
+package cleanup
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"cyber-container-platform/internal/docker"
+
+	"github.com/docker/docker/api/types"
+)
+
+// Options selects what a prune removes. Label and age filters apply to every
+// selected resource type, except that build cache records carry no labels and
+// are therefore skipped whenever a label filter is given.
+type Options struct {
+	Containers bool `json:"containers"`
+	Images     bool `json:"images"`
+	Volumes    bool `json:"volumes"`
+	Networks   bool `json:"networks"`
+	BuildCache bool `json:"build_cache"`
+
+	// AllImages removes every unused image instead of only dangling ones.
+	AllImages bool `json:"all_images"`
+	// AllVolumes removes unused named volumes instead of only anonymous ones.
+	AllVolumes bool `json:"all_volumes"`
+
+	Labels    []string `json:"labels"`
+	OlderThan string   `json:"older_than"`
+	DryRun    bool     `json:"dry_run"`
+}
+
+// Validate checks that at least one resource type is selected and the
+// filters parse.
+func (o Options) Validate() error {
+	if !o.Containers && !o.Images && !o.Volumes && !o.Networks && !o.BuildCache {
+		return fmt.Errorf("select at least one of containers, images, volumes, networks or build_cache")
+	}
+	_, err := newFilter(o, time.Now())
+	return err
+}
+
+// Item is a resource selected for removal.
+type Item struct {
+	Type      string    `json:"type"`
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	SizeBytes int64     `json:"size_bytes"`
+	Created   time.Time `json:"created"`
+	Removed   bool      `json:"removed"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Report is the outcome of a prune. For dry runs it lists what would be
+// removed and SpaceReclaimed is the estimated total.
+type Report struct {
+	DryRun         bool           `json:"dry_run"`
+	Items          []Item         `json:"items"`
+	Counts         map[string]int `json:"counts"`
+	SpaceReclaimed int64          `json:"space_reclaimed"`
+	Failed         int            `json:"failed"`
+}
+
+// Inventory is the state of the Docker host a prune is planned against.
+type Inventory struct {
+	Usage    types.DiskUsage
+	Networks []docker.NetworkInfo
+
+	// HeldHelpers are helper containers a request is still using and
+	// BusyVolumes the volumes a backup or restore is working on. Helpers in
+	// either are kept; other stopped helpers are leftovers.
+	HeldHelpers map[string]bool
+	BusyVolumes map[string]bool
+}
+
+var predefinedNetworks = map[string]bool{"bridge": true, "host": true, "none": true}
+
+var anonymousVolumeName = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// IsAnonymousVolume reports whether a volume was created by Docker for a
+// container rather than by name.
+func IsAnonymousVolume(name string, labels map[string]string) bool {
+	_, anonymous := labels["com.docker.volume.anonymous"]
+	return anonymous || anonymousVolumeName.MatchString(name)
+}
+
+// Plan selects the resources a prune removes, in removal order: containers
+// first, so that images, volumes and networks used only by removed
+// containers become unused in the same pass.
+func Plan(inv Inventory, opts Options, now time.Time) ([]Item, error) {
+	f, err := newFilter(opts, now)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []Item{}
+	removed := make(map[string]bool)
+
+	if opts.Containers {
+		for _, c := range inv.Usage.Containers {
+			created := time.Unix(c.Created, 0)
+			if c.State != "exited" && c.State != "created" && c.State != "dead" {
+				continue
+			}
+			if _, helper := c.Labels[docker.HelperLabel]; helper && inv.helperInUse(c) {
+				continue
+			}
+			if !f.matches(c.Labels, created) {
+				continue
+			}
+
+			removed[c.ID] = true
+			items = append(items, Item{
+				Type:      docker.KindContainer,
+				ID:        c.ID,
+				Name:      containerName(c),
+				SizeBytes: c.SizeRw,
+				Created:   created,
+			})
+		}
+	}
+
+	// What the containers that survive the prune still use.
+	usedImages := make(map[string]bool)
+	usedVolumes := make(map[string]bool)
+	usedNetworks := make(map[string]bool)
+	for _, c := range inv.Usage.Containers {
+		if removed[c.ID] {
+			continue
+		}
+		usedImages[c.ImageID] = true
+		for _, m := range c.Mounts {
+			if m.Type == "volume" {
+				usedVolumes[m.Name] = true
+			}
+		}
+		if c.NetworkSettings != nil {
+			for name, endpoint := range c.NetworkSettings.Networks {
+				usedNetworks[name] = true
+				if endpoint != nil {
+					usedNetworks[endpoint.NetworkID] = true
+				}
+			}
+		}
+	}
+
+	if opts.Images {
+		var images []Item
+		for _, img := range inv.Usage.Images {
+			dangling := isDangling(img.RepoTags)
+			if usedImages[img.ID] || (!dangling && !opts.AllImages) {
+				continue
+			}
+			created := time.Unix(img.Created, 0)
+			if !f.matches(img.Labels, created) {
+				continue
+			}
+
+			size := img.Size
+			if img.SharedSize > 0 {
+				size -= img.SharedSize
+			}
+			images = append(images, Item{
+				Type:      docker.KindImage,
+				ID:        img.ID,
+				Name:      imageName(img.RepoTags, img.RepoDigests, img.ID),
+				SizeBytes: size,
+				Created:   created,
+			})
+		}
+		// Newest first, so child images go before their parents.
+		sort.SliceStable(images, func(i, j int) bool { return images[i].Created.After(images[j].Created) })
+		items = append(items, images...)
+	}
+
+	if opts.Volumes {
+		for _, vol := range inv.Usage.Volumes {
+			if usedVolumes[vol.Name] {
+				continue
+			}
+			if !opts.AllVolumes && !IsAnonymousVolume(vol.Name, vol.Labels) {
+				continue
+			}
+			created, _ := time.Parse(time.RFC3339, vol.CreatedAt)
+			if !f.matches(vol.Labels, created) {
+				continue
+			}
+
+			var size int64
+			if vol.UsageData != nil && vol.UsageData.Size > 0 {
+				size = vol.UsageData.Size
+			}
+			items = append(items, Item{
+				Type:      docker.KindVolume,
+				ID:        vol.Name,
+				Name:      vol.Name,
+				SizeBytes: size,
+				Created:   created,
+			})
+		}
+	}
+
+	if opts.Networks {
+		for _, network := range inv.Networks {
+			if predefinedNetworks[network.Name] || network.Scope == "swarm" {
+				continue
+			}
+			if usedNetworks[network.ID] || usedNetworks[network.Name] || len(network.Containers) > 0 {
+				continue
+			}
+			if !f.matches(network.Labels, network.Created) {
+				continue
+			}
+
+			items = append(items, Item{
+				Type:    docker.KindNetwork,
+				ID:      network.ID,
+				Name:    network.Name,
+				Created: network.Created,
+			})
+		}
+	}
+
+	if opts.BuildCache && len(f.labels) == 0 {
+		for _, record := range inv.Usage.BuildCache {
+			if record.InUse {
+				continue
+			}
+			lastUsed := record.CreatedAt
+			if record.LastUsedAt != nil {
+				lastUsed = *record.LastUsedAt
+			}
+			if !f.matches(nil, lastUsed) {
+				continue
+			}
+
+			items = append(items, Item{
+				Type:      docker.KindBuildCache,
+				ID:        record.ID,
+				Name:      record.Description,
+				SizeBytes: record.Size,
+				Created:   record.CreatedAt,
+			})
+		}
+	}
+
+	return items, nil
+}
+
+func (inv Inventory) helperInUse(c *types.Container) bool {
+	if inv.HeldHelpers[c.ID] {
+		return true
+	}
+	for _, m := range c.Mounts {
+		if m.Type == "volume" && inv.BusyVolumes[m.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+func containerName(c *types.Container) string {
+	if len(c.Names) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+func isDangling(tags []string) bool {
+	for _, tag := range tags {
+		if tag != "<none>:<none>" {
+			return false
+		}
+	}
+	return true
+}
+
+func imageName(tags, digests []string, id string) string {
+	if !isDangling(tags) {
+		return tags[0]
+	}
+	if len(digests) > 0 && digests[0] != "<none>@<none>" {
+		return digests[0]
+	}
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	return id
+}
+
+// Prune plans against the current state of the host and, unless this is a
+// dry run, removes the selected resources. Failures to remove single items
+// are recorded in the report rather than aborting the prune. busyVolumes are
+// the volumes a backup or restore is working on.
+func Prune(client *docker.Client, opts Options, busyVolumes map[string]bool) (*Report, error) {
+	usage, err := client.DiskUsage()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read disk usage: %w", err)
+	}
+	networks, err := client.ListNetworks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+
+	inv := Inventory{
+		Usage:       usage,
+		Networks:    networks,
+		HeldHelpers: client.HeldHelpers(),
+		BusyVolumes: busyVolumes,
+	}
+	items, err := Plan(inv, opts, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{DryRun: opts.DryRun, Items: items, Counts: make(map[string]int)}
+	for i := range report.Items {
+		item := &report.Items[i]
+		if !opts.DryRun {
+			if err := client.RemoveUnused(item.Type, item.ID); err != nil {
+				item.Error = err.Error()
+				report.Failed++
+				continue
+			}
+			item.Removed = true
+		}
+		report.Counts[item.Type]++
+		report.SpaceReclaimed += item.SizeBytes
+	}
+
+	return report, nil
+}
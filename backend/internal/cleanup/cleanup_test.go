@@ -0,0 +1,130 @@
+package cleanup
+
+import (
+	"testing"
+	"time"
+
+	"cyber-container-platform/internal/docker"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)
+
+func inventory() Inventory {
+	old := now.Add(-72 * time.Hour)
+	recent := now.Add(-30 * time.Minute)
+	anonymous := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	return Inventory{
+		Usage: types.DiskUsage{
+			Containers: []*types.Container{
+				{
+					ID: "c-web", Names: []string{"/web"}, ImageID: "sha256:nginx", State: "running", Created: old.Unix(),
+					Mounts:          []types.MountPoint{{Type: "volume", Name: "webdata"}},
+					NetworkSettings: &types.SummaryNetworkSettings{Networks: map[string]*network.EndpointSettings{"frontend": {NetworkID: "n-front"}}},
+				},
+				{
+					ID: "c-job", Names: []string{"/job"}, ImageID: "sha256:worker", State: "exited", Created: old.Unix(), SizeRw: 100,
+					Labels: map[string]string{"env": "dev"},
+					Mounts: []types.MountPoint{{Type: "volume", Name: anonymous}},
+				},
+				{ID: "c-new", Names: []string{"/new"}, ImageID: "sha256:worker", State: "created", Created: recent.Unix(), SizeRw: 10},
+				{ID: "c-helper", Names: []string{"/helper"}, ImageID: "sha256:busybox", State: "created", Created: recent.Unix(), Labels: map[string]string{docker.HelperLabel: "volume-export"}},
+				{
+					ID: "c-restore", Names: []string{"/restore"}, ImageID: "sha256:busybox", State: "created", Created: old.Unix(),
+					Labels: map[string]string{docker.HelperLabel: "volume-import"},
+					Mounts: []types.MountPoint{{Type: "volume", Name: "restoring"}},
+				},
+				{ID: "c-leftover", Names: []string{"/leftover"}, ImageID: "sha256:busybox", State: "created", Created: recent.Unix(), Labels: map[string]string{docker.HelperLabel: "volume-files"}},
+			},
+			Images: []*image.Summary{
+				{ID: "sha256:nginx", RepoTags: []string{"nginx:latest"}, Size: 1000, Created: old.Unix()},
+				{ID: "sha256:worker", RepoTags: []string{"worker:1"}, Size: 500, SharedSize: 200, Created: old.Unix()},
+				{ID: "sha256:dangling", RepoTags: []string{"<none>:<none>"}, Size: 300, Created: old.Unix()},
+				{ID: "sha256:busybox", RepoTags: []string{"busybox:stable"}, Size: 5, Created: old.Unix()},
+			},
+			Volumes: []*volume.Volume{
+				{Name: "webdata", CreatedAt: old.Format(time.RFC3339), UsageData: &volume.UsageData{Size: 50, RefCount: 1}},
+				{Name: anonymous, CreatedAt: old.Format(time.RFC3339), UsageData: &volume.UsageData{Size: 70, RefCount: 1}},
+				{Name: "cache", CreatedAt: old.Format(time.RFC3339), UsageData: &volume.UsageData{Size: 20}},
+			},
+			BuildCache: []*types.BuildCache{
+				{ID: "b1", Size: 40, CreatedAt: old, LastUsedAt: &old},
+				{ID: "b2", Size: 60, CreatedAt: old, InUse: true},
+			},
+		},
+		Networks: []docker.NetworkInfo{
+			{ID: "n-bridge", Name: "bridge", Created: old},
+			{ID: "n-front", Name: "frontend", Created: old},
+			{ID: "n-old", Name: "legacy", Created: old, Labels: map[string]string{"env": "dev"}},
+		},
+		HeldHelpers: map[string]bool{"c-helper": true},
+		BusyVolumes: map[string]bool{"restoring": true},
+	}
+}
+
+func ids(items []Item) []string {
+	var result []string
+	for _, item := range items {
+		result = append(result, item.ID)
+	}
+	return result
+}
+
+func TestPlanEverything(t *testing.T) {
+	items, err := Plan(inventory(), Options{Containers: true, Images: true, Volumes: true, Networks: true, BuildCache: true}, now)
+	require.NoError(t, err)
+
+	// The anonymous volume and worker image become unused once their
+	// containers go. Helpers in use are kept however old they are, while a
+	// helper nobody holds is a leftover however young it is.
+	assert.Equal(t, []string{
+		"c-job", "c-new", "c-leftover",
+		"sha256:dangling",
+		"0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		"n-old",
+		"b1",
+	}, ids(items))
+}
+
+func TestPlanAllImagesAndVolumes(t *testing.T) {
+	items, err := Plan(inventory(), Options{Images: true, Volumes: true, AllImages: true, AllVolumes: true}, now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sha256:dangling", "cache"}, ids(items))
+
+	items, err = Plan(inventory(), Options{Containers: true, Images: true, AllImages: true}, now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c-job", "c-new", "c-leftover", "sha256:worker", "sha256:dangling"}, ids(items))
+	assert.Equal(t, int64(300), items[3].SizeBytes)
+	assert.Equal(t, "worker:1", items[3].Name)
+}
+
+func TestPlanFilters(t *testing.T) {
+	items, err := Plan(inventory(), Options{Containers: true, OlderThan: "1d"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c-job"}, ids(items))
+
+	items, err = Plan(inventory(), Options{Containers: true, Networks: true, BuildCache: true, Labels: []string{"env=dev"}}, now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c-job", "n-old"}, ids(items))
+
+	items, err = Plan(inventory(), Options{Containers: true, Labels: []string{"!env"}}, now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c-new", "c-leftover"}, ids(items))
+
+	_, err = Plan(inventory(), Options{Containers: true, Labels: []string{"=x"}}, now)
+	assert.Error(t, err)
+}
+
+func TestOptionsValidate(t *testing.T) {
+	assert.Error(t, Options{}.Validate())
+	assert.Error(t, Options{Images: true, OlderThan: "soon"}.Validate())
+	assert.Error(t, Options{Images: true, OlderThan: "-1h"}.Validate())
+	assert.NoError(t, Options{Images: true, OlderThan: "36h", Labels: []string{"env=dev", "!keep"}}.Validate())
+}
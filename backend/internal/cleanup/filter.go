@@ -0,0 +1,65 @@
+package cleanup
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"cyber-container-platform/internal/labels"
+)
+
+// ParseAge parses a minimum age such as "90m", "24h" or "7d".
+func ParseAge(spec string) (time.Duration, error) {
+	var age time.Duration
+	if days, ok := strings.CutSuffix(spec, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age %q", spec)
+		}
+		age = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if age, err = time.ParseDuration(spec); err != nil {
+			return 0, fmt.Errorf("invalid age %q", spec)
+		}
+	}
+
+	if age <= 0 {
+		return 0, fmt.Errorf("age must be positive, got %q", spec)
+	}
+	return age, nil
+}
+
+// filter holds the parsed label and age filters of a prune request.
+type filter struct {
+	labels labels.Selector
+	// cutoff is the latest creation time a resource may have; zero means
+	// no age filter.
+	cutoff time.Time
+}
+
+func newFilter(opts Options, now time.Time) (filter, error) {
+	selector, err := labels.Parse(opts.Labels)
+	if err != nil {
+		return filter{}, err
+	}
+	f := filter{labels: selector}
+
+	if opts.OlderThan != "" {
+		age, err := ParseAge(opts.OlderThan)
+		if err != nil {
+			return filter{}, err
+		}
+		f.cutoff = now.Add(-age)
+	}
+
+	return f, nil
+}
+
+func (f filter) matches(resourceLabels map[string]string, created time.Time) bool {
+	if !f.cutoff.IsZero() && created.After(f.cutoff) {
+		return false
+	}
+	return f.labels.Matches(resourceLabels)
+}
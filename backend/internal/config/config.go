@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 )
@@ -14,10 +15,43 @@ type Config struct {
 	CertPath     string
 	KeyPath      string
 	LogLevel     string
+
+	// Seconds a container gets to exit on stop or restart before it is killed
+	StopTimeout int
+	// Minutes between registry checks of the image update watcher; 0 disables it
+	ImageUpdateCheckMinutes int
+	// Seconds between health checks of the auto-heal supervisor; 0 disables it
+	AutohealIntervalSeconds int
+	// Failed probes in a row after which an unhealthy container is restarted
+	AutohealThreshold int
+	// Seconds to wait after the first restart; doubles with each restart
+	// that does not make the container healthy
+	AutohealBackoffSeconds int
+	// Host ports handed out for container ports published as "auto"
+	AutoPortRangeStart int
+	AutoPortRangeEnd   int
+
+	// Volume backups
+	BackupDir         string
+	BackupHelperImage string
+
+	// File browser transfer limits
+	MaxUploadBytes   int64
+	MaxDownloadBytes int64
+
+	// Vulnerability database used by the offline image scanner
+	VulnDBPath string
+
+	// Largest image archive accepted by the image load endpoint
+	MaxImageLoadBytes int64
+	// Largest build context accepted by the image build endpoint
+	MaxBuildContextBytes int64
 }
 
-func Load() *Config {
-	return &Config{
+// Load reads the configuration from the environment and rejects settings
+// the server cannot work with.
+func Load() (*Config, error) {
+	cfg := &Config{
 		Port:         getEnv("PORT", "8080"),
 		WSPort:       getEnv("WS_PORT", "8081"),
 		DatabasePath: getEnv("DATABASE_PATH", "./data/cyber.db"),
@@ -26,7 +60,32 @@ func Load() *Config {
 		CertPath:     getEnv("CERT_PATH", "./certs/server.crt"),
 		KeyPath:      getEnv("KEY_PATH", "./certs/server.key"),
 		LogLevel:     getEnv("LOG_LEVEL", "info"),
+
+		StopTimeout:             getIntEnv("CONTAINER_STOP_TIMEOUT", 30),
+		ImageUpdateCheckMinutes: getIntEnv("IMAGE_UPDATE_CHECK_MINUTES", 360),
+		AutohealIntervalSeconds: getIntEnv("AUTOHEAL_INTERVAL_SECONDS", 10),
+		AutohealThreshold:       getIntEnv("AUTOHEAL_THRESHOLD", 3),
+		AutohealBackoffSeconds:  getIntEnv("AUTOHEAL_BACKOFF_SECONDS", 30),
+		AutoPortRangeStart:      getIntEnv("AUTO_PORT_RANGE_START", 20000),
+		AutoPortRangeEnd:        getIntEnv("AUTO_PORT_RANGE_END", 29999),
+
+		BackupDir:         getEnv("BACKUP_DIR", "./data/backups"),
+		BackupHelperImage: getEnv("BACKUP_HELPER_IMAGE", "busybox:stable"),
+
+		MaxUploadBytes:   int64(getIntEnv("FILE_UPLOAD_MAX_MB", 100)) << 20,
+		MaxDownloadBytes: int64(getIntEnv("FILE_DOWNLOAD_MAX_MB", 1024)) << 20,
+
+		VulnDBPath: getEnv("VULN_DB_PATH", "./data/vulndb.json"),
+
+		MaxImageLoadBytes:    int64(getIntEnv("IMAGE_LOAD_MAX_MB", 10240)) << 20,
+		MaxBuildContextBytes: int64(getIntEnv("BUILD_CONTEXT_MAX_MB", 1024)) << 20,
 	}
+
+	if cfg.AutoPortRangeStart < 1 || cfg.AutoPortRangeEnd > 65535 || cfg.AutoPortRangeStart > cfg.AutoPortRangeEnd {
+		return nil, fmt.Errorf("AUTO_PORT_RANGE_START (%d) and AUTO_PORT_RANGE_END (%d) must be ports from 1 to 65535 with the start not above the end",
+			cfg.AutoPortRangeStart, cfg.AutoPortRangeEnd)
+	}
+	return cfg, nil
 }
 
 func getEnv(key, defaultValue string) string {
@@ -44,3 +103,12 @@ func getBoolEnv(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
@@ -1,16 +1,27 @@
 package database
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"path/filepath"
 
 	_ "github.com/mattn/go-sqlite3"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// AdminUsername is the built-in administrator account, created with a
+// random password whenever no user has the admin role.
+const AdminUsername = "admin"
+
 type Database struct {
 	db *sql.DB
+
+	// adminPassword is the password of the administrator created by Init,
+	// if it created one.
+	adminPassword string
 }
 
 func Init(dbPath string) (*Database, error) {
@@ -84,6 +95,147 @@ func (d *Database) initTables() error {
 			message TEXT NOT NULL,
 			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
+		`CREATE TABLE IF NOT EXISTS user_groups (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT UNIQUE NOT NULL,
+			created_by INTEGER,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (created_by) REFERENCES users (id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS group_members (
+			group_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (group_id, user_id),
+			FOREIGN KEY (group_id) REFERENCES user_groups (id),
+			FOREIGN KEY (user_id) REFERENCES users (id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS template_shares (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			template_id INTEGER NOT NULL,
+			user_id INTEGER,
+			group_id INTEGER,
+			created_by INTEGER,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (template_id) REFERENCES container_templates (id),
+			FOREIGN KEY (user_id) REFERENCES users (id),
+			FOREIGN KEY (group_id) REFERENCES user_groups (id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS volume_backups (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			volume_name TEXT NOT NULL,
+			file_path TEXT NOT NULL,
+			size_bytes INTEGER DEFAULT 0,
+			checksum TEXT DEFAULT '',
+			driver TEXT DEFAULT '',
+			driver_options TEXT DEFAULT '',
+			labels TEXT DEFAULT '',
+			status TEXT NOT NULL,
+			error TEXT DEFAULT '',
+			duration_ms INTEGER DEFAULT 0,
+			created_by INTEGER,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (created_by) REFERENCES users (id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS scheduled_jobs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			type TEXT NOT NULL,
+			schedule TEXT NOT NULL,
+			target TEXT DEFAULT '',
+			keep_last INTEGER DEFAULT 0,
+			keep_daily INTEGER DEFAULT 0,
+			paused BOOLEAN DEFAULT 0,
+			next_run DATETIME,
+			last_run DATETIME,
+			last_status TEXT DEFAULT '',
+			created_by INTEGER,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (created_by) REFERENCES users (id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS job_runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			job_id INTEGER NOT NULL,
+			status TEXT NOT NULL,
+			triggered_by TEXT NOT NULL,
+			started_at DATETIME NOT NULL,
+			finished_at DATETIME,
+			duration_ms INTEGER DEFAULT 0,
+			error TEXT DEFAULT '',
+			artifact TEXT DEFAULT '',
+			summary TEXT DEFAULT '',
+			pruned BOOLEAN DEFAULT 0,
+			FOREIGN KEY (job_id) REFERENCES scheduled_jobs (id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS registry_credentials (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			registry TEXT UNIQUE NOT NULL,
+			username TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			created_by INTEGER,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (created_by) REFERENCES users (id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS image_builds (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			tags TEXT DEFAULT '[]',
+			dockerfile TEXT DEFAULT '',
+			target TEXT DEFAULT '',
+			status TEXT NOT NULL,
+			image_id TEXT DEFAULT '',
+			error TEXT DEFAULT '',
+			log TEXT DEFAULT '',
+			duration_ms INTEGER DEFAULT 0,
+			created_by INTEGER,
+			started_at DATETIME NOT NULL,
+			finished_at DATETIME,
+			FOREIGN KEY (created_by) REFERENCES users (id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS image_scans (
+			digest TEXT PRIMARY KEY,
+			scanner TEXT NOT NULL,
+			database_version TEXT NOT NULL,
+			result TEXT NOT NULL,
+			scanned_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS image_commits (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			container_id TEXT NOT NULL,
+			container_name TEXT NOT NULL,
+			image_id TEXT NOT NULL,
+			reference TEXT NOT NULL,
+			message TEXT DEFAULT '',
+			author TEXT DEFAULT '',
+			created_by INTEGER,
+			created_at DATETIME NOT NULL,
+			FOREIGN KEY (created_by) REFERENCES users (id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS image_updates (
+			container_id TEXT PRIMARY KEY,
+			container_name TEXT NOT NULL,
+			image TEXT NOT NULL,
+			mode TEXT NOT NULL,
+			current_digest TEXT DEFAULT '',
+			latest_digest TEXT DEFAULT '',
+			update_available BOOLEAN DEFAULT 0,
+			error TEXT DEFAULT '',
+			checked_at DATETIME NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS autoheal_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			container_id TEXT NOT NULL,
+			container_name TEXT NOT NULL,
+			event TEXT NOT NULL,
+			failing_streak INTEGER DEFAULT 0,
+			output TEXT DEFAULT '',
+			error TEXT DEFAULT '',
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS settings (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
 	}
 
 	for _, query := range queries {
@@ -92,9 +244,157 @@ func (d *Database) initTables() error {
 		}
 	}
 
+	if err := d.migrateColumns(); err != nil {
+		return err
+	}
+	return d.seedAdmin()
+}
+
+// seedAdmin makes sure an administrator exists. If no user has the admin
+// role, the built-in account gets it with a new random password; a regular
+// user who registered the name before it was reserved loses the old one.
+func (d *Database) seedAdmin() error {
+	var exists bool
+	if err := d.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE role = 'admin')").Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look up admin user: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	secret := make([]byte, 18)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("failed to generate admin password: %w", err)
+	}
+	password := base64.RawURLEncoding.EncodeToString(secret)
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash admin password: %w", err)
+	}
+	if _, err := d.db.Exec(
+		`INSERT INTO users (username, password_hash, role) VALUES (?, ?, 'admin')
+		ON CONFLICT(username) DO UPDATE SET password_hash = excluded.password_hash, role = 'admin'`,
+		AdminUsername, string(hash),
+	); err != nil {
+		return fmt.Errorf("failed to create admin user: %w", err)
+	}
+
+	d.adminPassword = password
+	return nil
+}
+
+// InitialAdminPassword returns the password Init gave the built-in
+// administrator, or "" if an administrator existed already. The caller
+// should show it once so it can be used to log in.
+func (d *Database) InitialAdminPassword() string {
+	return d.adminPassword
+}
+
+// column describes a column added to an existing table after its first release.
+type column struct {
+	table      string
+	name       string
+	definition string
+}
+
+// addedColumns lists columns that databases created by older versions are
+// missing. SQLite has no ADD COLUMN IF NOT EXISTS, so migrateColumns checks
+// the table schema before altering it.
+var addedColumns = []column{
+	{"container_templates", "version", "TEXT DEFAULT '1.0.0'"},
+	{"container_templates", "icon", "TEXT DEFAULT ''"},
+	{"container_templates", "schema", "TEXT DEFAULT ''"},
+	{"container_templates", "parameters", "TEXT DEFAULT ''"},
+	{"container_templates", "source", "TEXT DEFAULT 'user'"},
+	// Templates that predate ownership have no creator, so they stay visible
+	// to everyone; new templates are created private unless requested otherwise.
+	{"container_templates", "visibility", "TEXT DEFAULT 'public'"},
+	{"volume_backups", "driver_options", "TEXT DEFAULT ''"},
+	{"job_runs", "summary", "TEXT DEFAULT ''"},
+}
+
+func (d *Database) migrateColumns() error {
+	for _, col := range addedColumns {
+		exists, err := d.hasColumn(col.table, col.name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", col.table, col.name, col.definition)
+		if _, err := d.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", col.table, col.name, err)
+		}
+	}
+
 	return nil
 }
 
+func (d *Database) hasColumn(table, name string) (bool, error) {
+	rows, err := d.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var colName, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &colName, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return false, err
+		}
+		if colName == name {
+			return true, nil
+		}
+	}
+
+	return false, rows.Err()
+}
+
+// GetSetting returns the value stored under key, or "" if it has never been set.
+func (d *Database) GetSetting(key string) (string, error) {
+	var value string
+	err := d.db.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+const setSettingQuery = `INSERT INTO settings (key, value, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP`
+
+// SetSetting stores value under key, replacing any previous value.
+func (d *Database) SetSetting(key, value string) error {
+	_, err := d.db.Exec(setSettingQuery, key, value)
+	return err
+}
+
+// SetSettingTx is SetSetting as part of tx.
+func SetSettingTx(tx *sql.Tx, key, value string) error {
+	_, err := tx.Exec(setSettingQuery, key, value)
+	return err
+}
+
+// Snapshot writes a consistent copy of the database to path using
+// VACUUM INTO, which is safe while the database is in use. The copy is
+// written next to path and only renamed into place once it is complete.
+func (d *Database) Snapshot(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	partial := path + ".partial"
+	defer os.Remove(partial)
+	if _, err := d.db.Exec("VACUUM INTO ?", partial); err != nil {
+		return fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	return os.Rename(partial, path)
+}
+
 func (d *Database) Close() error {
 	return d.db.Close()
 }
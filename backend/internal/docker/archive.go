@@ -0,0 +1,190 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// HelperLabel marks short-lived containers the platform creates to reach
+// volume contents, so they can be told apart from user workloads.
+const HelperLabel = "cyber.platform.helper"
+
+// VolumeMountPath is where helper containers mount the volume they operate on.
+// Archives produced by ExportVolume contain entries under "volume/".
+const VolumeMountPath = "/volume"
+
+// EnsureImage pulls ref unless it is already present locally.
+func (c *Client) EnsureImage(ref string) error {
+	ctx := context.Background()
+	if _, _, err := c.cli.ImageInspectWithRaw(ctx, ref); err == nil {
+		return nil
+	}
+
+	reader, err := c.cli.ImagePull(ctx, ref, types.ImagePullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull helper image %s: %w", ref, err)
+	}
+	defer reader.Close()
+
+	_, err = io.Copy(io.Discard, reader)
+	return err
+}
+
+// createVolumeHelper creates, but does not start, a container from
+// helperImage with the named volume mounted at VolumeMountPath. The archive
+// endpoints work on created containers, so nothing ever runs inside it.
+func (c *Client) createVolumeHelper(name, helperImage, purpose string, readOnly bool) (string, error) {
+	if err := c.EnsureImage(helperImage); err != nil {
+		return "", err
+	}
+
+	resp, err := c.cli.ContainerCreate(context.Background(),
+		&container.Config{
+			Image:  helperImage,
+			Cmd:    []string{"true"},
+			Labels: map[string]string{HelperLabel: purpose},
+		},
+		&container.HostConfig{
+			Mounts: []mount.Mount{{
+				Type:     mount.TypeVolume,
+				Source:   name,
+				Target:   VolumeMountPath,
+				ReadOnly: readOnly,
+			}},
+		},
+		nil, nil, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to create helper container: %w", err)
+	}
+
+	c.holdHelper(resp.ID)
+	return resp.ID, nil
+}
+
+func (c *Client) removeHelper(id string) error {
+	defer c.releaseHelper(id)
+	return c.cli.ContainerRemove(context.Background(), id, container.RemoveOptions{Force: true})
+}
+
+func (c *Client) holdHelper(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.helpers[id] = true
+}
+
+func (c *Client) releaseHelper(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.helpers, id)
+}
+
+// HeldHelpers returns the IDs of the helper containers that are still in
+// use by this process. Stopped helpers outside this set are leftovers.
+func (c *Client) HeldHelpers() map[string]bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	held := make(map[string]bool, len(c.helpers))
+	for id := range c.helpers {
+		held[id] = true
+	}
+	return held
+}
+
+// runHelper runs a helper container from config and hostConfig to
+// completion, removes it and returns what it printed and its exit code.
+func (c *Client) runHelper(config *container.Config, hostConfig *container.HostConfig) (execResult, error) {
+	if err := c.EnsureImage(config.Image); err != nil {
+		return execResult{}, err
+	}
+
+	ctx := context.Background()
+	resp, err := c.cli.ContainerCreate(ctx, config, hostConfig, nil, nil, "")
+	if err != nil {
+		return execResult{}, fmt.Errorf("failed to create helper container: %w", err)
+	}
+	c.holdHelper(resp.ID)
+	defer c.removeHelper(resp.ID)
+
+	waitCh, errCh := c.cli.ContainerWait(ctx, resp.ID, container.WaitConditionNextExit)
+	if err := c.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return execResult{}, fmt.Errorf("failed to start helper container: %w", err)
+	}
+
+	var result execResult
+	select {
+	case status := <-waitCh:
+		if status.Error != nil {
+			return execResult{}, fmt.Errorf("helper container failed: %s", status.Error.Message)
+		}
+		result.ExitCode = int(status.StatusCode)
+	case err := <-errCh:
+		return execResult{}, err
+	}
+
+	logs, err := c.cli.ContainerLogs(ctx, resp.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return execResult{}, fmt.Errorf("failed to read helper output: %w", err)
+	}
+	defer logs.Close()
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, logs); err != nil {
+		return execResult{}, fmt.Errorf("failed to read helper output: %w", err)
+	}
+	result.Stdout, result.Stderr = stdout.String(), stderr.String()
+	return result, nil
+}
+
+// helperArchive removes its helper container once the archive is closed.
+type helperArchive struct {
+	io.ReadCloser
+	client *Client
+	helper string
+}
+
+func (a *helperArchive) Close() error {
+	err := a.ReadCloser.Close()
+	if removeErr := a.client.removeHelper(a.helper); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// ExportVolume returns an uncompressed tar of the volume's contents. The
+// caller must close it to release the helper container.
+func (c *Client) ExportVolume(name, helperImage string) (io.ReadCloser, error) {
+	helper, err := c.createVolumeHelper(name, helperImage, "volume-export", true)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, _, err := c.cli.CopyFromContainer(context.Background(), helper, VolumeMountPath)
+	if err != nil {
+		c.removeHelper(helper)
+		return nil, fmt.Errorf("failed to read volume %s: %w", name, err)
+	}
+
+	return &helperArchive{ReadCloser: reader, client: c, helper: helper}, nil
+}
+
+// ImportVolume extracts a tar produced by ExportVolume into the named
+// volume. The archive may be gzip, bzip2 or xz compressed.
+func (c *Client) ImportVolume(name, helperImage string, content io.Reader) error {
+	helper, err := c.createVolumeHelper(name, helperImage, "volume-import", false)
+	if err != nil {
+		return err
+	}
+	defer c.removeHelper(helper)
+
+	err = c.cli.CopyToContainer(context.Background(), helper, "/", content, types.CopyToContainerOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to write volume %s: %w", name, err)
+	}
+	return nil
+}
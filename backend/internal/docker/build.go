@@ -0,0 +1,97 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// BuildOptions are the user facing parameters of an image build.
+type BuildOptions struct {
+	Tags       []string          `json:"tags"`
+	Dockerfile string            `json:"dockerfile"`
+	Target     string            `json:"target"`
+	BuildArgs  map[string]string `json:"build_args"`
+	Labels     map[string]string `json:"labels"`
+	NoCache    bool              `json:"no_cache"`
+	Pull       bool              `json:"pull"`
+}
+
+// BuildImage builds an image from a tar build context, which may be
+// compressed. Every line of build output is passed to onLog. It returns the
+// ID of the resulting image; a failing build step is returned as an error.
+func (c *Client) BuildImage(buildContext io.Reader, opts BuildOptions, auth map[string]registry.AuthConfig, onLog func(string)) (string, error) {
+	buildArgs := make(map[string]*string, len(opts.BuildArgs))
+	for key, value := range opts.BuildArgs {
+		value := value
+		buildArgs[key] = &value
+	}
+
+	resp, err := c.cli.ImageBuild(context.Background(), buildContext, types.ImageBuildOptions{
+		Tags:        opts.Tags,
+		Dockerfile:  opts.Dockerfile,
+		Target:      opts.Target,
+		BuildArgs:   buildArgs,
+		Labels:      opts.Labels,
+		NoCache:     opts.NoCache,
+		PullParent:  opts.Pull,
+		Remove:      true,
+		AuthConfigs: auth,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	return readBuildOutput(resp.Body, onLog)
+}
+
+// readBuildOutput follows the JSON message stream of a classic build. The
+// image ID arrives as an aux message at the end of a successful build.
+func readBuildOutput(r io.Reader, onLog func(string)) (string, error) {
+	var imageID string
+	decoder := json.NewDecoder(r)
+	for {
+		var message jsonmessage.JSONMessage
+		if err := decoder.Decode(&message); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("failed to read build output: %w", err)
+		}
+
+		if message.Error != nil {
+			return "", message.Error
+		}
+		if message.Aux != nil {
+			var result types.BuildResult
+			if err := json.Unmarshal(*message.Aux, &result); err == nil && result.ID != "" {
+				imageID = result.ID
+			}
+			continue
+		}
+
+		switch {
+		case message.Stream != "":
+			onLog(message.Stream)
+		case message.Status != "":
+			line := message.Status
+			if message.ID != "" {
+				line = message.ID + ": " + line
+			}
+			if message.Progress != nil && message.Progress.Total > 0 {
+				line += fmt.Sprintf(" %d/%d", message.Progress.Current, message.Progress.Total)
+			}
+			onLog(line + "\n")
+		}
+	}
+
+	if imageID == "" {
+		return "", fmt.Errorf("build finished without an image ID")
+	}
+	return imageID, nil
+}
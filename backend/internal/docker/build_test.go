@@ -0,0 +1,40 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBuildOutput(t *testing.T) {
+	output := `{"stream":"Step 1/2 : FROM alpine:3.19\n"}
+{"status":"Pulling fs layer","id":"4abcf2066143"}
+{"stream":"Step 2/2 : RUN echo hi\n"}
+{"aux":{"ID":"sha256:9a8b7c6d"}}
+{"stream":"Successfully built 9a8b7c6d\n"}
+`
+	var lines []string
+	id, err := readBuildOutput(strings.NewReader(output), func(line string) { lines = append(lines, line) })
+	require.NoError(t, err)
+	assert.Equal(t, "sha256:9a8b7c6d", id)
+	assert.Equal(t, []string{
+		"Step 1/2 : FROM alpine:3.19\n",
+		"4abcf2066143: Pulling fs layer\n",
+		"Step 2/2 : RUN echo hi\n",
+		"Successfully built 9a8b7c6d\n",
+	}, lines)
+}
+
+func TestReadBuildOutputError(t *testing.T) {
+	output := `{"stream":"Step 1/2 : RUN false\n"}
+{"errorDetail":{"code":1,"message":"The command '/bin/sh -c false' returned a non-zero code: 1"},"error":"The command '/bin/sh -c false' returned a non-zero code: 1"}
+`
+	_, err := readBuildOutput(strings.NewReader(output), func(string) {})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "non-zero code: 1")
+
+	_, err = readBuildOutput(strings.NewReader(`{"stream":"done\n"}`), func(string) {})
+	assert.Error(t, err, "a build without an image ID is a failure")
+}
@@ -5,6 +5,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"regexp"
+	"sync"
 	"time"
 
 	"github.com/docker/docker/api/types"
@@ -12,25 +14,53 @@ import (
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/pkg/stdcopy"
 )
 
 type Client struct {
 	cli *client.Client
+
+	// helpers holds the IDs of helper containers this client created and
+	// has not removed yet.
+	mu      sync.Mutex
+	helpers map[string]bool
 }
 
 type ContainerInfo struct {
-	ID          string            `json:"id"`
-	Name        string            `json:"name"`
-	Image       string            `json:"image"`
-	Status      string            `json:"status"`
-	State       string            `json:"state"`
-	Created     time.Time         `json:"created"`
-	Ports       []PortInfo        `json:"ports"`
-	Labels      map[string]string `json:"labels"`
-	Environment map[string]string `json:"environment"`
-	CPUUsage    float64           `json:"cpu_usage"`
-	MemoryUsage int64             `json:"memory_usage"`
+	ID          string                      `json:"id"`
+	Name        string                      `json:"name"`
+	Image       string                      `json:"image"`
+	ImageID     string                      `json:"image_id"`
+	Status      string                      `json:"status"`
+	State       string                      `json:"state"`
+	Created     time.Time                   `json:"created"`
+	Ports       []PortInfo                  `json:"ports"`
+	Labels      map[string]string           `json:"labels"`
+	Environment map[string]string           `json:"environment"`
+	Networks    map[string]ContainerNetwork `json:"networks"`
+	Mounts      []MountInfo                 `json:"mounts"`
+	Health      *HealthInfo                 `json:"health,omitempty"`
+	CPUUsage    float64                     `json:"cpu_usage"`
+	MemoryUsage int64                       `json:"memory_usage"`
+}
+
+// ContainerNetwork is a container's endpoint on one network, keyed by
+// network name in ContainerInfo.Networks.
+type ContainerNetwork struct {
+	NetworkID   string   `json:"network_id"`
+	IPAddress   string   `json:"ip_address"`
+	IPv6Address string   `json:"ipv6_address,omitempty"`
+	MacAddress  string   `json:"mac_address"`
+	Aliases     []string `json:"aliases,omitempty"`
+}
+
+type MountInfo struct {
+	Type        string `json:"type"`
+	Name        string `json:"name,omitempty"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	ReadWrite   bool   `json:"read_write"`
 }
 
 type PortInfo struct {
@@ -45,10 +75,27 @@ type NetworkInfo struct {
 	Name       string                      `json:"name"`
 	Driver     string                      `json:"driver"`
 	Scope      string                      `json:"scope"`
+	Created    time.Time                   `json:"created"`
+	Internal   bool                        `json:"internal"`
+	Attachable bool                        `json:"attachable"`
+	EnableIPv6 bool                        `json:"enable_ipv6"`
+	IPAM       NetworkIPAM                 `json:"ipam"`
+	Options    map[string]string           `json:"options"`
 	Labels     map[string]string           `json:"labels"`
 	Containers map[string]NetworkContainer `json:"containers"`
 }
 
+type NetworkIPAM struct {
+	Driver string              `json:"driver"`
+	Config []NetworkIPAMConfig `json:"config"`
+}
+
+type NetworkIPAMConfig struct {
+	Subnet  string `json:"subnet"`
+	Gateway string `json:"gateway,omitempty"`
+	IPRange string `json:"ip_range,omitempty"`
+}
+
 type NetworkContainer struct {
 	Name        string `json:"name"`
 	EndpointID  string `json:"endpoint_id"`
@@ -62,6 +109,7 @@ type VolumeInfo struct {
 	Driver     string            `json:"driver"`
 	Mountpoint string            `json:"mountpoint"`
 	Labels     map[string]string `json:"labels"`
+	Options    map[string]string `json:"options,omitempty"`
 	CreatedAt  time.Time         `json:"created_at"`
 }
 
@@ -92,13 +140,32 @@ func NewClient() (*Client, error) {
 		return nil, fmt.Errorf("failed to create Docker client: %w", err)
 	}
 
-	return &Client{cli: cli}, nil
+	return &Client{cli: cli, helpers: make(map[string]bool)}, nil
+}
+
+// IsNotFound reports whether err is a Docker "no such object" error.
+func IsNotFound(err error) bool {
+	return client.IsErrNotFound(err)
+}
+
+// IsConflict reports whether Docker rejected a request because of the
+// current state of an object, e.g. pausing a stopped container or renaming
+// to a name already in use.
+func IsConflict(err error) bool {
+	return errdefs.IsConflict(err)
+}
+
+// IsInvalid reports whether Docker rejected a request's parameters.
+func IsInvalid(err error) bool {
+	return errdefs.IsInvalidParameter(err)
 }
 
 func (c *Client) ListContainers() ([]ContainerInfo, error) {
-	containers, err := c.cli.ContainerList(context.Background(), container.ListOptions{
-		All: true,
-	})
+	return c.listContainers(container.ListOptions{All: true})
+}
+
+func (c *Client) listContainers(opts container.ListOptions) ([]ContainerInfo, error) {
+	containers, err := c.cli.ContainerList(context.Background(), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -106,13 +173,37 @@ func (c *Client) ListContainers() ([]ContainerInfo, error) {
 	var result []ContainerInfo
 	for _, cont := range containers {
 		info := ContainerInfo{
-			ID:      cont.ID,
-			Name:    cont.Names[0][1:], // Remove leading slash
-			Image:   cont.Image,
-			Status:  cont.Status,
-			State:   cont.State,
-			Created: time.Unix(cont.Created, 0),
-			Labels:  cont.Labels,
+			ID:       cont.ID,
+			Name:     cont.Names[0][1:], // Remove leading slash
+			Image:    cont.Image,
+			ImageID:  cont.ImageID,
+			Status:   cont.Status,
+			State:    cont.State,
+			Created:  time.Unix(cont.Created, 0),
+			Labels:   cont.Labels,
+			Networks: make(map[string]ContainerNetwork),
+		}
+
+		if cont.NetworkSettings != nil {
+			for name, endpoint := range cont.NetworkSettings.Networks {
+				info.Networks[name] = ContainerNetwork{
+					NetworkID:   endpoint.NetworkID,
+					IPAddress:   endpoint.IPAddress,
+					IPv6Address: endpoint.GlobalIPv6Address,
+					MacAddress:  endpoint.MacAddress,
+					Aliases:     endpoint.Aliases,
+				}
+			}
+		}
+
+		for _, m := range cont.Mounts {
+			info.Mounts = append(info.Mounts, MountInfo{
+				Type:        string(m.Type),
+				Name:        m.Name,
+				Source:      m.Source,
+				Destination: m.Destination,
+				ReadWrite:   m.RW,
+			})
 		}
 
 		// Parse ports
@@ -125,6 +216,17 @@ func (c *Client) ListContainers() ([]ContainerInfo, error) {
 			})
 		}
 
+		if status := healthFromStatus(cont.Status); status != "" {
+			info.Health = &HealthInfo{Status: status}
+			// The listing has no probe results; fetch them for containers
+			// whose probes fail.
+			if status == types.Unhealthy {
+				if inspect, err := c.InspectContainer(cont.ID); err == nil && inspect.State != nil && inspect.State.Health != nil {
+					info.Health = toHealthInfo(inspect.State.Health, false)
+				}
+			}
+		}
+
 		result = append(result, info)
 	}
 
@@ -135,16 +237,20 @@ func (c *Client) StartContainer(id string) error {
 	return c.cli.ContainerStart(context.Background(), id, container.StartOptions{})
 }
 
-func (c *Client) StopContainer(id string) error {
-	timeout := int(30)
+// StopContainer sends the stop signal and kills the container if it is
+// still running after timeout seconds.
+func (c *Client) StopContainer(id string, timeout int) error {
 	return c.cli.ContainerStop(context.Background(), id, container.StopOptions{
 		Timeout: &timeout,
 	})
 }
 
-func (c *Client) RemoveContainer(id string) error {
+// RemoveContainer removes a container. force also removes a running one;
+// removeVolumes removes its anonymous volumes.
+func (c *Client) RemoveContainer(id string, force, removeVolumes bool) error {
 	return c.cli.ContainerRemove(context.Background(), id, container.RemoveOptions{
-		Force: true,
+		Force:         force,
+		RemoveVolumes: removeVolumes,
 	})
 }
 
@@ -217,41 +323,138 @@ func (c *Client) ListNetworks() ([]NetworkInfo, error) {
 
 	var result []NetworkInfo
 	for _, net := range networks {
-		info := NetworkInfo{
-			ID:         net.ID,
-			Name:       net.Name,
-			Driver:     net.Driver,
-			Scope:      net.Scope,
-			Labels:     net.Labels,
-			Containers: make(map[string]NetworkContainer),
-		}
+		result = append(result, toNetworkInfo(net))
+	}
 
-		for name, container := range net.Containers {
-			info.Containers[name] = NetworkContainer{
-				Name:        container.Name,
-				EndpointID:  container.EndpointID,
-				MacAddress:  container.MacAddress,
-				IPv4Address: container.IPv4Address,
-				IPv6Address: container.IPv6Address,
-			}
-		}
+	return result, nil
+}
 
-		result = append(result, info)
+// InspectNetwork returns a single network by ID or name.
+func (c *Client) InspectNetwork(id string) (NetworkInfo, error) {
+	net, err := c.cli.NetworkInspect(context.Background(), id, types.NetworkInspectOptions{})
+	if err != nil {
+		return NetworkInfo{}, err
 	}
+	return toNetworkInfo(net), nil
+}
 
-	return result, nil
+func toNetworkInfo(net types.NetworkResource) NetworkInfo {
+	info := NetworkInfo{
+		ID:         net.ID,
+		Name:       net.Name,
+		Driver:     net.Driver,
+		Scope:      net.Scope,
+		Created:    net.Created,
+		Internal:   net.Internal,
+		Attachable: net.Attachable,
+		EnableIPv6: net.EnableIPv6,
+		IPAM:       NetworkIPAM{Driver: net.IPAM.Driver},
+		Options:    net.Options,
+		Labels:     net.Labels,
+		Containers: make(map[string]NetworkContainer),
+	}
+
+	for _, cfg := range net.IPAM.Config {
+		info.IPAM.Config = append(info.IPAM.Config, NetworkIPAMConfig{
+			Subnet:  cfg.Subnet,
+			Gateway: cfg.Gateway,
+			IPRange: cfg.IPRange,
+		})
+	}
+
+	for name, container := range net.Containers {
+		info.Containers[name] = NetworkContainer{
+			Name:        container.Name,
+			EndpointID:  container.EndpointID,
+			MacAddress:  container.MacAddress,
+			IPv4Address: container.IPv4Address,
+			IPv6Address: container.IPv6Address,
+		}
+	}
+
+	return info
 }
 
-func (c *Client) CreateNetwork(name string, driver string) (string, error) {
-	resp, err := c.cli.NetworkCreate(context.Background(), name, types.NetworkCreate{
-		Driver: driver,
-	})
+// NetworkOptions is the full desired configuration of a network. It is also
+// what the platform persists so a network can be recreated identically.
+type NetworkOptions struct {
+	Name        string            `json:"name"`
+	Driver      string            `json:"driver"`
+	Subnet      string            `json:"subnet,omitempty"`
+	Gateway     string            `json:"gateway,omitempty"`
+	IPRange     string            `json:"ip_range,omitempty"`
+	IPAMDriver  string            `json:"ipam_driver,omitempty"`
+	Internal    bool              `json:"internal,omitempty"`
+	Attachable  bool              `json:"attachable,omitempty"`
+	EnableIPv6  bool              `json:"enable_ipv6,omitempty"`
+	IPv6Subnet  string            `json:"ipv6_subnet,omitempty"`
+	IPv6Gateway string            `json:"ipv6_gateway,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Options     map[string]string `json:"options,omitempty"`
+}
+
+func (c *Client) CreateNetwork(opts NetworkOptions) (string, error) {
+	create := types.NetworkCreate{
+		Driver:     opts.Driver,
+		Internal:   opts.Internal,
+		Attachable: opts.Attachable,
+		EnableIPv6: opts.EnableIPv6,
+		Labels:     opts.Labels,
+		Options:    opts.Options,
+	}
+
+	if opts.IPAMDriver != "" || opts.Subnet != "" || opts.IPv6Subnet != "" {
+		ipam := &network.IPAM{Driver: opts.IPAMDriver}
+		if ipam.Driver == "" {
+			ipam.Driver = "default"
+		}
+		if opts.Subnet != "" {
+			ipam.Config = append(ipam.Config, network.IPAMConfig{
+				Subnet:  opts.Subnet,
+				Gateway: opts.Gateway,
+				IPRange: opts.IPRange,
+			})
+		}
+		if opts.IPv6Subnet != "" {
+			ipam.Config = append(ipam.Config, network.IPAMConfig{
+				Subnet:  opts.IPv6Subnet,
+				Gateway: opts.IPv6Gateway,
+			})
+		}
+		create.IPAM = ipam
+	}
+
+	resp, err := c.cli.NetworkCreate(context.Background(), opts.Name, create)
 	if err != nil {
 		return "", err
 	}
 	return resp.ID, nil
 }
 
+// EndpointOptions configures a container's endpoint when it joins a network.
+type EndpointOptions struct {
+	Aliases     []string `json:"aliases,omitempty"`
+	IPv4Address string   `json:"ipv4_address,omitempty"`
+	IPv6Address string   `json:"ipv6_address,omitempty"`
+}
+
+// ConnectNetwork attaches a container, running or not, to a network.
+func (c *Client) ConnectNetwork(networkID, containerID string, opts EndpointOptions) error {
+	settings := &network.EndpointSettings{Aliases: opts.Aliases}
+	if opts.IPv4Address != "" || opts.IPv6Address != "" {
+		settings.IPAMConfig = &network.EndpointIPAMConfig{
+			IPv4Address: opts.IPv4Address,
+			IPv6Address: opts.IPv6Address,
+		}
+	}
+	return c.cli.NetworkConnect(context.Background(), networkID, containerID, settings)
+}
+
+// DisconnectNetwork detaches a container from a network.
+func (c *Client) DisconnectNetwork(networkID, containerID string, force bool) error {
+	return c.cli.NetworkDisconnect(context.Background(), networkID, containerID, force)
+}
+
 func (c *Client) RemoveNetwork(id string) error {
 	return c.cli.NetworkRemove(context.Background(), id)
 }
@@ -264,24 +467,88 @@ func (c *Client) ListVolumes() ([]VolumeInfo, error) {
 
 	var result []VolumeInfo
 	for _, vol := range volumes.Volumes {
-		createdAt, _ := time.Parse(time.RFC3339, vol.CreatedAt)
-		info := VolumeInfo{
-			Name:       vol.Name,
-			Driver:     vol.Driver,
-			Mountpoint: vol.Mountpoint,
-			Labels:     vol.Labels,
-			CreatedAt:  createdAt,
-		}
-		result = append(result, info)
+		result = append(result, toVolumeInfo(*vol))
 	}
 
 	return result, nil
 }
 
-func (c *Client) CreateVolume(name string) (volume.Volume, error) {
+// InspectVolume returns a single volume by name as Docker reports it.
+func (c *Client) InspectVolume(name string) (volume.Volume, error) {
+	return c.cli.VolumeInspect(context.Background(), name)
+}
+
+// GetVolume returns a single volume by name.
+func (c *Client) GetVolume(name string) (VolumeInfo, error) {
+	vol, err := c.InspectVolume(name)
+	if err != nil {
+		return VolumeInfo{}, err
+	}
+	return toVolumeInfo(vol), nil
+}
+
+func toVolumeInfo(vol volume.Volume) VolumeInfo {
+	createdAt, _ := time.Parse(time.RFC3339, vol.CreatedAt)
+	return VolumeInfo{
+		Name:       vol.Name,
+		Driver:     vol.Driver,
+		Mountpoint: vol.Mountpoint,
+		Labels:     vol.Labels,
+		Options:    vol.Options,
+		CreatedAt:  createdAt,
+	}
+}
+
+// volumeNamePattern follows Docker's own volume name rules, which also keep
+// volume names safe to use as directory names.
+var volumeNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ValidVolumeName reports whether Docker accepts name for a volume.
+func ValidVolumeName(name string) bool {
+	return volumeNamePattern.MatchString(name)
+}
+
+// VolumeOptions is the desired configuration of a volume. It is also what
+// the platform persists so a volume can be recreated identically.
+type VolumeOptions struct {
+	Name    string            `json:"name"`
+	Driver  string            `json:"driver"`
+	Options map[string]string `json:"options,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+func (c *Client) CreateVolume(opts VolumeOptions) (volume.Volume, error) {
 	return c.cli.VolumeCreate(context.Background(), volume.CreateOptions{
-		Name: name,
+		Name:       opts.Name,
+		Driver:     opts.Driver,
+		DriverOpts: opts.Options,
+		Labels:     opts.Labels,
+	})
+}
+
+// VolumeUsage is the disk usage Docker reports for a volume. Both fields are
+// -1 when the driver does not report them.
+type VolumeUsage struct {
+	SizeBytes int64 `json:"size_bytes"`
+	RefCount  int64 `json:"ref_count"`
+}
+
+// VolumeUsage looks the volume up in the daemon's disk usage report, which
+// can be slow on hosts with many volumes since Docker sizes all of them.
+func (c *Client) VolumeUsage(name string) (VolumeUsage, error) {
+	usage, err := c.cli.DiskUsage(context.Background(), types.DiskUsageOptions{
+		Types: []types.DiskUsageObject{types.VolumeObject},
 	})
+	if err != nil {
+		return VolumeUsage{}, err
+	}
+
+	for _, vol := range usage.Volumes {
+		if vol.Name == name && vol.UsageData != nil {
+			return VolumeUsage{SizeBytes: vol.UsageData.Size, RefCount: vol.UsageData.RefCount}, nil
+		}
+	}
+	return VolumeUsage{SizeBytes: -1, RefCount: -1}, nil
 }
 
 func (c *Client) RemoveVolume(name string) error {
@@ -301,9 +568,10 @@ func (c *Client) GetSystemInfo() (types.Info, error) {
 	return c.cli.Info(context.Background())
 }
 
-// PullImage pulls a Docker image
-func (c *Client) PullImage(imageName string) (io.ReadCloser, error) {
-	options := types.ImagePullOptions{}
+// PullImage pulls a Docker image. registryAuth is an encoded X-Registry-Auth
+// value, or "" for anonymous pulls.
+func (c *Client) PullImage(imageName, registryAuth string) (io.ReadCloser, error) {
+	options := types.ImagePullOptions{RegistryAuth: registryAuth}
 	return c.cli.ImagePull(context.Background(), imageName, options)
 }
 
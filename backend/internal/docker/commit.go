@@ -0,0 +1,90 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// FileChange is a path that differs between a container's filesystem and
+// its image.
+type FileChange struct {
+	Path string `json:"path"`
+	// Kind is "added", "modified" or "deleted".
+	Kind string `json:"kind"`
+}
+
+var changeKinds = map[container.ChangeType]string{
+	container.ChangeAdd:    "added",
+	container.ChangeModify: "modified",
+	container.ChangeDelete: "deleted",
+}
+
+func toFileChanges(changes []container.FilesystemChange) []FileChange {
+	result := make([]FileChange, 0, len(changes))
+	for _, change := range changes {
+		result = append(result, FileChange{Path: change.Path, Kind: changeKinds[change.Kind]})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result
+}
+
+// ContainerChanges lists the paths added, modified or deleted in the
+// container since it was created from its image, sorted by path.
+func (c *Client) ContainerChanges(id string) ([]FileChange, error) {
+	changes, err := c.cli.ContainerDiff(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+	return toFileChanges(changes), nil
+}
+
+// CommitOptions describe the image a container is committed to.
+type CommitOptions struct {
+	Reference string
+	Message   string
+	Author    string
+	// Pause freezes the container while it is committed, so the snapshot
+	// is consistent.
+	Pause bool
+	// Changes are Dockerfile instructions applied to the image config.
+	Changes []string
+}
+
+// commitInstructions are the Dockerfile instructions Docker applies on
+// commit.
+var commitInstructions = map[string]bool{
+	"CMD": true, "ENTRYPOINT": true, "ENV": true, "EXPOSE": true, "LABEL": true,
+	"ONBUILD": true, "STOPSIGNAL": true, "USER": true, "VOLUME": true, "WORKDIR": true,
+}
+
+// ValidateCommitChanges checks that every change is a Dockerfile
+// instruction Docker can apply on commit.
+func ValidateCommitChanges(changes []string) error {
+	for _, change := range changes {
+		instruction, args, _ := strings.Cut(strings.TrimSpace(change), " ")
+		if !commitInstructions[strings.ToUpper(instruction)] || strings.TrimSpace(args) == "" {
+			return fmt.Errorf("invalid change %q: expected one of CMD, ENTRYPOINT, ENV, EXPOSE, LABEL, ONBUILD, STOPSIGNAL, USER, VOLUME or WORKDIR with arguments", change)
+		}
+	}
+	return nil
+}
+
+// CommitContainer snapshots the container's filesystem into a new image
+// and returns its ID.
+func (c *Client) CommitContainer(id string, opts CommitOptions) (string, error) {
+	resp, err := c.cli.ContainerCommit(context.Background(), id, container.CommitOptions{
+		Reference: opts.Reference,
+		Comment:   opts.Message,
+		Author:    opts.Author,
+		Pause:     opts.Pause,
+		Changes:   opts.Changes,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
@@ -0,0 +1,31 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToFileChanges(t *testing.T) {
+	changes := toFileChanges([]container.FilesystemChange{
+		{Path: "/var/log/app.log", Kind: container.ChangeAdd},
+		{Path: "/etc/nginx/nginx.conf", Kind: container.ChangeModify},
+		{Path: "/tmp/cache", Kind: container.ChangeDelete},
+	})
+
+	assert.Equal(t, []FileChange{
+		{Path: "/etc/nginx/nginx.conf", Kind: "modified"},
+		{Path: "/tmp/cache", Kind: "deleted"},
+		{Path: "/var/log/app.log", Kind: "added"},
+	}, changes)
+	assert.NotNil(t, toFileChanges(nil))
+}
+
+func TestValidateCommitChanges(t *testing.T) {
+	assert.NoError(t, ValidateCommitChanges([]string{`CMD ["nginx", "-g", "daemon off;"]`, "env DEBUG=1", "EXPOSE 8080/tcp"}))
+
+	for _, change := range []string{"RUN rm -rf /", "CMD", "", "COPY . /app"} {
+		assert.Error(t, ValidateCommitChanges([]string{change}), change)
+	}
+}
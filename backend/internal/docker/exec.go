@@ -0,0 +1,49 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// execResult is the outcome of a command run by execCommand.
+type execResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// execCommand runs cmd inside a running container as user ("" for the
+// image's user) and collects its output.
+func (c *Client) execCommand(id, user string, cmd []string) (execResult, error) {
+	ctx := context.Background()
+
+	exec, err := c.cli.ContainerExecCreate(ctx, id, types.ExecConfig{
+		User:         user,
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return execResult{}, err
+	}
+	attach, err := c.cli.ContainerExecAttach(ctx, exec.ID, types.ExecStartCheck{})
+	if err != nil {
+		return execResult{}, err
+	}
+	defer attach.Close()
+
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, attach.Reader); err != nil && err != io.EOF {
+		return execResult{}, err
+	}
+
+	inspect, err := c.cli.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return execResult{}, err
+	}
+	return execResult{Stdout: stdout.String(), Stderr: stderr.String(), ExitCode: inspect.ExitCode}, nil
+}
@@ -0,0 +1,347 @@
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+)
+
+// File types reported in FileEntry.Type.
+const (
+	FileTypeFile      = "file"
+	FileTypeDirectory = "directory"
+	FileTypeSymlink   = "symlink"
+	FileTypeOther     = "other"
+)
+
+// FileEntry describes a file inside a container filesystem.
+type FileEntry struct {
+	Name        string    `json:"name"`
+	Path        string    `json:"path"`
+	Type        string    `json:"type"`
+	Size        int64     `json:"size"`
+	Permissions string    `json:"permissions"`
+	Modified    time.Time `json:"modified"`
+	LinkTarget  string    `json:"link_target,omitempty"`
+}
+
+func fileType(mode os.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return FileTypeDirectory
+	case mode&os.ModeSymlink != 0:
+		return FileTypeSymlink
+	case mode.IsRegular():
+		return FileTypeFile
+	default:
+		return FileTypeOther
+	}
+}
+
+func toFileEntry(p string, stat types.ContainerPathStat) FileEntry {
+	return FileEntry{
+		Name:        path.Base(p),
+		Path:        p,
+		Type:        fileType(stat.Mode),
+		Size:        stat.Size,
+		Permissions: stat.Mode.String(),
+		Modified:    stat.Mtime,
+		LinkTarget:  stat.LinkTarget,
+	}
+}
+
+// StatPath returns information about p inside the container. It works on
+// stopped containers as well.
+func (c *Client) StatPath(id, p string) (FileEntry, error) {
+	stat, err := c.cli.ContainerStatPath(context.Background(), id, p)
+	if err != nil {
+		return FileEntry{}, err
+	}
+	return toFileEntry(p, stat), nil
+}
+
+// ListDir returns the direct children of dir, at most maxEntries of them;
+// truncated reports whether there were more. In a running container the
+// listing runs stat inside it and reads only dir itself. Otherwise, or if the
+// container has no shell and stat, it falls back to the tar stream of
+// CopyFromContainer, which carries the whole subtree, and stops after
+// scanning maxEntries archive headers.
+func (c *Client) ListDir(id, dir string, maxEntries int) (entries []FileEntry, truncated bool, err error) {
+	if inspect, err := c.InspectContainer(id); err == nil && inspect.State != nil && inspect.State.Running {
+		entries, truncated, err := c.listDirExec(id, dir, maxEntries)
+		if err == nil || !errors.Is(err, errNoListCommand) {
+			return entries, truncated, err
+		}
+	}
+
+	reader, stat, err := c.cli.CopyFromContainer(context.Background(), id, dir)
+	if err != nil {
+		return nil, false, err
+	}
+	defer reader.Close()
+
+	if !stat.Mode.IsDir() {
+		return nil, false, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	return listArchive(reader, stat.Name, dir, maxEntries)
+}
+
+// errNoListCommand means the container lacks the sh or stat commands
+// listDirExec needs.
+var errNoListCommand = errors.New("container cannot run the directory listing")
+
+// listDirScript prints "<hex mode> <size> <mtime>", the name and the link
+// target of each entry of directory $1, NUL separated, and stops with a lone
+// "+" after $2 entries. It only needs a POSIX sh and stat, as found in
+// busybox and coreutils.
+const listDirScript = `cd -- "$1" || exit 2
+n=0
+for f in * .[!.]* ..?*; do
+	[ -e "$f" ] || [ -L "$f" ] || continue
+	if [ "$n" -ge "$2" ]; then printf '+\0'; exit 0; fi
+	n=$((n+1))
+	t=
+	[ -L "$f" ] && t=$(readlink -- "$f")
+	s=$(stat -c '%f %s %Y' -- "$f") || exit 3
+	printf '%s\0%s\0%s\0' "$s" "$f" "$t"
+done`
+
+func listDirCommand(dir string, maxEntries int) []string {
+	return []string{"sh", "-c", listDirScript, "sh", dir, strconv.Itoa(maxEntries)}
+}
+
+func (c *Client) listDirExec(id, dir string, maxEntries int) ([]FileEntry, bool, error) {
+	result, err := c.execCommand(id, "", listDirCommand(dir, maxEntries))
+	if err != nil {
+		return nil, false, err
+	}
+	return listDirResult(result, dir)
+}
+
+// listDirResult parses the listing of a listDirScript run.
+func listDirResult(result execResult, dir string) ([]FileEntry, bool, error) {
+	switch result.ExitCode {
+	case 0:
+		return parseDirListing(result.Stdout, dir)
+	case 2:
+		return nil, false, fmt.Errorf("%s is not a directory: %s", dir, strings.TrimSpace(result.Stderr))
+	default:
+		// 126 and 127 report a missing shell; anything else a failing stat.
+		return nil, false, fmt.Errorf("%w: %s", errNoListCommand, strings.TrimSpace(result.Stderr))
+	}
+}
+
+// parseDirListing parses the output of listDirScript.
+func parseDirListing(out, dir string) ([]FileEntry, bool, error) {
+	fields := strings.Split(out, "\x00")
+	entries := []FileEntry{}
+	for i := 0; i+2 < len(fields); i += 3 {
+		var mode uint32
+		var size, mtime int64
+		if _, err := fmt.Sscanf(fields[i], "%x %d %d", &mode, &size, &mtime); err != nil {
+			return nil, false, fmt.Errorf("unexpected listing line %q", fields[i])
+		}
+		// The tar header conversion knows the Unix file type bits.
+		info := (&tar.Header{Mode: int64(mode)}).FileInfo()
+		name := fields[i+1]
+		entries = append(entries, FileEntry{
+			Name:        name,
+			Path:        path.Join(dir, name),
+			Type:        fileType(info.Mode()),
+			Size:        size,
+			Permissions: info.Mode().String(),
+			Modified:    time.Unix(mtime, 0).UTC(),
+			LinkTarget:  fields[i+2],
+		})
+	}
+	truncated := len(fields)%3 == 2 && fields[len(fields)-2] == "+"
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, truncated, nil
+}
+
+// listArchive collects the direct children of root from a tar produced by
+// CopyFromContainer, whose entries are named "<root>/<child>/...".
+func listArchive(r io.Reader, root, dir string, maxHeaders int) ([]FileEntry, bool, error) {
+	root = strings.Trim(root, "/")
+	if root == "." {
+		root = ""
+	}
+
+	entries := []FileEntry{}
+	tr := tar.NewReader(r)
+
+	for scanned := 0; ; scanned++ {
+		if scanned >= maxHeaders {
+			return entries, true, nil
+		}
+
+		header, err := tr.Next()
+		if err == io.EOF {
+			return entries, false, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read directory archive: %w", err)
+		}
+
+		name := strings.Trim(strings.TrimPrefix(header.Name, "./"), "/")
+		if root != "" {
+			if !strings.HasPrefix(name, root+"/") {
+				continue
+			}
+			name = strings.TrimPrefix(name, root+"/")
+		}
+		if name == "" || strings.Contains(name, "/") {
+			continue
+		}
+
+		info := header.FileInfo()
+		entries = append(entries, FileEntry{
+			Name:        name,
+			Path:        path.Join(dir, name),
+			Type:        fileType(info.Mode()),
+			Size:        header.Size,
+			Permissions: info.Mode().String(),
+			Modified:    header.ModTime,
+			LinkTarget:  header.Linkname,
+		})
+	}
+}
+
+// fileReader streams one file out of a CopyFromContainer archive.
+type fileReader struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (f *fileReader) Close() error {
+	return f.closer.Close()
+}
+
+// OpenPath streams p out of the container. Regular files are returned as
+// their contents; directories as an uncompressed tar of the subtree.
+func (c *Client) OpenPath(id, p string) (io.ReadCloser, FileEntry, error) {
+	reader, stat, err := c.cli.CopyFromContainer(context.Background(), id, p)
+	if err != nil {
+		return nil, FileEntry{}, err
+	}
+
+	entry := toFileEntry(p, stat)
+	if entry.Type != FileTypeFile {
+		return reader, entry, nil
+	}
+
+	tr := tar.NewReader(reader)
+	if _, err := tr.Next(); err != nil {
+		reader.Close()
+		return nil, FileEntry{}, fmt.Errorf("failed to read %s: %w", p, err)
+	}
+
+	return &fileReader{Reader: tr, closer: reader}, entry, nil
+}
+
+// WriteFile streams content into dir/name inside the container, replacing
+// an existing file of that name. size must be the exact content length.
+func (c *Client) WriteFile(id, dir, name string, content io.Reader, size int64) error {
+	pr, pw := io.Pipe()
+
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    size,
+			ModTime: time.Now(),
+		})
+		if err == nil {
+			_, err = io.Copy(tw, content)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	err := c.cli.CopyToContainer(context.Background(), id, dir, pr, types.CopyToContainerOptions{})
+	pr.CloseWithError(err)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path.Join(dir, name), err)
+	}
+	return nil
+}
+
+// RemovePath deletes p recursively by running rm inside the container, which
+// must therefore be running and ship an rm binary.
+func (c *Client) RemovePath(id, p string) error {
+	result, err := c.execCommand(id, "", []string{"rm", "-rf", "--", p})
+	if err != nil {
+		return fmt.Errorf("failed to run rm: %w", err)
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("rm exited with code %d: %s", result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+	return nil
+}
+
+// OpenVolume creates a helper container with the volume mounted at
+// VolumeMountPath, so the file methods above can be used on the volume.
+// release removes the helper and must be called once the caller is done.
+func (c *Client) OpenVolume(name, helperImage string, readOnly bool) (id string, release func(), err error) {
+	id, err = c.createVolumeHelper(name, helperImage, "volume-files", readOnly)
+	if err != nil {
+		return "", nil, err
+	}
+	return id, func() { c.removeHelper(id) }, nil
+}
+
+// RemoveVolumePath deletes p (relative to the volume root) by running rm in
+// a helper container.
+func (c *Client) RemoveVolumePath(name, helperImage, p string) error {
+	result, err := c.runHelper(
+		&container.Config{
+			Image:  helperImage,
+			Cmd:    []string{"rm", "-rf", "--", path.Join(VolumeMountPath, p)},
+			Labels: map[string]string{HelperLabel: "volume-files"},
+		},
+		&container.HostConfig{
+			Mounts: []mount.Mount{{Type: mount.TypeVolume, Source: name, Target: VolumeMountPath}},
+		})
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("rm exited with code %d: %s", result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+	return nil
+}
+
+// ListVolumeDir is ListDir for the directory dir of a volume, a path under
+// VolumeMountPath. The listing script runs once in a helper container with
+// the volume mounted read-only, so only dir itself is read.
+func (c *Client) ListVolumeDir(name, helperImage, dir string, maxEntries int) ([]FileEntry, bool, error) {
+	result, err := c.runHelper(
+		&container.Config{
+			Image:  helperImage,
+			Cmd:    listDirCommand(dir, maxEntries),
+			Labels: map[string]string{HelperLabel: "volume-files"},
+		},
+		&container.HostConfig{
+			Mounts: []mount.Mount{{Type: mount.TypeVolume, Source: name, Target: VolumeMountPath, ReadOnly: true}},
+		})
+	if err != nil {
+		return nil, false, err
+	}
+	return listDirResult(result, dir)
+}
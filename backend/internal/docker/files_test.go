@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildArchive(t *testing.T, headers ...*tar.Header) *bytes.Buffer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, header := range headers {
+		header.ModTime = time.Unix(1700000000, 0)
+		require.NoError(t, tw.WriteHeader(header))
+		if header.Size > 0 {
+			_, err := tw.Write(make([]byte, header.Size))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tw.Close())
+	return &buf
+}
+
+func TestListArchive(t *testing.T) {
+	archive := buildArchive(t,
+		&tar.Header{Name: "nginx/", Typeflag: tar.TypeDir, Mode: 0755},
+		&tar.Header{Name: "nginx/conf.d/", Typeflag: tar.TypeDir, Mode: 0755},
+		&tar.Header{Name: "nginx/conf.d/default.conf", Typeflag: tar.TypeReg, Mode: 0644, Size: 12},
+		&tar.Header{Name: "nginx/nginx.conf", Typeflag: tar.TypeReg, Mode: 0644, Size: 5},
+		&tar.Header{Name: "nginx/current", Typeflag: tar.TypeSymlink, Linkname: "conf.d", Mode: 0777},
+	)
+
+	entries, truncated, err := listArchive(archive, "nginx", "/etc/nginx", 100)
+	require.NoError(t, err)
+	assert.False(t, truncated)
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, FileEntry{Name: "conf.d", Path: "/etc/nginx/conf.d", Type: FileTypeDirectory, Permissions: "drwxr-xr-x", Modified: time.Unix(1700000000, 0)}, entries[0])
+	assert.Equal(t, "/etc/nginx/nginx.conf", entries[1].Path)
+	assert.Equal(t, FileTypeFile, entries[1].Type)
+	assert.Equal(t, int64(5), entries[1].Size)
+	assert.Equal(t, FileTypeSymlink, entries[2].Type)
+	assert.Equal(t, "conf.d", entries[2].LinkTarget)
+}
+
+func TestListArchiveRootAndLimit(t *testing.T) {
+	headers := func() []*tar.Header {
+		return []*tar.Header{
+			{Name: "./", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "./bin/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "./bin/sh", Typeflag: tar.TypeReg, Mode: 0755},
+			{Name: "./etc/", Typeflag: tar.TypeDir, Mode: 0755},
+		}
+	}
+
+	entries, truncated, err := listArchive(buildArchive(t, headers()...), "/", "/", 100)
+	require.NoError(t, err)
+	assert.False(t, truncated)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "/bin", entries[0].Path)
+	assert.Equal(t, "/etc", entries[1].Path)
+
+	entries, truncated, err = listArchive(buildArchive(t, headers()...), "/", "/", 3)
+	require.NoError(t, err)
+	assert.True(t, truncated)
+	assert.Len(t, entries, 1)
+}
+
+func TestParseDirListing(t *testing.T) {
+	out := "41ed 4096 1700000000\x00conf.d\x00\x00" +
+		"81a4 5 1700000001\x00nginx.conf\x00\x00" +
+		"a1ff 6 1700000002\x00current\x00conf.d\x00" +
+		"81a4 0 1700000003\x00.hidden file\x00\x00"
+
+	entries, truncated, err := parseDirListing(out, "/etc/nginx")
+	require.NoError(t, err)
+	assert.False(t, truncated)
+	require.Len(t, entries, 4)
+
+	assert.Equal(t, FileEntry{Name: ".hidden file", Path: "/etc/nginx/.hidden file", Type: FileTypeFile, Permissions: "-rw-r--r--", Modified: time.Unix(1700000003, 0).UTC()}, entries[0])
+	assert.Equal(t, FileTypeDirectory, entries[1].Type)
+	assert.Equal(t, "drwxr-xr-x", entries[1].Permissions)
+	assert.Equal(t, FileTypeSymlink, entries[2].Type)
+	assert.Equal(t, "conf.d", entries[2].LinkTarget)
+	assert.Equal(t, int64(5), entries[3].Size)
+
+	_, truncated, err = parseDirListing("81a4 5 1700000001\x00a\x00\x00+\x00", "/")
+	require.NoError(t, err)
+	assert.True(t, truncated)
+
+	entries, _, err = parseDirListing("", "/empty")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
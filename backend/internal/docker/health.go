@@ -0,0 +1,85 @@
+package docker
+
+import (
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+// ContainerHealthStates are the values of HealthInfo.Status, plus "none"
+// for containers without a healthcheck in a ContainerQuery.
+var ContainerHealthStates = []string{types.Starting, types.Healthy, types.Unhealthy, types.NoHealthcheck}
+
+// HealthInfo is the healthcheck state of a container.
+type HealthInfo struct {
+	// Status is "starting", "healthy" or "unhealthy".
+	Status        string `json:"status"`
+	FailingStreak int    `json:"failing_streak"`
+	// LastOutput is the output of the latest failed probe.
+	LastOutput string `json:"last_output,omitempty"`
+	// Probes are the latest probe results, oldest first. They are only
+	// filled in by ContainerHealth.
+	Probes []HealthProbe `json:"probes,omitempty"`
+}
+
+// HealthProbe is the result of one healthcheck run.
+type HealthProbe struct {
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	ExitCode int       `json:"exit_code"`
+	Output   string    `json:"output"`
+}
+
+// healthFromStatus extracts the health status from the status text of a
+// container listing, such as "Up 5 minutes (health: starting)".
+func healthFromStatus(status string) string {
+	switch {
+	case strings.HasSuffix(status, "(health: starting)"):
+		return types.Starting
+	case strings.HasSuffix(status, "(unhealthy)"):
+		return types.Unhealthy
+	case strings.HasSuffix(status, "(healthy)"):
+		return types.Healthy
+	default:
+		return ""
+	}
+}
+
+func toHealthInfo(health *types.Health, withProbes bool) *HealthInfo {
+	info := &HealthInfo{Status: health.Status, FailingStreak: health.FailingStreak}
+	for i := len(health.Log) - 1; i >= 0; i-- {
+		if probe := health.Log[i]; probe != nil && probe.ExitCode != 0 {
+			info.LastOutput = strings.TrimSpace(probe.Output)
+			break
+		}
+	}
+	if withProbes {
+		info.Probes = make([]HealthProbe, 0, len(health.Log))
+		for _, probe := range health.Log {
+			if probe == nil {
+				continue
+			}
+			info.Probes = append(info.Probes, HealthProbe{
+				Start:    probe.Start,
+				End:      probe.End,
+				ExitCode: probe.ExitCode,
+				Output:   strings.TrimSpace(probe.Output),
+			})
+		}
+	}
+	return info
+}
+
+// ContainerHealth returns the healthcheck state of a container with its
+// latest probe results, or nil if it has no healthcheck or is not running.
+func (c *Client) ContainerHealth(id string) (*HealthInfo, error) {
+	inspect, err := c.InspectContainer(id)
+	if err != nil {
+		return nil, err
+	}
+	if inspect.State == nil || inspect.State.Health == nil || inspect.State.Health.Status == types.NoHealthcheck {
+		return nil, nil
+	}
+	return toHealthInfo(inspect.State.Health, true), nil
+}
@@ -0,0 +1,40 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthFromStatus(t *testing.T) {
+	assert.Equal(t, "starting", healthFromStatus("Up 3 seconds (health: starting)"))
+	assert.Equal(t, "healthy", healthFromStatus("Up 5 minutes (healthy)"))
+	assert.Equal(t, "unhealthy", healthFromStatus("Up 2 hours (unhealthy)"))
+	assert.Empty(t, healthFromStatus("Up 2 hours"))
+	assert.Empty(t, healthFromStatus("Exited (0) 3 minutes ago"))
+}
+
+func TestToHealthInfo(t *testing.T) {
+	health := &types.Health{
+		Status:        types.Unhealthy,
+		FailingStreak: 2,
+		Log: []*types.HealthcheckResult{
+			{ExitCode: 1, Output: "connection refused\n"},
+			{ExitCode: 0, Output: "ok"},
+			{ExitCode: 1, Output: "curl: (22) The requested URL returned error: 503\n"},
+			{ExitCode: 1, Output: ""},
+		},
+	}
+
+	info := toHealthInfo(health, false)
+	assert.Equal(t, types.Unhealthy, info.Status)
+	assert.Equal(t, 2, info.FailingStreak)
+	assert.Empty(t, info.LastOutput, "the latest failure had no output")
+	assert.Nil(t, info.Probes)
+
+	health.Log = health.Log[:3]
+	info = toHealthInfo(health, true)
+	assert.Equal(t, "curl: (22) The requested URL returned error: 503", info.LastOutput)
+	assert.Len(t, info.Probes, 3)
+}
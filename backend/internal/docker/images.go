@@ -0,0 +1,214 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// ImageDetail is the inspect view of an image together with its build
+// history.
+type ImageDetail struct {
+	ID           string         `json:"id"`
+	RepoTags     []string       `json:"repo_tags"`
+	RepoDigests  []string       `json:"repo_digests"`
+	Parent       string         `json:"parent,omitempty"`
+	Created      time.Time      `json:"created"`
+	Author       string         `json:"author,omitempty"`
+	Architecture string         `json:"architecture"`
+	Variant      string         `json:"variant,omitempty"`
+	Os           string         `json:"os"`
+	SizeBytes    int64          `json:"size_bytes"`
+	Config       ImageConfig    `json:"config"`
+	Layers       []string       `json:"layers"`
+	History      []ImageHistory `json:"history"`
+}
+
+// ImageConfig is the runtime configuration baked into an image.
+type ImageConfig struct {
+	User         string            `json:"user,omitempty"`
+	Env          []string          `json:"env"`
+	Entrypoint   []string          `json:"entrypoint"`
+	Cmd          []string          `json:"cmd"`
+	WorkingDir   string            `json:"working_dir,omitempty"`
+	ExposedPorts []string          `json:"exposed_ports"`
+	Volumes      []string          `json:"volumes"`
+	Labels       map[string]string `json:"labels"`
+	StopSignal   string            `json:"stop_signal,omitempty"`
+	Healthcheck  []string          `json:"healthcheck,omitempty"`
+}
+
+// ImageHistory is one step of an image's build. Steps that only change
+// metadata (ENV, CMD, ...) have an empty layer.
+type ImageHistory struct {
+	ID         string    `json:"id,omitempty"`
+	Created    time.Time `json:"created"`
+	CreatedBy  string    `json:"created_by"`
+	Comment    string    `json:"comment,omitempty"`
+	Tags       []string  `json:"tags,omitempty"`
+	SizeBytes  int64     `json:"size_bytes"`
+	Percent    float64   `json:"percent"`
+	EmptyLayer bool      `json:"empty_layer"`
+}
+
+// InspectImage returns the inspect data and layer history of an image.
+// ref may be an image ID, a short ID or a reference such as "nginx:1.25".
+func (c *Client) InspectImage(ref string) (ImageDetail, error) {
+	ctx := context.Background()
+
+	inspect, _, err := c.cli.ImageInspectWithRaw(ctx, ref)
+	if err != nil {
+		return ImageDetail{}, err
+	}
+
+	history, err := c.cli.ImageHistory(ctx, inspect.ID)
+	if err != nil {
+		return ImageDetail{}, err
+	}
+
+	return toImageDetail(inspect, history), nil
+}
+
+func toImageDetail(inspect types.ImageInspect, history []image.HistoryResponseItem) ImageDetail {
+	created, _ := time.Parse(time.RFC3339Nano, inspect.Created)
+
+	detail := ImageDetail{
+		ID:           inspect.ID,
+		RepoTags:     nonNil(inspect.RepoTags),
+		RepoDigests:  nonNil(inspect.RepoDigests),
+		Parent:       inspect.Parent,
+		Created:      created,
+		Author:       inspect.Author,
+		Architecture: inspect.Architecture,
+		Variant:      inspect.Variant,
+		Os:           inspect.Os,
+		SizeBytes:    inspect.Size,
+		Layers:       nonNil(inspect.RootFS.Layers),
+		History:      []ImageHistory{},
+	}
+
+	if cfg := inspect.Config; cfg != nil {
+		detail.Config = ImageConfig{
+			User:         cfg.User,
+			Env:          nonNil(cfg.Env),
+			Entrypoint:   nonNil(cfg.Entrypoint),
+			Cmd:          nonNil(cfg.Cmd),
+			WorkingDir:   cfg.WorkingDir,
+			ExposedPorts: []string{},
+			Volumes:      []string{},
+			Labels:       cfg.Labels,
+			StopSignal:   cfg.StopSignal,
+		}
+		for port := range cfg.ExposedPorts {
+			detail.Config.ExposedPorts = append(detail.Config.ExposedPorts, string(port))
+		}
+		for volume := range cfg.Volumes {
+			detail.Config.Volumes = append(detail.Config.Volumes, volume)
+		}
+		sort.Strings(detail.Config.ExposedPorts)
+		sort.Strings(detail.Config.Volumes)
+		if cfg.Healthcheck != nil {
+			detail.Config.Healthcheck = cfg.Healthcheck.Test
+		}
+	} else {
+		detail.Config = ImageConfig{Env: []string{}, Entrypoint: []string{}, Cmd: []string{}, ExposedPorts: []string{}, Volumes: []string{}}
+	}
+
+	for _, item := range history {
+		step := ImageHistory{
+			Created:    time.Unix(item.Created, 0).UTC(),
+			CreatedBy:  strings.TrimSpace(strings.TrimPrefix(item.CreatedBy, "/bin/sh -c #(nop)")),
+			Comment:    item.Comment,
+			Tags:       item.Tags,
+			SizeBytes:  item.Size,
+			EmptyLayer: item.Size == 0,
+		}
+		// Docker reports "<missing>" for steps built elsewhere.
+		if item.ID != "<missing>" {
+			step.ID = item.ID
+		}
+		if inspect.Size > 0 {
+			step.Percent = math.Round(float64(item.Size)*1000/float64(inspect.Size)) / 10
+		}
+		detail.History = append(detail.History, step)
+	}
+
+	return detail
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// TagImage adds the reference target to the image source.
+func (c *Client) TagImage(source, target string) error {
+	return c.cli.ImageTag(context.Background(), source, target)
+}
+
+// PushImage pushes ref and returns Docker's JSON progress stream. Push
+// failures are reported inside the stream, not as an error.
+func (c *Client) PushImage(ref, registryAuth string) (io.ReadCloser, error) {
+	// The daemon rejects pushes without an auth header, even anonymous ones.
+	if registryAuth == "" {
+		registryAuth = "e30=" // base64 of "{}"
+	}
+	return c.cli.ImagePush(context.Background(), ref, types.ImagePushOptions{RegistryAuth: registryAuth})
+}
+
+// SaveImages returns a tar archive of the given images in the format
+// accepted by LoadImages and "docker load".
+func (c *Client) SaveImages(refs []string) (io.ReadCloser, error) {
+	return c.cli.ImageSave(context.Background(), refs)
+}
+
+// LoadImages imports a tar produced by SaveImages and returns the
+// references ("Loaded image: nginx:1.25") it reported.
+func (c *Client) LoadImages(archive io.Reader) ([]string, error) {
+	resp, err := c.cli.ImageLoad(context.Background(), archive, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	loaded := []string{}
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var message jsonmessage.JSONMessage
+		if err := decoder.Decode(&message); err == io.EOF {
+			return loaded, nil
+		} else if err != nil {
+			return loaded, fmt.Errorf("failed to read load response: %w", err)
+		}
+
+		if message.Error != nil {
+			return loaded, message.Error
+		}
+		for _, prefix := range []string{"Loaded image: ", "Loaded image ID: "} {
+			if name, ok := strings.CutPrefix(strings.TrimSpace(message.Stream), prefix); ok {
+				loaded = append(loaded, name)
+			}
+		}
+	}
+}
+
+// RemoteDigest asks the registry for the manifest digest that ref currently
+// resolves to, without pulling the image.
+func (c *Client) RemoteDigest(ref, registryAuth string) (string, error) {
+	inspect, err := c.cli.DistributionInspect(context.Background(), ref, registryAuth)
+	if err != nil {
+		return "", err
+	}
+	return string(inspect.Descriptor.Digest), nil
+}
@@ -0,0 +1,52 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToImageDetail(t *testing.T) {
+	inspect := types.ImageInspect{
+		ID:           "sha256:abc",
+		RepoTags:     []string{"api:1.2"},
+		Created:      "2025-10-15T16:00:00.123456789Z",
+		Architecture: "amd64",
+		Os:           "linux",
+		Size:         2000,
+		Config: &container.Config{
+			Env:          []string{"PATH=/usr/bin"},
+			Entrypoint:   []string{"/entrypoint.sh"},
+			ExposedPorts: nat.PortSet{"8080/tcp": {}, "443/tcp": {}},
+			Volumes:      map[string]struct{}{"/data": {}},
+		},
+		RootFS: types.RootFS{Type: "layers", Layers: []string{"sha256:l1", "sha256:l2"}},
+	}
+	history := []image.HistoryResponseItem{
+		{ID: "sha256:abc", Created: 1760544000, CreatedBy: "/bin/sh -c #(nop)  CMD [\"serve\"]", Tags: []string{"api:1.2"}},
+		{ID: "<missing>", Created: 1760543000, CreatedBy: "RUN npm ci", Size: 1500},
+		{ID: "<missing>", Created: 1760542000, CreatedBy: "/bin/sh -c #(nop) ADD file:123 in /", Size: 500},
+	}
+
+	detail := toImageDetail(inspect, history)
+
+	assert.Equal(t, "sha256:abc", detail.ID)
+	assert.Equal(t, 2025, detail.Created.Year())
+	assert.Empty(t, detail.RepoDigests)
+	assert.NotNil(t, detail.RepoDigests)
+	assert.Equal(t, []string{"443/tcp", "8080/tcp"}, detail.Config.ExposedPorts)
+	assert.Equal(t, []string{"/data"}, detail.Config.Volumes)
+	assert.Equal(t, []string{}, detail.Config.Cmd)
+	assert.Len(t, detail.Layers, 2)
+
+	assert.Len(t, detail.History, 3)
+	assert.Equal(t, `CMD ["serve"]`, detail.History[0].CreatedBy)
+	assert.True(t, detail.History[0].EmptyLayer)
+	assert.Equal(t, "", detail.History[1].ID)
+	assert.Equal(t, 75.0, detail.History[1].Percent)
+	assert.Equal(t, "ADD file:123 in /", detail.History[2].CreatedBy)
+}
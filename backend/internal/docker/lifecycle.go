@@ -0,0 +1,79 @@
+package docker
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// RestartContainer stops the container, waiting up to timeout seconds, and
+// starts it again.
+func (c *Client) RestartContainer(id string, timeout int) error {
+	return c.cli.ContainerRestart(context.Background(), id, container.StopOptions{
+		Timeout: &timeout,
+	})
+}
+
+func (c *Client) PauseContainer(id string) error {
+	return c.cli.ContainerPause(context.Background(), id)
+}
+
+func (c *Client) UnpauseContainer(id string) error {
+	return c.cli.ContainerUnpause(context.Background(), id)
+}
+
+// KillContainer sends signal, a name such as "SIGHUP" or a number, to the
+// container's main process.
+func (c *Client) KillContainer(id, signal string) error {
+	return c.cli.ContainerKill(context.Background(), id, signal)
+}
+
+func (c *Client) RenameContainer(id, name string) error {
+	return c.cli.ContainerRename(context.Background(), id, name)
+}
+
+// ResourceUpdate is a live change to a container's resource limits and
+// restart policy. Nil fields are left unchanged.
+type ResourceUpdate struct {
+	NanoCPUs          *int64
+	CPUShares         *int64
+	Memory            *int64
+	MemoryReservation *int64
+	MemorySwap        *int64
+	PidsLimit         *int64
+	RestartPolicy     *container.RestartPolicy
+}
+
+// UpdateContainer applies update to a running or stopped container and
+// returns Docker's warnings, for example about unsupported cgroup options.
+func (c *Client) UpdateContainer(id string, update ResourceUpdate) ([]string, error) {
+	var config container.UpdateConfig
+	if update.NanoCPUs != nil {
+		config.NanoCPUs = *update.NanoCPUs
+	}
+	if update.CPUShares != nil {
+		config.CPUShares = *update.CPUShares
+	}
+	if update.Memory != nil {
+		config.Memory = *update.Memory
+	}
+	if update.MemoryReservation != nil {
+		config.MemoryReservation = *update.MemoryReservation
+	}
+	if update.MemorySwap != nil {
+		config.MemorySwap = *update.MemorySwap
+	}
+	config.PidsLimit = update.PidsLimit
+	if update.RestartPolicy != nil {
+		config.RestartPolicy = *update.RestartPolicy
+	}
+
+	resp, err := c.cli.ContainerUpdate(context.Background(), id, config)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Warnings == nil {
+		return []string{}, nil
+	}
+	return resp.Warnings, nil
+}
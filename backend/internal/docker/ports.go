@@ -0,0 +1,94 @@
+package docker
+
+import (
+	"context"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+// PublishedPort is a host port claimed by a container: published by a
+// running one, or bound in the configuration of a stopped one, which takes
+// it again when it starts.
+type PublishedPort struct {
+	HostPort      int
+	Protocol      string
+	ContainerID   string
+	ContainerName string
+	State         string
+}
+
+// boundPorts lists the fixed host ports of a container's port bindings.
+// Bindings without a host port get a random one when the container starts.
+func boundPorts(bindings nat.PortMap) []PublishedPort {
+	var out []PublishedPort
+	for port, list := range bindings {
+		for _, binding := range list {
+			if binding.HostPort == "" {
+				continue
+			}
+			start, end, err := nat.ParsePortRangeToInt(binding.HostPort)
+			if err != nil || start == 0 {
+				continue
+			}
+			for p := start; p <= end; p++ {
+				out = append(out, PublishedPort{HostPort: p, Protocol: port.Proto()})
+			}
+		}
+	}
+	return out
+}
+
+// PublishedPorts lists the host ports claimed by all containers.
+func (c *Client) PublishedPorts() ([]PublishedPort, error) {
+	ctx := context.Background()
+	containers, err := c.cli.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []PublishedPort
+	for _, cont := range containers {
+		var name string
+		if len(cont.Names) > 0 {
+			name = strings.TrimPrefix(cont.Names[0], "/")
+		}
+
+		var ports []PublishedPort
+		if cont.State == "running" || cont.State == "paused" {
+			for _, p := range cont.Ports {
+				if p.PublicPort != 0 {
+					ports = append(ports, PublishedPort{HostPort: int(p.PublicPort), Protocol: p.Type})
+				}
+			}
+		} else {
+			inspect, err := c.InspectContainer(cont.ID)
+			if err != nil {
+				// Removed since it was listed.
+				continue
+			}
+			if inspect.HostConfig != nil {
+				ports = boundPorts(inspect.HostConfig.PortBindings)
+			}
+		}
+
+		for _, p := range ports {
+			p.ContainerID = cont.ID
+			p.ContainerName = name
+			p.State = cont.State
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// IsPortInUse reports whether a container failed to start because one of
+// its host ports is taken.
+func IsPortInUse(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "port is already allocated") || strings.Contains(msg, "address already in use")
+}
@@ -0,0 +1,35 @@
+package docker
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoundPorts(t *testing.T) {
+	ports := boundPorts(nat.PortMap{
+		"80/tcp":   {{HostPort: "8080"}, {HostIP: "::", HostPort: "8080"}},
+		"53/udp":   {{HostPort: "5353"}},
+		"9000/tcp": {{HostPort: "9000-9001"}},
+		"443/tcp":  {{HostPort: ""}},
+	})
+	sort.Slice(ports, func(i, j int) bool { return ports[i].HostPort < ports[j].HostPort })
+
+	assert.Equal(t, []PublishedPort{
+		{HostPort: 5353, Protocol: "udp"},
+		{HostPort: 8080, Protocol: "tcp"},
+		{HostPort: 8080, Protocol: "tcp"},
+		{HostPort: 9000, Protocol: "tcp"},
+		{HostPort: 9001, Protocol: "tcp"},
+	}, ports, "random host ports are not claimed")
+}
+
+func TestIsPortInUse(t *testing.T) {
+	assert.True(t, IsPortInUse(errors.New("driver failed programming external connectivity: Bind for 0.0.0.0:8080 failed: port is already allocated")))
+	assert.True(t, IsPortInUse(errors.New("listen tcp4 0.0.0.0:80: bind: address already in use")))
+	assert.False(t, IsPortInUse(errors.New("No such image: nginx:nope")))
+	assert.False(t, IsPortInUse(nil))
+}
@@ -0,0 +1,51 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// Resource kinds that can be pruned.
+const (
+	KindContainer  = "container"
+	KindImage      = "image"
+	KindVolume     = "volume"
+	KindNetwork    = "network"
+	KindBuildCache = "build_cache"
+)
+
+// DiskUsage returns the daemon's disk usage report for all object types.
+func (c *Client) DiskUsage() (types.DiskUsage, error) {
+	return c.cli.DiskUsage(context.Background(), types.DiskUsageOptions{})
+}
+
+// RemoveUnused removes a single resource without forcing, so Docker still
+// refuses to remove anything that came into use since it was selected.
+func (c *Client) RemoveUnused(kind, id string) error {
+	ctx := context.Background()
+
+	switch kind {
+	case KindContainer:
+		return c.cli.ContainerRemove(ctx, id, container.RemoveOptions{})
+	case KindImage:
+		_, err := c.cli.ImageRemove(ctx, id, types.ImageRemoveOptions{PruneChildren: true})
+		return err
+	case KindVolume:
+		return c.cli.VolumeRemove(ctx, id, false)
+	case KindNetwork:
+		return c.cli.NetworkRemove(ctx, id)
+	case KindBuildCache:
+		// The builder only offers prune; an id filter narrows it to one record.
+		_, err := c.cli.BuildCachePrune(ctx, types.BuildCachePruneOptions{
+			All:     true,
+			Filters: filters.NewArgs(filters.Arg("id", id)),
+		})
+		return err
+	default:
+		return fmt.Errorf("unknown resource kind %q", kind)
+	}
+}
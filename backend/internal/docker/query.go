@@ -0,0 +1,51 @@
+package docker
+
+import (
+	"regexp"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// ContainerStates are the values of ContainerInfo.State.
+var ContainerStates = []string{"created", "restarting", "running", "removing", "paused", "exited", "dead"}
+
+// ContainerQuery narrows a container listing on the Docker side. Empty
+// fields do not filter.
+type ContainerQuery struct {
+	States []string
+	// Labels are Docker label filters ("key" or "key=value").
+	Labels []string
+	// Name matches containers whose name contains it.
+	Name string
+	// Network is a network name or ID the container must be attached to.
+	Network string
+	// Health are ContainerHealthStates the container must be in.
+	Health []string
+}
+
+func (q ContainerQuery) filters() filters.Args {
+	args := filters.NewArgs()
+	for _, state := range q.States {
+		args.Add("status", state)
+	}
+	for _, label := range q.Labels {
+		args.Add("label", label)
+	}
+	if q.Name != "" {
+		// Docker matches the name filter as a regular expression.
+		args.Add("name", regexp.QuoteMeta(q.Name))
+	}
+	if q.Network != "" {
+		args.Add("network", q.Network)
+	}
+	for _, health := range q.Health {
+		args.Add("health", health)
+	}
+	return args
+}
+
+// QueryContainers lists the containers, stopped ones included, that match q.
+func (c *Client) QueryContainers(q ContainerQuery) ([]ContainerInfo, error) {
+	return c.listContainers(container.ListOptions{All: true, Filters: q.filters()})
+}
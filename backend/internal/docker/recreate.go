@@ -0,0 +1,358 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+)
+
+// RecreateOptions controls Recreate. An empty Image keeps the current one.
+type RecreateOptions struct {
+	Image       string
+	StopTimeout int
+	// HealthTimeout is how long the replacement has to become healthy, or
+	// without a healthcheck, how long it has to keep running.
+	HealthTimeout time.Duration
+	// KeepOld keeps the replaced container, stopped and renamed, instead of
+	// removing it.
+	KeepOld bool
+}
+
+type RecreateResult struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Image      string `json:"image"`
+	PreviousID string `json:"previous_id"`
+	// KeptAs is the name of the replaced container when it was kept.
+	KeptAs string `json:"kept_as,omitempty"`
+}
+
+// RecreateError reports the step a recreate failed in and whether the
+// original container was restored.
+type RecreateError struct {
+	Stage       string
+	Err         error
+	RolledBack  bool
+	RollbackErr error
+}
+
+func (e *RecreateError) Error() string {
+	msg := fmt.Sprintf("recreate failed to %s: %v", e.Stage, e.Err)
+	if e.RollbackErr != nil {
+		msg += fmt.Sprintf("; rollback failed: %v", e.RollbackErr)
+	} else if e.RolledBack {
+		msg += "; the original container was restored"
+	}
+	return msg
+}
+
+func (e *RecreateError) Unwrap() error {
+	return e.Err
+}
+
+// ContainerSpec is everything needed to create a container.
+type ContainerSpec struct {
+	Config     *container.Config
+	HostConfig *container.HostConfig
+	// Networks are the endpoints to attach, keyed by network name. The one
+	// named by HostConfig.NetworkMode is attached on create, the others
+	// before start.
+	Networks map[string]*network.EndpointSettings
+}
+
+// cloneSpec derives the spec of a container that runs image with the same
+// settings as cont. Settings cont inherited from its image (oldImage) are
+// dropped so that the new image's defaults apply, and anonymous volumes are
+// mounted by name so their data carries over.
+func cloneSpec(cont types.ContainerJSON, oldImage *container.Config, image string) ContainerSpec {
+	config := *cont.Config
+	config.Image = image
+	if strings.HasPrefix(cont.ID, config.Hostname) {
+		// The default hostname is the short container ID.
+		config.Hostname = ""
+	}
+	if oldImage != nil {
+		config.Env = withoutInherited(config.Env, oldImage.Env)
+		if equalStrings(config.Cmd, oldImage.Cmd) {
+			config.Cmd = nil
+		}
+		if equalStrings(config.Entrypoint, oldImage.Entrypoint) {
+			config.Entrypoint = nil
+		}
+		if config.WorkingDir == oldImage.WorkingDir {
+			config.WorkingDir = ""
+		}
+		if config.User == oldImage.User {
+			config.User = ""
+		}
+		if config.StopSignal == oldImage.StopSignal {
+			config.StopSignal = ""
+		}
+		if config.Healthcheck != nil && oldImage.Healthcheck != nil && equalStrings(config.Healthcheck.Test, oldImage.Healthcheck.Test) {
+			config.Healthcheck = nil
+		}
+		if len(config.Labels) > 0 {
+			labels := make(map[string]string, len(config.Labels))
+			for key, value := range config.Labels {
+				if inherited, ok := oldImage.Labels[key]; !ok || inherited != value {
+					labels[key] = value
+				}
+			}
+			config.Labels = labels
+		}
+		if len(config.ExposedPorts) > 0 {
+			ports := make(nat.PortSet, len(config.ExposedPorts))
+			for port := range config.ExposedPorts {
+				if _, inherited := oldImage.ExposedPorts[port]; !inherited {
+					ports[port] = struct{}{}
+				}
+			}
+			config.ExposedPorts = ports
+		}
+		config.Volumes = nil
+	}
+
+	hostConfig := *cont.HostConfig
+	declared := make(map[string]bool)
+	for _, bind := range hostConfig.Binds {
+		parts := strings.Split(bind, ":")
+		if len(parts) >= 2 {
+			declared[parts[1]] = true
+		}
+	}
+	for _, m := range hostConfig.Mounts {
+		declared[m.Target] = true
+	}
+	hostConfig.Mounts = append([]mount.Mount(nil), hostConfig.Mounts...)
+	for _, m := range cont.Mounts {
+		if m.Type == mount.TypeVolume && m.Name != "" && !declared[m.Destination] {
+			hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{
+				Type:     mount.TypeVolume,
+				Source:   m.Name,
+				Target:   m.Destination,
+				ReadOnly: !m.RW,
+			})
+		}
+	}
+
+	networks := make(map[string]*network.EndpointSettings)
+	if cont.NetworkSettings != nil {
+		for name, endpoint := range cont.NetworkSettings.Networks {
+			if endpoint == nil {
+				continue
+			}
+			settings := &network.EndpointSettings{
+				IPAMConfig: endpoint.IPAMConfig,
+				Links:      endpoint.Links,
+				DriverOpts: endpoint.DriverOpts,
+			}
+			for _, alias := range endpoint.Aliases {
+				// Docker adds the short container ID itself.
+				if !strings.HasPrefix(cont.ID, alias) {
+					settings.Aliases = append(settings.Aliases, alias)
+				}
+			}
+			networks[name] = settings
+		}
+	}
+
+	return ContainerSpec{Config: &config, HostConfig: &hostConfig, Networks: networks}
+}
+
+// withoutInherited removes the entries of env that are also in inherited.
+func withoutInherited(env, inherited []string) []string {
+	skip := make(map[string]bool, len(inherited))
+	for _, entry := range inherited {
+		skip[entry] = true
+	}
+	var out []string
+	for _, entry := range env {
+		if !skip[entry] {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// InspectContainer returns Docker's inspect data of a container.
+func (c *Client) InspectContainer(id string) (types.ContainerJSON, error) {
+	return c.cli.ContainerInspect(context.Background(), id)
+}
+
+// CreateFromSpec creates a container from spec and attaches its networks.
+func (c *Client) CreateFromSpec(spec ContainerSpec, name string) (string, error) {
+	ctx := context.Background()
+	mode := spec.HostConfig.NetworkMode
+	primary := string(mode)
+	if mode.IsDefault() || primary == "" {
+		primary = "bridge"
+	}
+
+	var networking *network.NetworkingConfig
+	if endpoint, ok := spec.Networks[primary]; ok {
+		networking = &network.NetworkingConfig{EndpointsConfig: map[string]*network.EndpointSettings{primary: endpoint}}
+	}
+
+	resp, err := c.cli.ContainerCreate(ctx, spec.Config, spec.HostConfig, networking, nil, name)
+	if err != nil {
+		return "", err
+	}
+	if mode.IsHost() || mode.IsNone() || mode.IsContainer() {
+		// These modes share or disable networking; there is nothing to attach.
+		return resp.ID, nil
+	}
+
+	for netName, endpoint := range spec.Networks {
+		if netName == primary {
+			continue
+		}
+		if err := c.cli.NetworkConnect(ctx, netName, resp.ID, endpoint); err != nil {
+			c.cli.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+			return "", fmt.Errorf("connect to network %s: %w", netName, err)
+		}
+	}
+	return resp.ID, nil
+}
+
+// WaitHealthy waits until the container reports healthy or, if it has no
+// healthcheck, until it has kept running for timeout. It fails as soon as
+// the container is unhealthy or stops.
+func (c *Client) WaitHealthy(id string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		inspect, err := c.InspectContainer(id)
+		if err != nil {
+			return err
+		}
+		state := inspect.State
+		if state == nil || !state.Running || state.Restarting {
+			status := "exited"
+			if state != nil {
+				status = fmt.Sprintf("%s with code %d", state.Status, state.ExitCode)
+			}
+			return fmt.Errorf("container is %s", status)
+		}
+
+		if state.Health != nil {
+			switch state.Health.Status {
+			case types.Healthy:
+				return nil
+			case types.Unhealthy:
+				return fmt.Errorf("container is unhealthy")
+			}
+		}
+
+		if time.Now().After(deadline) {
+			if state.Health == nil {
+				return nil
+			}
+			return fmt.Errorf("container did not become healthy within %s", timeout)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// Recreate replaces a container with a copy of it running opts.Image. The
+// copy is created under a temporary name, then the original is stopped and
+// the names are swapped. The copy is only started if the original was
+// running. If it fails to start or become healthy, it is removed and the
+// original is renamed back and started again.
+func (c *Client) Recreate(id string, opts RecreateOptions) (RecreateResult, error) {
+	ctx := context.Background()
+
+	old, err := c.InspectContainer(id)
+	if err != nil {
+		return RecreateResult{}, err
+	}
+	name := strings.TrimPrefix(old.Name, "/")
+	image := opts.Image
+	if image == "" {
+		image = old.Config.Image
+	}
+
+	var oldImage *container.Config
+	if inspect, _, err := c.cli.ImageInspectWithRaw(ctx, old.Image); err == nil {
+		oldImage = inspect.Config
+	}
+	spec := cloneSpec(old, oldImage, image)
+
+	short := old.ID[:12]
+	tempName := name + "-recreate-" + short
+	keptName := name + "-replaced-" + short
+
+	newID, err := c.CreateFromSpec(spec, tempName)
+	if err != nil {
+		return RecreateResult{}, &RecreateError{Stage: "create the replacement", Err: err, RolledBack: true}
+	}
+	removeNew := func() error {
+		return c.cli.ContainerRemove(ctx, newID, container.RemoveOptions{Force: true})
+	}
+
+	wasRunning := old.State != nil && old.State.Running
+	if wasRunning {
+		if err := c.StopContainer(old.ID, opts.StopTimeout); err != nil {
+			rerr := removeNew()
+			return RecreateResult{}, &RecreateError{Stage: "stop the original", Err: err, RolledBack: rerr == nil, RollbackErr: rerr}
+		}
+	}
+
+	rollback := func(stage string, cause error, renamed bool) error {
+		rerr := removeNew()
+		if rerr == nil && renamed {
+			rerr = c.RenameContainer(old.ID, name)
+		}
+		if rerr == nil && wasRunning {
+			rerr = c.StartContainer(old.ID)
+		}
+		return &RecreateError{Stage: stage, Err: cause, RolledBack: rerr == nil, RollbackErr: rerr}
+	}
+
+	if err := c.RenameContainer(old.ID, keptName); err != nil {
+		return RecreateResult{}, rollback("rename the original", err, false)
+	}
+	if err := c.RenameContainer(newID, name); err != nil {
+		return RecreateResult{}, rollback("rename the replacement", err, true)
+	}
+	if wasRunning {
+		if err := c.StartContainer(newID); err != nil {
+			return RecreateResult{}, rollback("start the replacement", err, true)
+		}
+		if err := c.WaitHealthy(newID, opts.HealthTimeout); err != nil {
+			return RecreateResult{}, rollback("bring the replacement up", err, true)
+		}
+	}
+
+	result := RecreateResult{ID: newID, Name: name, Image: image, PreviousID: old.ID}
+	if opts.KeepOld {
+		result.KeptAs = keptName
+	} else if err := c.RemoveContainer(old.ID, false, false); err != nil {
+		// The replacement is up; a leftover stopped container is not fatal.
+		result.KeptAs = keptName
+	}
+	return result, nil
+}
+
+// SpecFromInspect returns the spec of a container with exactly the settings
+// of cont, for creating it again elsewhere.
+func SpecFromInspect(cont types.ContainerJSON) ContainerSpec {
+	return cloneSpec(cont, nil, cont.Config.Image)
+}
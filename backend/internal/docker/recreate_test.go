@@ -0,0 +1,70 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloneSpec(t *testing.T) {
+	oldImage := &container.Config{
+		Env:          []string{"PATH=/usr/bin", "APP_VERSION=1.0"},
+		Cmd:          []string{"serve"},
+		WorkingDir:   "/app",
+		ExposedPorts: nat.PortSet{"8080/tcp": {}},
+		Labels:       map[string]string{"org.opencontainers.image.version": "1.0"},
+	}
+	cont := types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID: "4f2a9c1b7e3d5f6a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a",
+			HostConfig: &container.HostConfig{
+				NetworkMode:   "front",
+				Binds:         []string{"/srv/config:/etc/app:ro"},
+				RestartPolicy: container.RestartPolicy{Name: container.RestartPolicyAlways},
+			},
+		},
+		Mounts: []types.MountPoint{
+			{Type: mount.TypeBind, Source: "/srv/config", Destination: "/etc/app"},
+			{Type: mount.TypeVolume, Name: "3c5e...anon", Destination: "/data", RW: true},
+		},
+		Config: &container.Config{
+			Hostname:     "4f2a9c1b7e3d",
+			Image:        "api:1.0",
+			Env:          []string{"PATH=/usr/bin", "APP_VERSION=1.0", "LOG_LEVEL=debug"},
+			Cmd:          []string{"serve"},
+			WorkingDir:   "/app",
+			ExposedPorts: nat.PortSet{"8080/tcp": {}, "9090/tcp": {}},
+			Labels:       map[string]string{"org.opencontainers.image.version": "1.0", "tier": "api"},
+		},
+		NetworkSettings: &types.NetworkSettings{Networks: map[string]*network.EndpointSettings{
+			"front": {Aliases: []string{"api", "4f2a9c1b7e3d"}},
+			"back":  {IPAMConfig: &network.EndpointIPAMConfig{IPv4Address: "10.1.0.5"}},
+		}},
+	}
+
+	spec := cloneSpec(cont, oldImage, "api:1.1")
+
+	assert.Equal(t, "api:1.1", spec.Config.Image)
+	assert.Empty(t, spec.Config.Hostname, "the generated hostname is not copied")
+	assert.Equal(t, []string{"LOG_LEVEL=debug"}, spec.Config.Env, "image defaults come from the new image")
+	assert.Nil(t, spec.Config.Cmd)
+	assert.Empty(t, spec.Config.WorkingDir)
+	assert.Equal(t, nat.PortSet{"9090/tcp": {}}, spec.Config.ExposedPorts)
+	assert.Equal(t, map[string]string{"tier": "api"}, spec.Config.Labels)
+	assert.Equal(t, "api:1.0", cont.Config.Image, "the inspected config is not modified")
+
+	assert.Equal(t, container.RestartPolicyAlways, spec.HostConfig.RestartPolicy.Name)
+	assert.Equal(t, []mount.Mount{{Type: mount.TypeVolume, Source: "3c5e...anon", Target: "/data"}}, spec.HostConfig.Mounts,
+		"anonymous volumes are reattached by name")
+
+	assert.Equal(t, []string{"api"}, spec.Networks["front"].Aliases)
+	assert.Equal(t, "10.1.0.5", spec.Networks["back"].IPAMConfig.IPv4Address)
+
+	spec = cloneSpec(cont, nil, "api:1.0")
+	assert.Equal(t, cont.Config.Env, spec.Config.Env, "without the old image everything is kept")
+}
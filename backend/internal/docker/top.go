@@ -0,0 +1,147 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// Process is a row of a container's process list. The well-known ps columns
+// are parsed into typed fields; Fields holds every column as printed.
+type Process struct {
+	PID     int      `json:"pid"`
+	PPID    int      `json:"ppid,omitempty"`
+	User    string   `json:"user,omitempty"`
+	CPU     *float64 `json:"cpu_percent,omitempty"`
+	Memory  *float64 `json:"memory_percent,omitempty"`
+	RSS     int64    `json:"rss_bytes,omitempty"`
+	State   string   `json:"state,omitempty"`
+	Started string   `json:"started,omitempty"`
+	Time    string   `json:"time,omitempty"`
+	Command string   `json:"command"`
+	// Fields maps the ps column titles to the row's values.
+	Fields map[string]string `json:"fields"`
+}
+
+// ProcessList is the output of ps for a container, ordered by PID.
+type ProcessList struct {
+	Titles    []string  `json:"titles"`
+	Processes []Process `json:"processes"`
+}
+
+// toProcessList parses the rows of a top response by their column titles,
+// which depend on the ps arguments.
+func toProcessList(top container.ContainerTopOKBody) ProcessList {
+	list := ProcessList{Titles: top.Titles, Processes: make([]Process, 0, len(top.Processes))}
+	for _, row := range top.Processes {
+		p := Process{Fields: make(map[string]string, len(top.Titles))}
+		for i, title := range top.Titles {
+			if i >= len(row) {
+				break
+			}
+			value := row[i]
+			p.Fields[title] = value
+
+			switch strings.ToUpper(title) {
+			case "PID":
+				p.PID, _ = strconv.Atoi(value)
+			case "PPID":
+				p.PPID, _ = strconv.Atoi(value)
+			case "USER", "UID", "EUSER", "RUSER":
+				p.User = value
+			case "%CPU":
+				if f, err := strconv.ParseFloat(value, 64); err == nil {
+					p.CPU = &f
+				}
+			case "%MEM":
+				if f, err := strconv.ParseFloat(value, 64); err == nil {
+					p.Memory = &f
+				}
+			case "RSS", "RSZ":
+				// ps reports resident memory in KiB.
+				if kib, err := strconv.ParseInt(value, 10, 64); err == nil {
+					p.RSS = kib * 1024
+				}
+			case "STAT", "S":
+				p.State = value
+			case "STIME", "START", "STARTED":
+				p.Started = value
+			case "TIME":
+				p.Time = value
+			case "CMD", "COMMAND", "ARGS":
+				p.Command = value
+			}
+		}
+		list.Processes = append(list.Processes, p)
+	}
+	sort.SliceStable(list.Processes, func(i, j int) bool { return list.Processes[i].PID < list.Processes[j].PID })
+	return list
+}
+
+// DefaultPSArgs are the ps arguments ContainerTop uses when none are given.
+const DefaultPSArgs = "-ef"
+
+// ErrNoSuchProcess is returned by SignalProcess for a PID that is not a
+// process of the container.
+var ErrNoSuchProcess = errors.New("no such process in container")
+
+// ContainerTop lists the processes of a running container with Docker's
+// top, which runs ps with psArgs, DefaultPSArgs if empty, on the Docker
+// host. PIDs are therefore host PIDs, the ones SignalProcess takes, and the
+// container needs no ps of its own.
+func (c *Client) ContainerTop(id, psArgs string) (ProcessList, error) {
+	if strings.TrimSpace(psArgs) == "" {
+		psArgs = DefaultPSArgs
+	}
+	top, err := c.cli.ContainerTop(context.Background(), id, strings.Fields(psArgs))
+	if err != nil {
+		return ProcessList{}, err
+	}
+	return toProcessList(top), nil
+}
+
+// SignalProcess sends signal, a name such as "TERM" or "SIGHUP" or a
+// number, to the process pid, a host PID as listed by ContainerTop. A PID
+// that is not a process of the container is rejected with ErrNoSuchProcess.
+// kill runs in a helper container from helperImage that shares the host's
+// PID namespace, so the container itself needs no kill command.
+func (c *Client) SignalProcess(id string, pid int, signal, helperImage string) error {
+	list, err := c.ContainerTop(id, "")
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, p := range list.Processes {
+		found = found || p.PID == pid
+	}
+	if !found {
+		return fmt.Errorf("%w: %d", ErrNoSuchProcess, pid)
+	}
+
+	name := strings.TrimPrefix(strings.ToUpper(signal), "SIG")
+	cmd := []string{"kill", "-s", name, strconv.Itoa(pid)}
+	if _, err := strconv.Atoi(name); err == nil {
+		cmd = []string{"kill", "-" + name, strconv.Itoa(pid)}
+	}
+
+	result, err := c.runHelper(
+		&container.Config{Image: helperImage, User: "0", Cmd: cmd, Labels: map[string]string{HelperLabel: "process-signal"}},
+		&container.HostConfig{PidMode: "host"},
+	)
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		msg := strings.TrimSpace(result.Stdout + result.Stderr)
+		if msg == "" {
+			msg = fmt.Sprintf("kill exited with code %d", result.ExitCode)
+		}
+		return fmt.Errorf("signal process %d: %s", pid, msg)
+	}
+	return nil
+}
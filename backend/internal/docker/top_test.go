@@ -0,0 +1,43 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToProcessList(t *testing.T) {
+	list := toProcessList(container.ContainerTopOKBody{
+		Titles: []string{"USER", "PID", "%CPU", "%MEM", "VSZ", "RSS", "TTY", "STAT", "START", "TIME", "COMMAND"},
+		Processes: [][]string{
+			{"nginx", "2210", "0.3", "1.2", "10092", "2048", "?", "S", "09:12", "0:00", "nginx: worker process"},
+			{"root", "2187", "0.0", "0.4", "9792", "6144", "?", "Ss", "09:12", "0:00", "nginx: master process nginx -g daemon off;"},
+		},
+	})
+
+	require.Len(t, list.Processes, 2)
+	master := list.Processes[0]
+	assert.Equal(t, 2187, master.PID, "processes are ordered by PID")
+	assert.Equal(t, "root", master.User)
+	assert.Equal(t, 0.4, *master.Memory)
+	assert.Equal(t, int64(6144*1024), master.RSS)
+	assert.Equal(t, "Ss", master.State)
+	assert.Equal(t, "nginx: master process nginx -g daemon off;", master.Command)
+	assert.Equal(t, "9792", master.Fields["VSZ"])
+	assert.Equal(t, 0.3, *list.Processes[1].CPU)
+
+	// The default "-ef" columns have no CPU or memory percentages.
+	list = toProcessList(container.ContainerTopOKBody{
+		Titles:    []string{"UID", "PID", "PPID", "C", "STIME", "TTY", "TIME", "CMD"},
+		Processes: [][]string{{"999", "3401", "3380", "0", "09:12", "?", "00:00:01", "redis-server *:6379"}},
+	})
+	p := list.Processes[0]
+	assert.Equal(t, 3380, p.PPID)
+	assert.Equal(t, "999", p.User)
+	assert.Nil(t, p.CPU)
+	assert.Equal(t, "09:12", p.Started)
+	assert.Equal(t, "00:00:01", p.Time)
+	assert.Equal(t, "redis-server *:6379", p.Command)
+}
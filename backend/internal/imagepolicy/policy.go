@@ -0,0 +1,219 @@
+// Package imagepolicy decides which images may be pulled and run, based on
+// an administrator defined set of rules.
+package imagepolicy
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/distribution/reference"
+)
+
+// Rule names, as reported in violations.
+const (
+	RuleAllowedRegistries = "allowed_registries"
+	RuleRequireDigest     = "require_digest"
+	RuleDeniedTags        = "denied_tags"
+	RuleRequiredLabels    = "required_labels"
+	RuleRequireSignature  = "require_signature"
+)
+
+// Policy is the image policy. The zero value allows every image.
+type Policy struct {
+	// AllowedRegistries lists registry hosts ("ghcr.io", "*.corp.example")
+	// or repository prefixes ("docker.io/library"). Empty allows all.
+	AllowedRegistries []string `json:"allowed_registries"`
+	// RequireDigest only allows references pinned with @sha256:...
+	RequireDigest bool `json:"require_digest"`
+	// DeniedTags are tag patterns ("latest", "*-dev"). A reference without
+	// tag or digest counts as "latest".
+	DeniedTags []string `json:"denied_tags"`
+	// RequiredLabels must be present on the image; an empty value only
+	// requires the key.
+	RequiredLabels map[string]string `json:"required_labels"`
+	// RequireSignature requires a cosign signature for the image digest in
+	// its registry.
+	RequireSignature bool `json:"require_signature"`
+}
+
+// ErrInvalidReference is returned for references the policy cannot parse.
+var ErrInvalidReference = errors.New("invalid image reference")
+
+// Violation is a policy rule an image does not satisfy.
+type Violation struct {
+	Rule   string `json:"rule"`
+	Image  string `json:"image"`
+	Detail string `json:"detail"`
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("image policy rule %s violated by %s: %s", v.Rule, v.Image, v.Detail)
+}
+
+// Validate checks the rule syntax and fills in empty collections.
+func (p *Policy) Validate() error {
+	for _, entry := range p.AllowedRegistries {
+		if strings.TrimSpace(entry) == "" || strings.Contains(entry, "://") {
+			return fmt.Errorf("invalid allowed registry %q", entry)
+		}
+	}
+	for _, pattern := range p.DeniedTags {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("invalid denied tag pattern %q", pattern)
+		}
+	}
+	for key := range p.RequiredLabels {
+		if key == "" {
+			return fmt.Errorf("required label keys must not be empty")
+		}
+	}
+
+	if p.AllowedRegistries == nil {
+		p.AllowedRegistries = []string{}
+	}
+	if p.DeniedTags == nil {
+		p.DeniedTags = []string{}
+	}
+	if p.RequiredLabels == nil {
+		p.RequiredLabels = map[string]string{}
+	}
+	return nil
+}
+
+// CheckReference evaluates the rules that depend only on the reference:
+// allowed registries, digest pinning and denied tags.
+func (p Policy) CheckReference(ref string) error {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return fmt.Errorf("%w %q: %v", ErrInvalidReference, ref, err)
+	}
+
+	if len(p.AllowedRegistries) > 0 && !p.registryAllowed(named) {
+		return &Violation{
+			Rule:   RuleAllowedRegistries,
+			Image:  ref,
+			Detail: fmt.Sprintf("%s is not in the allowed registries %s", reference.Domain(named), strings.Join(p.AllowedRegistries, ", ")),
+		}
+	}
+
+	_, digested := named.(reference.Digested)
+	if p.RequireDigest && !digested {
+		return &Violation{Rule: RuleRequireDigest, Image: ref, Detail: "the reference must be pinned with @sha256:<digest>"}
+	}
+
+	tag := ""
+	if tagged, ok := named.(reference.Tagged); ok {
+		tag = tagged.Tag()
+	} else if !digested {
+		tag = "latest"
+	}
+	if tag != "" {
+		for _, pattern := range p.DeniedTags {
+			if ok, _ := path.Match(pattern, tag); ok {
+				return &Violation{Rule: RuleDeniedTags, Image: ref, Detail: fmt.Sprintf("tag %q matches the denied pattern %q", tag, pattern)}
+			}
+		}
+	}
+	return nil
+}
+
+func (p Policy) registryAllowed(named reference.Named) bool {
+	domain := reference.Domain(named)
+	name := named.Name()
+	for _, entry := range p.AllowedRegistries {
+		entry = strings.TrimSuffix(strings.ToLower(entry), "/")
+		switch {
+		case strings.Contains(entry, "/"):
+			if name == entry || strings.HasPrefix(name, entry+"/") {
+				return true
+			}
+		case strings.HasPrefix(entry, "*."):
+			if strings.HasSuffix(domain, entry[1:]) {
+				return true
+			}
+		case entry == domain:
+			return true
+		}
+	}
+	return false
+}
+
+// CheckOrigin evaluates the allowed registries against where a local image
+// came from. Any local tag can be set on an image, so the name proves
+// nothing; Docker records a repository digest when an image is pulled or
+// pushed, and one of those must be in an allowed registry. Images that were
+// built, committed or loaded and never pushed have none.
+func (p Policy) CheckOrigin(ref string, repoDigests []string) error {
+	if len(p.AllowedRegistries) == 0 {
+		return nil
+	}
+	for _, repoDigest := range repoDigests {
+		named, err := reference.ParseNormalizedNamed(repoDigest)
+		if err == nil && p.registryAllowed(named) {
+			return nil
+		}
+	}
+	return &Violation{
+		Rule:   RuleAllowedRegistries,
+		Image:  ref,
+		Detail: fmt.Sprintf("the local image was not pulled from or pushed to the allowed registries %s", strings.Join(p.AllowedRegistries, ", ")),
+	}
+}
+
+// CheckLabels evaluates the required labels against an image's labels.
+func (p Policy) CheckLabels(ref string, labels map[string]string) error {
+	keys := make([]string, 0, len(p.RequiredLabels))
+	for key := range p.RequiredLabels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		want := p.RequiredLabels[key]
+		value, ok := labels[key]
+		switch {
+		case !ok:
+			return &Violation{Rule: RuleRequiredLabels, Image: ref, Detail: fmt.Sprintf("label %q is missing", key)}
+		case want != "" && value != want:
+			return &Violation{Rule: RuleRequiredLabels, Image: ref, Detail: fmt.Sprintf("label %q is %q, expected %q", key, value, want)}
+		}
+	}
+	return nil
+}
+
+// SignatureRef returns the reference under which cosign stores the
+// signature of digest in the repository of ref.
+func SignatureRef(ref, digest string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", fmt.Errorf("%w %q: %v", ErrInvalidReference, ref, err)
+	}
+	return named.Name() + ":" + strings.Replace(digest, ":", "-", 1) + ".sig", nil
+}
+
+// Digest returns the manifest digest ref is pinned to, or the digest from
+// repoDigests (as reported by image inspect) for the repository of ref.
+// It returns "" when neither is known.
+func Digest(ref string, repoDigests []string) string {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return ""
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		return digested.Digest().String()
+	}
+
+	for _, repoDigest := range repoDigests {
+		candidate, err := reference.ParseNormalizedNamed(repoDigest)
+		if err != nil || candidate.Name() != named.Name() {
+			continue
+		}
+		if digested, ok := candidate.(reference.Digested); ok {
+			return digested.Digest().String()
+		}
+	}
+	return ""
+}
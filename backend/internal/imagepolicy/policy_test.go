@@ -0,0 +1,86 @@
+package imagepolicy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func rule(err error) string {
+	var violation *Violation
+	if errors.As(err, &violation) {
+		return violation.Rule
+	}
+	return ""
+}
+
+func TestCheckReference(t *testing.T) {
+	policy := Policy{
+		AllowedRegistries: []string{"ghcr.io", "*.corp.example", "docker.io/library"},
+		DeniedTags:        []string{"latest", "*-dev"},
+	}
+	require.NoError(t, policy.Validate())
+
+	for _, ref := range []string{"nginx:1.25", "ghcr.io/acme/api:1.3", "registry.corp.example/app:2", "nginx@" + digest} {
+		assert.NoError(t, policy.CheckReference(ref), ref)
+	}
+
+	assert.Equal(t, RuleAllowedRegistries, rule(policy.CheckReference("quay.io/acme/api:1")))
+	assert.Equal(t, RuleAllowedRegistries, rule(policy.CheckReference("acme/api:1")), "docker.io/acme is outside docker.io/library")
+	assert.Equal(t, RuleDeniedTags, rule(policy.CheckReference("nginx")), "no tag means latest")
+	assert.Equal(t, RuleDeniedTags, rule(policy.CheckReference("ghcr.io/acme/api:feature-dev")))
+
+	policy.RequireDigest = true
+	assert.Equal(t, RuleRequireDigest, rule(policy.CheckReference("nginx:1.25")))
+	assert.NoError(t, policy.CheckReference("nginx:1.25@"+digest))
+
+	err := policy.CheckReference("Not A Reference")
+	assert.ErrorIs(t, err, ErrInvalidReference)
+
+	assert.NoError(t, Policy{}.CheckReference("anything/at:all"), "the zero policy allows everything")
+}
+
+func TestCheckOrigin(t *testing.T) {
+	policy := Policy{AllowedRegistries: []string{"ghcr.io", "docker.io/library"}}
+
+	assert.NoError(t, policy.CheckOrigin("ghcr.io/acme/api:1", []string{"ghcr.io/acme/api@" + digest}))
+	assert.NoError(t, policy.CheckOrigin("nginx:1.25", []string{"nginx@" + digest}))
+	assert.NoError(t, policy.CheckOrigin("ghcr.io/acme/api:1", []string{"quay.io/acme/api@" + digest, "ghcr.io/acme/mirror@" + digest}))
+
+	assert.Equal(t, RuleAllowedRegistries, rule(policy.CheckOrigin("ghcr.io/acme/api:1", nil)), "built, committed or loaded locally")
+	assert.Equal(t, RuleAllowedRegistries, rule(policy.CheckOrigin("ghcr.io/acme/api:1", []string{"quay.io/acme/api@" + digest})), "retagged from elsewhere")
+	assert.NoError(t, Policy{}.CheckOrigin("local:1", nil))
+}
+
+func TestCheckLabels(t *testing.T) {
+	policy := Policy{RequiredLabels: map[string]string{"org.opencontainers.image.source": "", "tier": "prod"}}
+
+	assert.NoError(t, policy.CheckLabels("api", map[string]string{"org.opencontainers.image.source": "x", "tier": "prod"}))
+
+	err := policy.CheckLabels("api", map[string]string{"tier": "prod"})
+	assert.Equal(t, RuleRequiredLabels, rule(err))
+	assert.Contains(t, err.Error(), "org.opencontainers.image.source")
+
+	err = policy.CheckLabels("api", map[string]string{"org.opencontainers.image.source": "x", "tier": "dev"})
+	assert.Contains(t, err.Error(), `label "tier" is "dev", expected "prod"`)
+}
+
+func TestValidate(t *testing.T) {
+	assert.Error(t, (&Policy{AllowedRegistries: []string{"https://ghcr.io"}}).Validate())
+	assert.Error(t, (&Policy{DeniedTags: []string{"[bad"}}).Validate())
+	assert.Error(t, (&Policy{RequiredLabels: map[string]string{"": "x"}}).Validate())
+}
+
+func TestSignatureAndDigest(t *testing.T) {
+	ref, err := SignatureRef("ghcr.io/acme/api:1.3", digest)
+	require.NoError(t, err)
+	assert.Equal(t, "ghcr.io/acme/api:sha256-0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef.sig", ref)
+
+	assert.Equal(t, digest, Digest("nginx@"+digest, nil))
+	assert.Equal(t, digest, Digest("nginx:1.25", []string{"ghcr.io/other@sha256:" + digest[7:], "nginx@" + digest}))
+	assert.Equal(t, "", Digest("api:dev", nil))
+}
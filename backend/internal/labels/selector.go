@@ -0,0 +1,83 @@
+// Package labels implements label selectors in Docker's filter syntax.
+package labels
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Requirement is one label filter: "key", "key=value", or the negations
+// "!key" and "!key=value".
+type Requirement struct {
+	Key      string
+	Value    string
+	HasValue bool
+	Negate   bool
+}
+
+// ParseRequirement parses a single label filter.
+func ParseRequirement(spec string) (Requirement, error) {
+	var r Requirement
+	raw := spec
+	if strings.HasPrefix(spec, "!") {
+		r.Negate = true
+		spec = spec[1:]
+	}
+
+	r.Key, r.Value, r.HasValue = strings.Cut(spec, "=")
+	if r.Key == "" {
+		return Requirement{}, fmt.Errorf("invalid label filter %q", raw)
+	}
+	return r, nil
+}
+
+// Matches reports whether labels satisfy the requirement.
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	match := ok && (!r.HasValue || value == r.Value)
+	return match != r.Negate
+}
+
+// Selector is a set of requirements that must all hold. The empty selector
+// matches everything.
+type Selector []Requirement
+
+// Parse parses a list of label filters into a selector.
+func Parse(specs []string) (Selector, error) {
+	selector := make(Selector, 0, len(specs))
+	for _, spec := range specs {
+		r, err := ParseRequirement(spec)
+		if err != nil {
+			return nil, err
+		}
+		selector = append(selector, r)
+	}
+	return selector, nil
+}
+
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// DockerFilters returns the requirements the Docker API can evaluate itself
+// as values for its "label" filter. Negated requirements are not supported
+// by Docker's list filters and have to be applied with Matches.
+func (s Selector) DockerFilters() []string {
+	var filters []string
+	for _, r := range s {
+		if r.Negate {
+			continue
+		}
+		if r.HasValue {
+			filters = append(filters, r.Key+"="+r.Value)
+		} else {
+			filters = append(filters, r.Key)
+		}
+	}
+	return filters
+}
@@ -0,0 +1,36 @@
+package labels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelector(t *testing.T) {
+	selector, err := Parse([]string{"tier=web", "!pinned", "owner", "!env=prod"})
+	assert.NoError(t, err)
+
+	assert.True(t, selector.Matches(map[string]string{"tier": "web", "owner": "ops", "env": "dev"}))
+	assert.False(t, selector.Matches(map[string]string{"tier": "web", "owner": "ops", "pinned": ""}))
+	assert.False(t, selector.Matches(map[string]string{"tier": "db", "owner": "ops"}))
+	assert.False(t, selector.Matches(map[string]string{"tier": "web", "owner": "ops", "env": "prod"}))
+	assert.True(t, Selector(nil).Matches(nil))
+
+	assert.Equal(t, []string{"tier=web", "owner"}, selector.DockerFilters())
+
+	for _, spec := range []string{"", "!", "=value", "!=value"} {
+		_, err := Parse([]string{spec})
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestParseRequirement(t *testing.T) {
+	r, err := ParseRequirement("!env=prod=eu")
+	assert.NoError(t, err)
+	assert.Equal(t, Requirement{Key: "env", Value: "prod=eu", HasValue: true, Negate: true}, r)
+
+	r, err = ParseRequirement("owner=")
+	assert.NoError(t, err)
+	assert.True(t, r.Matches(map[string]string{"owner": ""}), "an empty value must match exactly")
+	assert.False(t, r.Matches(map[string]string{"owner": "ops"}))
+}
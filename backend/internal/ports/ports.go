@@ -0,0 +1,117 @@
+// Package ports checks the host ports requested for a new container
+// against the ports already in use and allocates free ones.
+package ports
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Use is a host port taken by a container, or by a process on the host
+// when Host is set.
+type Use struct {
+	HostPort      int    `json:"host_port"`
+	Protocol      string `json:"protocol"`
+	ContainerID   string `json:"container_id,omitempty"`
+	ContainerName string `json:"container_name,omitempty"`
+	// State is the owning container's state; a stopped container still
+	// claims its ports for when it starts again.
+	State string `json:"state,omitempty"`
+	Host  bool   `json:"host,omitempty"`
+}
+
+func (u Use) owner() string {
+	if u.Host {
+		return "another process on the host"
+	}
+	return fmt.Sprintf("container %s (%s)", u.ContainerName, u.State)
+}
+
+// ConflictError lists the requested host ports that are already taken.
+type ConflictError struct {
+	Conflicts []Use
+}
+
+func (e *ConflictError) Error() string {
+	parts := make([]string, 0, len(e.Conflicts))
+	for _, u := range e.Conflicts {
+		parts = append(parts, fmt.Sprintf("host port %d/%s is used by %s", u.HostPort, u.Protocol, u.owner()))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ErrRangeExhausted is returned when no port of the allocation range is
+// free.
+var ErrRangeExhausted = errors.New("no free host port left in the allocation range")
+
+// Allocator hands out the host ports of one container. It is not safe for
+// concurrent use; callers serialize allocations until the container has
+// bound its ports.
+type Allocator struct {
+	min, max int
+	used     map[string]Use
+	hostFree func(protocol string, port int) bool
+}
+
+func key(port int, protocol string) string {
+	return strconv.Itoa(port) + "/" + protocol
+}
+
+// NewAllocator returns an allocator for ports min to max that treats used
+// as taken and asks hostFree about every other port.
+func NewAllocator(min, max int, used []Use, hostFree func(protocol string, port int) bool) *Allocator {
+	a := &Allocator{min: min, max: max, used: make(map[string]Use, len(used)), hostFree: hostFree}
+	for _, u := range used {
+		if _, ok := a.used[key(u.HostPort, u.Protocol)]; !ok {
+			a.used[key(u.HostPort, u.Protocol)] = u
+		}
+	}
+	return a
+}
+
+// Claim reserves a requested host port, or returns what already uses it.
+func (a *Allocator) Claim(port int, protocol string) *Use {
+	if u, ok := a.used[key(port, protocol)]; ok {
+		return &u
+	}
+	if !a.hostFree(protocol, port) {
+		return &Use{HostPort: port, Protocol: protocol, Host: true}
+	}
+	a.used[key(port, protocol)] = Use{HostPort: port, Protocol: protocol}
+	return nil
+}
+
+// Allocate reserves the lowest free port of the range.
+func (a *Allocator) Allocate(protocol string) (int, error) {
+	for port := a.min; port <= a.max; port++ {
+		if a.Claim(port, protocol) == nil {
+			return port, nil
+		}
+	}
+	return 0, ErrRangeExhausted
+}
+
+// HostPortFree reports whether port can be bound on all interfaces. It
+// sees the network namespace this process runs in, which is the Docker
+// host's only when running on the host or with host networking. Ports it
+// may not bind, such as privileged ones, count as free.
+func HostPortFree(protocol string, port int) bool {
+	addr := ":" + strconv.Itoa(port)
+	var err error
+	if protocol == "udp" {
+		var conn net.PacketConn
+		if conn, err = net.ListenPacket("udp", addr); err == nil {
+			conn.Close()
+		}
+	} else {
+		var l net.Listener
+		if l, err = net.Listen("tcp", addr); err == nil {
+			l.Close()
+		}
+	}
+	return !errors.Is(err, syscall.EADDRINUSE)
+}
@@ -0,0 +1,45 @@
+package ports
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllocator(t *testing.T) {
+	hostTaken := map[string]bool{"20001/tcp": true}
+	hostFree := func(protocol string, port int) bool { return !hostTaken[key(port, protocol)] }
+	a := NewAllocator(20000, 20003, []Use{
+		{HostPort: 8080, Protocol: "tcp", ContainerID: "abc", ContainerName: "web", State: "running"},
+		{HostPort: 20000, Protocol: "tcp", ContainerName: "old", State: "exited"},
+	}, hostFree)
+
+	conflict := a.Claim(8080, "tcp")
+	require.NotNil(t, conflict)
+	assert.Equal(t, "web", conflict.ContainerName)
+	assert.Nil(t, a.Claim(8080, "udp"), "protocols are separate")
+	assert.True(t, a.Claim(20001, "tcp").Host)
+
+	port, err := a.Allocate("tcp")
+	assert.NoError(t, err)
+	assert.Equal(t, 20002, port, "skips ports of stopped containers and the host")
+	port, _ = a.Allocate("tcp")
+	assert.Equal(t, 20003, port, "allocated ports are reserved")
+	_, err = a.Allocate("tcp")
+	assert.True(t, errors.Is(err, ErrRangeExhausted))
+	assert.NotNil(t, a.Claim(20003, "tcp"))
+
+	msg := (&ConflictError{Conflicts: []Use{*conflict, {HostPort: 20001, Protocol: "tcp", Host: true}}}).Error()
+	assert.Equal(t, "host port 8080/tcp is used by container web (running); host port 20001/tcp is used by another process on the host", msg)
+}
+
+func TestHostPortFree(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	assert.False(t, HostPortFree("tcp", l.Addr().(*net.TCPAddr).Port))
+}
@@ -0,0 +1,276 @@
+// Package registry stores container registry credentials and resolves which
+// registry an image reference belongs to.
+package registry
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	registrytypes "github.com/docker/docker/api/types/registry"
+)
+
+var ErrCredentialNotFound = errors.New("registry credential not found")
+
+// DockerHub is the normalized name of the default registry.
+const DockerHub = "docker.io"
+
+// dockerHubIndex is the key Docker uses for Docker Hub credentials.
+const dockerHubIndex = "https://index.docker.io/v1/"
+
+// Credential is a stored registry login. The password is never returned.
+type Credential struct {
+	ID        int64     `json:"id"`
+	Registry  string    `json:"registry"`
+	Username  string    `json:"username"`
+	CreatedBy int64     `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store keeps registry passwords encrypted with AES-GCM under a key derived
+// from the server secret, since they have to be sent to Docker in clear.
+type Store struct {
+	db   *sql.DB
+	aead cipher.AEAD
+}
+
+func NewStore(db *sql.DB, secret string) *Store {
+	key := sha256.Sum256([]byte("registry-credentials:" + secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return &Store{db: db, aead: aead}
+}
+
+// NormalizeRegistry turns user input such as "https://ghcr.io/" or
+// "index.docker.io" into the host name Docker uses for the registry.
+func NormalizeRegistry(registry string) (string, error) {
+	host := strings.TrimSpace(registry)
+	host = strings.TrimPrefix(host, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	host, _, _ = strings.Cut(host, "/")
+	host = strings.ToLower(host)
+
+	if host == "" {
+		return "", fmt.Errorf("registry is required")
+	}
+	if host == "index.docker.io" || host == "registry-1.docker.io" {
+		return DockerHub, nil
+	}
+	return host, nil
+}
+
+// Domain returns the registry an image reference points to, for example
+// "docker.io" for "nginx" and "ghcr.io" for "ghcr.io/org/app:1".
+func Domain(ref string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference %q: %w", ref, err)
+	}
+	return reference.Domain(named), nil
+}
+
+func (s *Store) seal(plaintext string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (s *Store) open(ciphertext string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(data) < s.aead.NonceSize() {
+		return "", fmt.Errorf("corrupt registry secret")
+	}
+	nonce, sealed := data[:s.aead.NonceSize()], data[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt registry secret; was JWT_SECRET changed? %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// Save stores the login for a registry, replacing an earlier one.
+func (s *Store) Save(registry, username, password string, createdBy interface{}) (*Credential, error) {
+	host, err := NormalizeRegistry(registry)
+	if err != nil {
+		return nil, err
+	}
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("username and password are required")
+	}
+
+	secret, err := s.seal(password)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.Exec(
+		`INSERT INTO registry_credentials (registry, username, secret, created_by) VALUES (?, ?, ?, ?)
+		ON CONFLICT(registry) DO UPDATE SET username = excluded.username, secret = excluded.secret,
+			created_by = excluded.created_by, created_at = CURRENT_TIMESTAMP`,
+		host, username, secret, createdBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.get("registry = ?", host)
+}
+
+const credentialColumns = "id, registry, username, created_by, created_at"
+
+func scanCredential(row interface{ Scan(...interface{}) error }) (*Credential, error) {
+	var credential Credential
+	var createdBy sql.NullInt64
+	if err := row.Scan(&credential.ID, &credential.Registry, &credential.Username, &createdBy, &credential.CreatedAt); err != nil {
+		return nil, err
+	}
+	credential.CreatedBy = createdBy.Int64
+	return &credential, nil
+}
+
+func (s *Store) get(where string, arg interface{}) (*Credential, error) {
+	credential, err := scanCredential(s.db.QueryRow("SELECT "+credentialColumns+" FROM registry_credentials WHERE "+where, arg))
+	if err == sql.ErrNoRows {
+		return nil, ErrCredentialNotFound
+	}
+	return credential, err
+}
+
+// List returns all stored logins without their passwords.
+func (s *Store) List() ([]*Credential, error) {
+	rows, err := s.db.Query("SELECT " + credentialColumns + " FROM registry_credentials ORDER BY registry")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credentials := []*Credential{}
+	for rows.Next() {
+		credential, err := scanCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, rows.Err()
+}
+
+func (s *Store) Delete(id int64) error {
+	result, err := s.db.Exec("DELETE FROM registry_credentials WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrCredentialNotFound
+	}
+	return nil
+}
+
+// AuthFor returns the encoded X-Registry-Auth value for the registry that
+// ref points to, or "" when no login is stored for it.
+func (s *Store) AuthFor(ref string) (string, error) {
+	host, err := Domain(ref)
+	if err != nil {
+		return "", err
+	}
+
+	var username, secret string
+	err = s.db.QueryRow("SELECT username, secret FROM registry_credentials WHERE registry = ?", host).Scan(&username, &secret)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	password, err := s.open(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return registrytypes.EncodeAuthConfig(registrytypes.AuthConfig{
+		Username:      username,
+		Password:      password,
+		ServerAddress: host,
+	})
+}
+
+// AuthConfigs returns every stored login keyed the way the Docker build API
+// expects, so that builds can pull base images from private registries.
+func (s *Store) AuthConfigs() (map[string]registrytypes.AuthConfig, error) {
+	rows, err := s.db.Query("SELECT registry, username, secret FROM registry_credentials")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	configs := make(map[string]registrytypes.AuthConfig)
+	for rows.Next() {
+		var host, username, secret string
+		if err := rows.Scan(&host, &username, &secret); err != nil {
+			return nil, err
+		}
+		password, err := s.open(secret)
+		if err != nil {
+			return nil, err
+		}
+
+		key := host
+		if host == DockerHub {
+			key = dockerHubIndex
+		}
+		configs[key] = registrytypes.AuthConfig{Username: username, Password: password, ServerAddress: key}
+	}
+	return configs, rows.Err()
+}
+
+// TagReference validates a repository and tag and joins them into a full
+// reference. The tag defaults to "latest".
+func TagReference(repository, tag string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(repository)
+	if err != nil {
+		return "", fmt.Errorf("invalid repository %q: %w", repository, err)
+	}
+	if !reference.IsNameOnly(named) {
+		return "", fmt.Errorf("repository %q must not include a tag or digest", repository)
+	}
+	if tag == "" {
+		tag = "latest"
+	}
+
+	tagged, err := reference.WithTag(named, tag)
+	if err != nil {
+		return "", fmt.Errorf("invalid tag %q: %w", tag, err)
+	}
+	return reference.FamiliarString(tagged), nil
+}
+
+// ValidateTag checks that ref is a repository with an optional tag, as
+// accepted by "docker build -t".
+func ValidateTag(ref string) error {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return fmt.Errorf("invalid tag %q: %w", ref, err)
+	}
+	if _, ok := named.(reference.Digested); ok {
+		return fmt.Errorf("tag %q must not include a digest", ref)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeRegistry(t *testing.T) {
+	cases := map[string]string{
+		"ghcr.io":                      "ghcr.io",
+		"https://GHCR.io/":             "ghcr.io",
+		"http://registry.local:5000/":  "registry.local:5000",
+		"index.docker.io":              DockerHub,
+		"https://registry-1.docker.io": DockerHub,
+	}
+	for input, want := range cases {
+		got, err := NormalizeRegistry(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	_, err := NormalizeRegistry("  ")
+	assert.Error(t, err)
+}
+
+func TestDomain(t *testing.T) {
+	cases := map[string]string{
+		"nginx":                                 DockerHub,
+		"library/nginx:1.25":                    DockerHub,
+		"ghcr.io/org/app:1":                     "ghcr.io",
+		"registry.local:5000/app@sha256:" + sha: "registry.local:5000",
+	}
+	for ref, want := range cases {
+		got, err := Domain(ref)
+		require.NoError(t, err, ref)
+		assert.Equal(t, want, got, ref)
+	}
+
+	_, err := Domain("Not A Reference")
+	assert.Error(t, err)
+}
+
+const sha = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestTagReference(t *testing.T) {
+	ref, err := TagReference("ghcr.io/org/app", "1.2")
+	require.NoError(t, err)
+	assert.Equal(t, "ghcr.io/org/app:1.2", ref)
+
+	ref, err = TagReference("app", "")
+	require.NoError(t, err)
+	assert.Equal(t, "app:latest", ref)
+
+	_, err = TagReference("app:1.0", "2.0")
+	assert.Error(t, err, "repository with a tag")
+
+	_, err = TagReference("app", "bad tag")
+	assert.Error(t, err)
+}
+
+func TestSealRoundTrip(t *testing.T) {
+	store := NewStore(nil, "secret")
+
+	sealed, err := store.seal("hunter2")
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, "hunter2")
+
+	plaintext, err := store.open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", plaintext)
+
+	_, err = NewStore(nil, "other").open(sealed)
+	assert.Error(t, err, "a different secret must not decrypt")
+}
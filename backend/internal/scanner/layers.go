@@ -0,0 +1,285 @@
+package scanner
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// maxPackageDBSize bounds how much of a single package database file is
+// read from a layer.
+const maxPackageDBSize = 64 << 20
+
+// wantedFile reports whether a layer path holds OS or package metadata.
+func wantedFile(name string) bool {
+	switch name {
+	case "etc/os-release", "usr/lib/os-release", "var/lib/dpkg/status", "lib/apk/db/installed":
+		return true
+	}
+	// Distroless images keep one dpkg status file per package.
+	return path.Dir(name) == "var/lib/dpkg/status.d"
+}
+
+// layerFiles is the package metadata found in one layer, together with the
+// whiteouts that hide files of lower layers.
+type layerFiles struct {
+	files   map[string][]byte
+	removed []string
+	opaque  []string
+}
+
+// readLayer extracts the wanted files from a (possibly gzip compressed) layer
+// tar. ok is false when r is not a layer at all, for example an image config.
+func readLayer(r io.Reader) (layer *layerFiles, ok bool, err error) {
+	buffered := bufio.NewReader(r)
+	if magic, _ := buffered.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, false, nil
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = buffered
+	}
+
+	layer = &layerFiles{files: make(map[string][]byte)}
+	tr := tar.NewReader(r)
+	for first := true; ; first = false {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return layer, !first, nil
+		}
+		if err != nil {
+			if first {
+				return nil, false, nil
+			}
+			return nil, false, err
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+		dir, base := path.Split(name)
+		dir = strings.TrimSuffix(dir, "/")
+		switch {
+		case base == ".wh..wh..opq":
+			layer.opaque = append(layer.opaque, dir)
+		case strings.HasPrefix(base, ".wh."):
+			layer.removed = append(layer.removed, path.Join(dir, strings.TrimPrefix(base, ".wh.")))
+		case header.Typeflag == tar.TypeReg && wantedFile(name):
+			data, err := io.ReadAll(io.LimitReader(tr, maxPackageDBSize))
+			if err != nil {
+				return nil, false, err
+			}
+			layer.files[name] = data
+		}
+	}
+}
+
+// saveManifest is the manifest.json of a "docker save" archive.
+type saveManifest struct {
+	Layers []string `json:"Layers"`
+}
+
+// flattenImage reads a "docker save" archive and returns the wanted files as
+// they appear in the image's final filesystem.
+func flattenImage(archive io.Reader) (map[string][]byte, error) {
+	// manifest.json usually comes last, so every entry is probed as a layer
+	// and ordered afterwards.
+	layers := make(map[string]*layerFiles)
+	var manifests []saveManifest
+
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(header.Name)
+		if name == "manifest.json" {
+			if err := json.NewDecoder(tr).Decode(&manifests); err != nil {
+				return nil, fmt.Errorf("invalid manifest.json: %w", err)
+			}
+			continue
+		}
+
+		layer, ok, err := readLayer(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read layer %s: %w", name, err)
+		}
+		if ok {
+			layers[name] = layer
+		}
+	}
+
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("image archive has no manifest.json")
+	}
+
+	fs := make(map[string][]byte)
+	for _, layerPath := range manifests[0].Layers {
+		layer, ok := layers[path.Clean(layerPath)]
+		if !ok {
+			return nil, fmt.Errorf("image archive is missing layer %s", layerPath)
+		}
+
+		for _, dir := range layer.opaque {
+			removeTree(fs, dir)
+		}
+		for _, p := range layer.removed {
+			removeTree(fs, p)
+		}
+		for name, data := range layer.files {
+			fs[name] = data
+		}
+	}
+	return fs, nil
+}
+
+func removeTree(fs map[string][]byte, p string) {
+	for name := range fs {
+		if name == p || strings.HasPrefix(name, p+"/") {
+			delete(fs, name)
+		}
+	}
+}
+
+// OSInfo identifies the distribution of an image.
+type OSInfo struct {
+	ID      string `json:"id"`
+	Version string `json:"version"`
+}
+
+// Package is an installed OS package. Source is the source package name,
+// which distribution advisories are usually published against.
+type Package struct {
+	Name    string `json:"name"`
+	Source  string `json:"source,omitempty"`
+	Version string `json:"version"`
+}
+
+func parseOSRelease(data []byte) OSInfo {
+	var info OSInfo
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+		switch key {
+		case "ID":
+			info.ID = strings.ToLower(value)
+		case "VERSION_ID":
+			info.Version = value
+		}
+	}
+
+	// Alpine advisories are per branch ("3.19"), not per point release.
+	if info.ID == "alpine" {
+		if parts := strings.SplitN(info.Version, ".", 3); len(parts) >= 2 {
+			info.Version = parts[0] + "." + parts[1]
+		}
+	}
+	return info
+}
+
+// parseStanzas splits a dpkg or apk database into blank-line separated
+// records of "key: value" (dpkg) or "K:value" (apk) lines.
+func parseStanzas(data []byte, fn func(fields map[string]string)) {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			if len(fields) > 0 {
+				fn(fields)
+				fields = make(map[string]string)
+			}
+			continue
+		}
+		// Continuation lines of multi-line dpkg fields.
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			fields[key] = strings.TrimSpace(value)
+		}
+	}
+	if len(fields) > 0 {
+		fn(fields)
+	}
+}
+
+func parseDpkgStatus(data []byte) []Package {
+	var packages []Package
+	parseStanzas(data, func(fields map[string]string) {
+		// Distroless status.d files carry no Status field.
+		if status, ok := fields["Status"]; ok && !strings.HasSuffix(status, " installed") {
+			return
+		}
+		if fields["Package"] == "" || fields["Version"] == "" {
+			return
+		}
+
+		source, _, _ := strings.Cut(fields["Source"], " ")
+		packages = append(packages, Package{Name: fields["Package"], Source: source, Version: fields["Version"]})
+	})
+	return packages
+}
+
+func parseApkInstalled(data []byte) []Package {
+	var packages []Package
+	parseStanzas(data, func(fields map[string]string) {
+		if fields["P"] == "" || fields["V"] == "" {
+			return
+		}
+		packages = append(packages, Package{Name: fields["P"], Source: fields["o"], Version: fields["V"]})
+	})
+	return packages
+}
+
+// inventory returns the OS and installed packages of a flattened image, and
+// the package databases it read them from.
+func inventory(fs map[string][]byte) (OSInfo, []Package, []string) {
+	var info OSInfo
+	if data, ok := fs["etc/os-release"]; ok {
+		info = parseOSRelease(data)
+	} else if data, ok := fs["usr/lib/os-release"]; ok {
+		info = parseOSRelease(data)
+	}
+
+	var packages []Package
+	var sources []string
+	names := make([]string, 0, len(fs))
+	for name := range fs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch {
+		case name == "var/lib/dpkg/status" || path.Dir(name) == "var/lib/dpkg/status.d":
+			packages = append(packages, parseDpkgStatus(fs[name])...)
+			if len(sources) == 0 || sources[len(sources)-1] != "dpkg" {
+				sources = append(sources, "dpkg")
+			}
+		case name == "lib/apk/db/installed":
+			packages = append(packages, parseApkInstalled(fs[name])...)
+			sources = append(sources, "apk")
+		}
+	}
+	return info, packages, sources
+}
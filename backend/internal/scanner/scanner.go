@@ -0,0 +1,284 @@
+// Package scanner finds known vulnerabilities in container images and
+// evaluates scan results against a severity policy.
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNoDatabase is returned by scans before a vulnerability database has
+// been imported.
+var ErrNoDatabase = errors.New("no vulnerability database has been imported")
+
+// Scanner scans an image, supplied as a "docker save" archive. Other
+// implementations (for example wrappers around external tools) can be
+// plugged in behind this interface.
+type Scanner interface {
+	Name() string
+	// DatabaseVersion identifies the vulnerability data in use. Stored
+	// results from another version are stale.
+	DatabaseVersion() string
+	Scan(archive io.Reader) (*Result, error)
+}
+
+// Severity of a vulnerability, from SeverityUnknown to SeverityCritical.
+type Severity string
+
+const (
+	SeverityUnknown  Severity = "unknown"
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+var severityRank = map[Severity]int{
+	SeverityUnknown:  0,
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityCritical: 4,
+}
+
+// ParseSeverity accepts a severity name in any case; "" means unknown.
+func ParseSeverity(s string) (Severity, error) {
+	if s == "" {
+		return SeverityUnknown, nil
+	}
+	severity := Severity(strings.ToLower(s))
+	if _, ok := severityRank[severity]; !ok {
+		return "", fmt.Errorf("unknown severity %q", s)
+	}
+	return severity, nil
+}
+
+// AtLeast reports whether s is as severe as other.
+func (s Severity) AtLeast(other Severity) bool {
+	return severityRank[s] >= severityRank[other]
+}
+
+// Finding is a vulnerability affecting an installed package.
+type Finding struct {
+	ID               string   `json:"id"`
+	Package          string   `json:"package"`
+	InstalledVersion string   `json:"installed_version"`
+	FixedVersion     string   `json:"fixed_version,omitempty"`
+	Severity         Severity `json:"severity"`
+	Title            string   `json:"title,omitempty"`
+}
+
+// Result is the outcome of scanning one image.
+type Result struct {
+	Scanner         string           `json:"scanner"`
+	DatabaseVersion string           `json:"database_version"`
+	OS              OSInfo           `json:"os"`
+	PackageSources  []string         `json:"package_sources"`
+	Packages        int              `json:"packages"`
+	Findings        []Finding        `json:"findings"`
+	Summary         map[Severity]int `json:"summary"`
+	// Notice explains results that are incomplete, such as images without
+	// a supported package database.
+	Notice string `json:"notice,omitempty"`
+}
+
+func newResult(scanner, version string, info OSInfo, sources []string, packages int, findings []Finding) *Result {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return findings[i].Severity.AtLeast(findings[j].Severity)
+		}
+		return findings[i].ID < findings[j].ID
+	})
+
+	result := &Result{
+		Scanner:         scanner,
+		DatabaseVersion: version,
+		OS:              info,
+		PackageSources:  sources,
+		Packages:        packages,
+		Findings:        findings,
+		Summary:         make(map[Severity]int),
+	}
+	if result.PackageSources == nil {
+		result.PackageSources = []string{}
+	}
+	for _, finding := range findings {
+		result.Summary[finding.Severity]++
+	}
+	if len(sources) == 0 {
+		result.Notice = "no supported package database (dpkg, apk) found in the image"
+	}
+	return result
+}
+
+// Offline matches the OS packages of an image against a vulnerability
+// database file imported with Import. It needs no network access.
+type Offline struct {
+	path string
+
+	mu sync.RWMutex
+	db *Database
+}
+
+// NewOffline returns a scanner backed by the database file at path. A
+// missing file is not an error; scans fail with ErrNoDatabase until one is
+// imported. An unreadable file is reported, but the returned scanner is
+// still usable and behaves as if no database had been imported.
+func NewOffline(path string) (*Offline, error) {
+	scanner := &Offline{path: path}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return scanner, nil
+	}
+	if err != nil {
+		return scanner, err
+	}
+	defer file.Close()
+
+	db, err := ParseDatabase(file)
+	if err != nil {
+		return scanner, fmt.Errorf("%s: %w", path, err)
+	}
+	scanner.db = db
+	return scanner, nil
+}
+
+func (o *Offline) Name() string {
+	return "offline"
+}
+
+func (o *Offline) DatabaseVersion() string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if o.db == nil {
+		return ""
+	}
+	return o.db.Version
+}
+
+// Advisories returns the number of entries in the current database.
+func (o *Offline) Advisories() int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if o.db == nil {
+		return 0
+	}
+	return len(o.db.Advisories)
+}
+
+// Import validates a database file and replaces the current one with it.
+func (o *Offline) Import(r io.Reader) (*Database, error) {
+	if err := os.MkdirAll(filepath.Dir(o.path), 0750); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(o.path), ".vulndb-*.json")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	db, err := ParseDatabase(io.TeeReader(r, tmp))
+	if err != nil {
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), o.path); err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	o.db = db
+	o.mu.Unlock()
+	return db, nil
+}
+
+func (o *Offline) Scan(archive io.Reader) (*Result, error) {
+	o.mu.RLock()
+	db := o.db
+	o.mu.RUnlock()
+	if db == nil {
+		return nil, ErrNoDatabase
+	}
+
+	fs, err := flattenImage(archive)
+	if err != nil {
+		return nil, err
+	}
+	info, packages, sources := inventory(fs)
+
+	return newResult(o.Name(), db.Version, info, sources, len(packages), db.Match(info, packages)), nil
+}
+
+// Policy blocks images whose scan has findings at or above BlockSeverity.
+// An empty BlockSeverity disables the policy.
+type Policy struct {
+	BlockSeverity Severity `json:"block_severity"`
+	OnPull        bool     `json:"on_pull"`
+	OnDeploy      bool     `json:"on_deploy"`
+	// IgnoreUnfixed skips findings that have no fixed version yet.
+	IgnoreUnfixed bool `json:"ignore_unfixed"`
+	// Allowlist holds vulnerability IDs that never block.
+	Allowlist []string `json:"allowlist"`
+}
+
+// Validate normalizes the severity and checks the allowlist.
+func (p *Policy) Validate() error {
+	if p.BlockSeverity != "" {
+		severity, err := ParseSeverity(string(p.BlockSeverity))
+		if err != nil {
+			return err
+		}
+		p.BlockSeverity = severity
+	}
+	for _, id := range p.Allowlist {
+		if strings.TrimSpace(id) == "" {
+			return fmt.Errorf("allowlist entries must not be empty")
+		}
+	}
+	if p.Allowlist == nil {
+		p.Allowlist = []string{}
+	}
+	return nil
+}
+
+// Enabled reports whether the policy applies at the given stage, "pull" or
+// "deploy".
+func (p Policy) Enabled(stage string) bool {
+	if p.BlockSeverity == "" {
+		return false
+	}
+	return (stage == "pull" && p.OnPull) || (stage == "deploy" && p.OnDeploy)
+}
+
+// Violations returns the findings of result that the policy blocks.
+func (p Policy) Violations(result *Result) []Finding {
+	violations := []Finding{}
+	if p.BlockSeverity == "" {
+		return violations
+	}
+
+	allowed := make(map[string]bool, len(p.Allowlist))
+	for _, id := range p.Allowlist {
+		allowed[id] = true
+	}
+	for _, finding := range result.Findings {
+		if allowed[finding.ID] || !finding.Severity.AtLeast(p.BlockSeverity) {
+			continue
+		}
+		if p.IgnoreUnfixed && finding.FixedVersion == "" {
+			continue
+		}
+		violations = append(violations, finding)
+	}
+	return violations
+}
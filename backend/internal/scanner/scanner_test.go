@@ -0,0 +1,165 @@
+package scanner
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.1", -1},
+		{"1.10", "1.9", 1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0", "1.0a", -1},
+		{"1:0.9", "2.0", 1},
+		{"3.0.11-1~deb12u1", "3.0.11-1~deb12u2", -1},
+		{"3.0.11-1", "3.0.11-1~deb12u2", 1},
+		{"3.1.4-r5", "3.1.4-r10", -1},
+		{"1.2.3-0ubuntu1", "1.2.3-0ubuntu1.1", -1},
+		{"007", "7", 0},
+	} {
+		assert.Equal(t, c.want, CompareVersions(c.a, c.b), "%s vs %s", c.a, c.b)
+		assert.Equal(t, -c.want, CompareVersions(c.b, c.a), "%s vs %s", c.b, c.a)
+	}
+}
+
+type file struct{ name, body string }
+
+func tarOf(t *testing.T, gz bool, files ...file) []byte {
+	var buf bytes.Buffer
+	var zw *gzip.Writer
+	tw := tar.NewWriter(&buf)
+	if gz {
+		zw = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(zw)
+	}
+	for _, f := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.body)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(f.body))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	if zw != nil {
+		require.NoError(t, zw.Close())
+	}
+	return buf.Bytes()
+}
+
+const dpkgStatus = `Package: libssl3
+Status: install ok installed
+Source: openssl (3.0.11-1~deb12u1)
+Version: 3.0.11-1~deb12u1
+Description: Secure Sockets Layer toolkit
+ multi-line description
+
+Package: zlib1g
+Status: install ok installed
+Source: zlib
+Version: 1:1.2.13.dfsg-1
+
+Package: removed-pkg
+Status: deinstall ok config-files
+Version: 1.0
+`
+
+// saveArchive builds a "docker save" style archive with the manifest last,
+// as Docker writes it.
+func saveArchive(t *testing.T) []byte {
+	base := tarOf(t, false,
+		file{"etc/os-release", "ID=debian\nVERSION_ID=\"12\"\n"},
+		file{"var/lib/dpkg/status", dpkgStatus},
+		file{"lib/apk/db/installed", "P:stale\nV:1.0\n"},
+	)
+	// The second layer deletes the apk database and is compressed.
+	top := tarOf(t, true,
+		file{"lib/apk/db/.wh.installed", ""},
+		file{"app/server.js", "console.log(1)"},
+	)
+	manifest, _ := json.Marshal([]map[string]interface{}{
+		{"Config": "blobs/sha256/cfg", "Layers": []string{"blobs/sha256/aaa", "blobs/sha256/bbb"}},
+	})
+	return tarOf(t, false,
+		file{"blobs/sha256/cfg", `{"architecture":"amd64"}`},
+		file{"blobs/sha256/bbb", string(top)},
+		file{"blobs/sha256/aaa", string(base)},
+		file{"manifest.json", string(manifest)},
+	)
+}
+
+const database = `{
+  "version": "2025-10-15",
+  "vulnerabilities": [
+    {"id": "CVE-2024-5535", "os": "debian", "release": "12", "package": "openssl", "fixed_version": "3.0.14-1~deb12u1", "severity": "Critical"},
+    {"id": "CVE-2023-0001", "os": "debian", "release": "11", "package": "openssl", "fixed_version": "3.0.20-1", "severity": "high"},
+    {"id": "CVE-2022-37434", "os": "debian", "package": "zlib", "fixed_version": "1:1.2.13.dfsg-1", "severity": "critical"},
+    {"id": "CVE-2023-45853", "os": "debian", "package": "zlib1g", "severity": "medium"},
+    {"id": "CVE-2024-0002", "os": "alpine", "package": "stale", "severity": "high"}
+  ]
+}`
+
+func TestOfflineScan(t *testing.T) {
+	scanner, err := NewOffline(filepath.Join(t.TempDir(), "db", "vulndb.json"))
+	require.NoError(t, err)
+
+	_, err = scanner.Scan(bytes.NewReader(saveArchive(t)))
+	assert.ErrorIs(t, err, ErrNoDatabase)
+
+	_, err = scanner.Import(strings.NewReader(`{"vulnerabilities": []}`))
+	assert.Error(t, err, "version is required")
+	_, err = scanner.Import(strings.NewReader(database))
+	require.NoError(t, err)
+	assert.Equal(t, "2025-10-15", scanner.DatabaseVersion())
+
+	result, err := scanner.Scan(bytes.NewReader(saveArchive(t)))
+	require.NoError(t, err)
+	assert.Equal(t, OSInfo{ID: "debian", Version: "12"}, result.OS)
+	assert.Equal(t, []string{"dpkg"}, result.PackageSources)
+	assert.Equal(t, 2, result.Packages)
+
+	var ids []string
+	for _, finding := range result.Findings {
+		ids = append(ids, finding.ID)
+	}
+	// Ordered by severity; the fixed zlib and the other release don't match.
+	assert.Equal(t, []string{"CVE-2024-5535", "CVE-2023-45853"}, ids)
+	assert.Equal(t, "libssl3", result.Findings[0].Package)
+	assert.Equal(t, map[Severity]int{SeverityCritical: 1, SeverityMedium: 1}, result.Summary)
+
+	// The imported file is picked up by a new scanner.
+	reloaded, err := NewOffline(scanner.path)
+	require.NoError(t, err)
+	assert.Equal(t, 5, reloaded.Advisories())
+}
+
+func TestPolicy(t *testing.T) {
+	result := &Result{Findings: []Finding{
+		{ID: "CVE-1", Severity: SeverityCritical, FixedVersion: "2.0"},
+		{ID: "CVE-2", Severity: SeverityHigh},
+		{ID: "CVE-3", Severity: SeverityMedium, FixedVersion: "1.1"},
+	}}
+
+	policy := Policy{BlockSeverity: "HIGH", OnPull: true}
+	require.NoError(t, policy.Validate())
+	assert.True(t, policy.Enabled("pull"))
+	assert.False(t, policy.Enabled("deploy"))
+	assert.Len(t, policy.Violations(result), 2)
+
+	policy.IgnoreUnfixed = true
+	policy.Allowlist = []string{"CVE-1"}
+	assert.Empty(t, policy.Violations(result))
+
+	assert.False(t, Policy{OnPull: true}.Enabled("pull"), "no severity disables the policy")
+	assert.Error(t, (&Policy{BlockSeverity: "severe"}).Validate())
+}
@@ -0,0 +1,102 @@
+package scanner
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CompareVersions orders two package versions using the Debian version
+// rules (epoch, upstream version, revision; "~" sorts before everything).
+// Alpine versions such as "3.1.4-r5" compare correctly under the same rules.
+// It returns -1, 0 or 1.
+func CompareVersions(a, b string) int {
+	epochA, upstreamA, revisionA := splitVersion(a)
+	epochB, upstreamB, revisionB := splitVersion(b)
+
+	if epochA != epochB {
+		if epochA < epochB {
+			return -1
+		}
+		return 1
+	}
+	if c := compareFragment(upstreamA, upstreamB); c != 0 {
+		return c
+	}
+	return compareFragment(revisionA, revisionB)
+}
+
+func splitVersion(v string) (int, string, string) {
+	epoch := 0
+	if before, after, ok := strings.Cut(v, ":"); ok {
+		if n, err := strconv.Atoi(before); err == nil {
+			epoch, v = n, after
+		}
+	}
+
+	revision := ""
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		v, revision = v[:i], v[i+1:]
+	}
+	return epoch, v, revision
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// charOrder ranks a non-digit character: "~" first, then the end of the
+// string, then letters, then everything else.
+func charOrder(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+	c := s[i]
+	switch {
+	case isDigit(c):
+		return 0
+	case c == '~':
+		return -1
+	case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
+
+func compareFragment(a, b string) int {
+	for a != "" || b != "" {
+		// Non-digit prefix, character by character.
+		i := 0
+		for (i < len(a) && !isDigit(a[i])) || (i < len(b) && !isDigit(b[i])) {
+			if oa, ob := charOrder(a, i), charOrder(b, i); oa != ob {
+				if oa < ob {
+					return -1
+				}
+				return 1
+			}
+			i++
+		}
+		a, b = a[min(i, len(a)):], b[min(i, len(b)):]
+
+		// Numeric run, compared by value.
+		na, nb := 0, 0
+		for na < len(a) && isDigit(a[na]) {
+			na++
+		}
+		for nb < len(b) && isDigit(b[nb]) {
+			nb++
+		}
+		da, db := strings.TrimLeft(a[:na], "0"), strings.TrimLeft(b[:nb], "0")
+		if len(da) != len(db) {
+			if len(da) < len(db) {
+				return -1
+			}
+			return 1
+		}
+		if c := strings.Compare(da, db); c != 0 {
+			return c
+		}
+		a, b = a[na:], b[nb:]
+	}
+	return 0
+}
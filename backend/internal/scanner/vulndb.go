@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Advisory is one entry of the vulnerability database: package Package of
+// the distribution OS (and, if set, Release) is affected below FixedVersion.
+// An empty FixedVersion means no fix is available and every version is
+// affected.
+type Advisory struct {
+	ID           string   `json:"id"`
+	OS           string   `json:"os"`
+	Release      string   `json:"release,omitempty"`
+	Package      string   `json:"package"`
+	FixedVersion string   `json:"fixed_version,omitempty"`
+	Severity     Severity `json:"severity"`
+	Title        string   `json:"title,omitempty"`
+}
+
+// Database is a vulnerability database in the file format accepted by the
+// import endpoint.
+type Database struct {
+	Version    string     `json:"version"`
+	Advisories []Advisory `json:"vulnerabilities"`
+
+	index map[string][]*Advisory
+}
+
+// ParseDatabase reads and validates a vulnerability database file.
+func ParseDatabase(r io.Reader) (*Database, error) {
+	var db Database
+	if err := json.NewDecoder(r).Decode(&db); err != nil {
+		return nil, fmt.Errorf("invalid vulnerability database: %w", err)
+	}
+	if db.Version == "" {
+		return nil, fmt.Errorf("invalid vulnerability database: version is required")
+	}
+
+	db.index = make(map[string][]*Advisory)
+	for i := range db.Advisories {
+		advisory := &db.Advisories[i]
+		if advisory.ID == "" || advisory.OS == "" || advisory.Package == "" {
+			return nil, fmt.Errorf("invalid vulnerability database: entry %d needs id, os and package", i)
+		}
+		advisory.OS = strings.ToLower(advisory.OS)
+		severity, err := ParseSeverity(string(advisory.Severity))
+		if err != nil {
+			return nil, fmt.Errorf("invalid vulnerability database: %s: %w", advisory.ID, err)
+		}
+		advisory.Severity = severity
+
+		key := advisory.OS + "/" + advisory.Package
+		db.index[key] = append(db.index[key], advisory)
+	}
+	return &db, nil
+}
+
+// Match returns the findings for the packages of an image running info.
+func (db *Database) Match(info OSInfo, packages []Package) []Finding {
+	findings := []Finding{}
+	for _, pkg := range packages {
+		seen := make(map[string]bool)
+		for _, name := range []string{pkg.Name, pkg.Source} {
+			if name == "" {
+				continue
+			}
+			for _, advisory := range db.index[info.ID+"/"+name] {
+				if seen[advisory.ID] {
+					continue
+				}
+				if advisory.Release != "" && advisory.Release != info.Version {
+					continue
+				}
+				if advisory.FixedVersion != "" && CompareVersions(pkg.Version, advisory.FixedVersion) >= 0 {
+					continue
+				}
+
+				seen[advisory.ID] = true
+				findings = append(findings, Finding{
+					ID:               advisory.ID,
+					Package:          pkg.Name,
+					InstalledVersion: pkg.Version,
+					FixedVersion:     advisory.FixedVersion,
+					Severity:         advisory.Severity,
+					Title:            advisory.Title,
+				})
+			}
+		}
+	}
+	return findings
+}
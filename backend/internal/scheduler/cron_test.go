@@ -0,0 +1,50 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	base := time.Date(2025, 10, 15, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"*/15 * * * *", time.Date(2025, 10, 15, 10, 30, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2025, 10, 16, 3, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 10, 15, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)},
+		// 2025-10-15 is a Wednesday.
+		{"30 2 * * 1-5", time.Date(2025, 10, 16, 2, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 10, 19, 0, 0, 0, 0, time.UTC)},
+		// Restricted day-of-month and day-of-week match either one.
+		{"0 0 1 * 5", time.Date(2025, 10, 17, 0, 0, 0, 0, time.UTC)},
+		{"@every 90m", time.Date(2025, 10, 15, 11, 47, 30, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.next, schedule.Next(base))
+		})
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "@every 10s", "@every soon"} {
+		_, err := ParseSchedule(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestScheduleNeverFires(t *testing.T) {
+	schedule, err := ParseSchedule("0 0 31 2 *")
+	require.NoError(t, err)
+	assert.True(t, schedule.Next(time.Now()).IsZero())
+}
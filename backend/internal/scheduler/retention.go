@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+)
+
+// Retention decides which job artifacts are kept. Zero values disable a
+// rule; with both rules disabled every artifact is kept.
+type Retention struct {
+	// KeepLast keeps the N most recent artifacts.
+	KeepLast int `json:"keep_last"`
+	// KeepDaily keeps the newest artifact of each of the last D days.
+	KeepDaily int `json:"keep_daily"`
+}
+
+// Artifact is the output of one successful run.
+type Artifact struct {
+	RunID     int64
+	CreatedAt time.Time
+}
+
+// Expired returns the run IDs whose artifacts no rule keeps.
+func (r Retention) Expired(artifacts []Artifact, now time.Time) []int64 {
+	if r.KeepLast <= 0 && r.KeepDaily <= 0 {
+		return nil
+	}
+
+	sorted := make([]Artifact, len(artifacts))
+	copy(sorted, artifacts)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].CreatedAt.After(sorted[j].CreatedAt) })
+
+	keep := make(map[int64]bool)
+	for i := 0; i < len(sorted) && i < r.KeepLast; i++ {
+		keep[sorted[i].RunID] = true
+	}
+
+	if r.KeepDaily > 0 {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		oldest := today.AddDate(0, 0, -(r.KeepDaily - 1))
+		seenDays := make(map[string]bool)
+
+		for _, artifact := range sorted {
+			created := artifact.CreatedAt.In(now.Location())
+			if created.Before(oldest) {
+				continue
+			}
+			day := created.Format("2006-01-02")
+			if !seenDays[day] {
+				seenDays[day] = true
+				keep[artifact.RunID] = true
+			}
+		}
+	}
+
+	var expired []int64
+	for _, artifact := range sorted {
+		if !keep[artifact.RunID] {
+			expired = append(expired, artifact.RunID)
+		}
+	}
+	return expired
+}
@@ -0,0 +1,27 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionExpired(t *testing.T) {
+	now := time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)
+	artifacts := []Artifact{
+		{RunID: 1, CreatedAt: now.Add(-72 * time.Hour)},
+		{RunID: 2, CreatedAt: now.Add(-50 * time.Hour)},
+		{RunID: 3, CreatedAt: now.Add(-26 * time.Hour)},
+		{RunID: 4, CreatedAt: now.Add(-25 * time.Hour)},
+		{RunID: 5, CreatedAt: now.Add(-2 * time.Hour)},
+		{RunID: 6, CreatedAt: now.Add(-1 * time.Hour)},
+	}
+
+	assert.Nil(t, Retention{}.Expired(artifacts, now))
+	assert.Equal(t, []int64{4, 3, 2, 1}, Retention{KeepLast: 2}.Expired(artifacts, now))
+	// Newest of today and of yesterday.
+	assert.Equal(t, []int64{5, 3, 2, 1}, Retention{KeepDaily: 2}.Expired(artifacts, now))
+	// The union of both rules is kept.
+	assert.Equal(t, []int64{3, 2, 1}, Retention{KeepLast: 3, KeepDaily: 2}.Expired(artifacts, now))
+}
@@ -0,0 +1,469 @@
+package scheduler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cyber-container-platform/internal/logger"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrUnknownType = errors.New("unknown job type")
+	ErrJobRunning  = errors.New("job is already running")
+)
+
+// Run statuses.
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Output is what a run of a job produced.
+type Output struct {
+	// Artifact references what the run left behind, such as a file, or is
+	// "" if there is nothing for retention to manage.
+	Artifact string
+	// Summary describes the result in the run history.
+	Summary string
+}
+
+// Task performs one type of job.
+type Task interface {
+	// Run executes the job against target. The output is recorded even when
+	// the run fails.
+	Run(target string) (Output, error)
+	// Remove deletes an artifact once retention no longer keeps it.
+	Remove(artifact string) error
+	// Validate checks a job's target when the job is created.
+	Validate(target string) error
+}
+
+// TaskFuncs adapts plain functions to Task. Nil functions are no-ops.
+type TaskFuncs struct {
+	RunFunc      func(target string) (Output, error)
+	RemoveFunc   func(artifact string) error
+	ValidateFunc func(target string) error
+}
+
+func (t TaskFuncs) Run(target string) (Output, error) { return t.RunFunc(target) }
+
+func (t TaskFuncs) Remove(artifact string) error {
+	if t.RemoveFunc == nil {
+		return nil
+	}
+	return t.RemoveFunc(artifact)
+}
+
+func (t TaskFuncs) Validate(target string) error {
+	if t.ValidateFunc == nil {
+		return nil
+	}
+	return t.ValidateFunc(target)
+}
+
+type Job struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Type       string     `json:"type"`
+	Schedule   string     `json:"schedule"`
+	Target     string     `json:"target"`
+	Retention  Retention  `json:"retention"`
+	Paused     bool       `json:"paused"`
+	NextRun    *time.Time `json:"next_run"`
+	LastRun    *time.Time `json:"last_run"`
+	LastStatus string     `json:"last_status"`
+	CreatedBy  int64      `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type Run struct {
+	ID         int64      `json:"id"`
+	JobID      int64      `json:"job_id"`
+	Status     string     `json:"status"`
+	Trigger    string     `json:"triggered_by"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	DurationMs int64      `json:"duration_ms"`
+	Error      string     `json:"error,omitempty"`
+	Artifact   string     `json:"artifact,omitempty"`
+	Summary    string     `json:"summary,omitempty"`
+	Pruned     bool       `json:"pruned"`
+}
+
+// Scheduler runs jobs stored in scheduled_jobs when their cron schedule is
+// due and records every run in job_runs.
+type Scheduler struct {
+	db       *sql.DB
+	logger   *logger.Logger
+	interval time.Duration
+
+	mu      sync.Mutex
+	tasks   map[string]Task
+	running map[int64]bool
+	stop    chan struct{}
+}
+
+func New(db *sql.DB) *Scheduler {
+	return &Scheduler{
+		db:       db,
+		logger:   logger.New("scheduler", logger.INFO),
+		interval: 30 * time.Second,
+		tasks:    make(map[string]Task),
+		running:  make(map[int64]bool),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Register makes a job type available. It must be called before Run.
+func (s *Scheduler) Register(jobType string, task Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[jobType] = task
+}
+
+// Types lists the registered job types.
+func (s *Scheduler) Types() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var types []string
+	for jobType := range s.tasks {
+		types = append(types, jobType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func (s *Scheduler) task(jobType string) (Task, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.tasks[jobType]
+	return task, ok
+}
+
+// Run checks for due jobs until Stop is called. Runs left "running" by a
+// previous process are marked failed first.
+func (s *Scheduler) Run() {
+	s.db.Exec(
+		"UPDATE job_runs SET status = ?, error = ?, finished_at = ? WHERE status = ?",
+		StatusFailed, "interrupted by shutdown", time.Now(), StatusRunning,
+	)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.runDue(time.Now())
+	for {
+		select {
+		case now := <-ticker.C:
+			s.runDue(now)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) Stop() {
+	close(s.stop)
+}
+
+func (s *Scheduler) runDue(now time.Time) {
+	jobs, err := s.List()
+	if err != nil {
+		s.logger.Error("Failed to load scheduled jobs", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if job.Paused || job.NextRun == nil || job.NextRun.After(now) {
+			continue
+		}
+		if _, err := s.start(job, "schedule"); err != nil && err != ErrJobRunning {
+			s.logger.Error("Failed to start scheduled job", err, map[string]interface{}{"job": job.Name})
+		}
+	}
+}
+
+// Trigger runs a job immediately, regardless of its schedule or pause state.
+func (s *Scheduler) Trigger(id int64) (int64, error) {
+	job, err := s.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	return s.start(job, "manual")
+}
+
+// start records a run, advances next_run and executes the job in the
+// background. A job never runs twice concurrently.
+func (s *Scheduler) start(job *Job, trigger string) (int64, error) {
+	task, ok := s.task(job.Type)
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrUnknownType, job.Type)
+	}
+
+	s.mu.Lock()
+	if s.running[job.ID] {
+		s.mu.Unlock()
+		return 0, ErrJobRunning
+	}
+	s.running[job.ID] = true
+	s.mu.Unlock()
+
+	started := time.Now()
+	result, err := s.db.Exec(
+		"INSERT INTO job_runs (job_id, status, triggered_by, started_at) VALUES (?, ?, ?, ?)",
+		job.ID, StatusRunning, trigger, started,
+	)
+	if err != nil {
+		s.finish(job.ID)
+		return 0, err
+	}
+	runID, _ := result.LastInsertId()
+
+	if trigger == "schedule" {
+		if schedule, err := ParseSchedule(job.Schedule); err == nil {
+			s.db.Exec("UPDATE scheduled_jobs SET next_run = ? WHERE id = ?", nullTime(schedule.Next(started)), job.ID)
+		}
+	}
+
+	go s.execute(job, task, runID, started)
+	return runID, nil
+}
+
+func (s *Scheduler) finish(jobID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, jobID)
+}
+
+func (s *Scheduler) execute(job *Job, task Task, runID int64, started time.Time) {
+	defer s.finish(job.ID)
+
+	output, err := task.Run(job.Target)
+
+	finished := time.Now()
+	status, message := StatusSucceeded, ""
+	if err != nil {
+		status, message = StatusFailed, err.Error()
+		s.logger.Error("Scheduled job failed", err, map[string]interface{}{"job": job.Name, "run": runID})
+	}
+
+	s.db.Exec(
+		"UPDATE job_runs SET status = ?, error = ?, artifact = ?, summary = ?, finished_at = ?, duration_ms = ? WHERE id = ?",
+		status, message, output.Artifact, output.Summary, finished, finished.Sub(started).Milliseconds(), runID,
+	)
+	s.db.Exec("UPDATE scheduled_jobs SET last_run = ?, last_status = ? WHERE id = ?", started, status, job.ID)
+
+	if err == nil {
+		s.applyRetention(job, task, finished)
+	}
+}
+
+// applyRetention removes artifacts of successful runs that the job's
+// retention policy no longer keeps. The run history itself is kept.
+func (s *Scheduler) applyRetention(job *Job, task Task, now time.Time) {
+	rows, err := s.db.Query(
+		"SELECT id, artifact, started_at FROM job_runs WHERE job_id = ? AND status = ? AND artifact != '' AND pruned = 0",
+		job.ID, StatusSucceeded,
+	)
+	if err != nil {
+		s.logger.Error("Failed to load job artifacts", err)
+		return
+	}
+
+	artifacts := make(map[int64]string)
+	var candidates []Artifact
+	for rows.Next() {
+		var id int64
+		var artifact string
+		var started time.Time
+		if err := rows.Scan(&id, &artifact, &started); err != nil {
+			continue
+		}
+		artifacts[id] = artifact
+		candidates = append(candidates, Artifact{RunID: id, CreatedAt: started})
+	}
+	rows.Close()
+
+	for _, runID := range job.Retention.Expired(candidates, now) {
+		if err := task.Remove(artifacts[runID]); err != nil {
+			s.logger.Error("Failed to remove expired artifact", err, map[string]interface{}{"job": job.Name, "run": runID})
+			continue
+		}
+		s.db.Exec("UPDATE job_runs SET pruned = 1 WHERE id = ?", runID)
+	}
+}
+
+// Create validates and stores a new job, computing its first run time.
+func (s *Scheduler) Create(job *Job) error {
+	task, ok := s.task(job.Type)
+	if !ok {
+		return fmt.Errorf("%w %q (available: %s)", ErrUnknownType, job.Type, strings.Join(s.Types(), ", "))
+	}
+	if strings.TrimSpace(job.Name) == "" {
+		return fmt.Errorf("job name is required")
+	}
+	if job.Retention.KeepLast < 0 || job.Retention.KeepDaily < 0 {
+		return fmt.Errorf("retention values cannot be negative")
+	}
+	if err := task.Validate(job.Target); err != nil {
+		return err
+	}
+
+	schedule, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+	next := schedule.Next(time.Now())
+	if next.IsZero() {
+		return fmt.Errorf("invalid schedule: it never fires")
+	}
+	job.NextRun = &next
+
+	result, err := s.db.Exec(
+		`INSERT INTO scheduled_jobs (name, type, schedule, target, keep_last, keep_daily, paused, next_run, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.Name, job.Type, job.Schedule, job.Target, job.Retention.KeepLast, job.Retention.KeepDaily,
+		job.Paused, next, nullID(job.CreatedBy),
+	)
+	if err != nil {
+		return err
+	}
+
+	job.ID, _ = result.LastInsertId()
+	job.CreatedAt = time.Now()
+	return nil
+}
+
+// SetPaused pauses or resumes a job. Resuming recomputes the next run so a
+// long pause does not cause an immediate catch-up run.
+func (s *Scheduler) SetPaused(id int64, paused bool) error {
+	job, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+
+	var next interface{}
+	if !paused {
+		if schedule, err := ParseSchedule(job.Schedule); err == nil {
+			next = nullTime(schedule.Next(time.Now()))
+		}
+	}
+
+	_, err = s.db.Exec("UPDATE scheduled_jobs SET paused = ?, next_run = ? WHERE id = ?", paused, next, id)
+	return err
+}
+
+// Delete removes a job and its run history. Artifacts already produced are
+// left in place.
+func (s *Scheduler) Delete(id int64) error {
+	result, err := s.db.Exec("DELETE FROM scheduled_jobs WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrJobNotFound
+	}
+
+	_, err = s.db.Exec("DELETE FROM job_runs WHERE job_id = ?", id)
+	return err
+}
+
+const jobColumns = "id, name, type, schedule, target, keep_last, keep_daily, paused, next_run, last_run, last_status, created_by, created_at"
+
+func scanJob(row interface{ Scan(...interface{}) error }) (*Job, error) {
+	var job Job
+	var nextRun, lastRun sql.NullTime
+	var createdBy sql.NullInt64
+
+	err := row.Scan(&job.ID, &job.Name, &job.Type, &job.Schedule, &job.Target,
+		&job.Retention.KeepLast, &job.Retention.KeepDaily, &job.Paused,
+		&nextRun, &lastRun, &job.LastStatus, &createdBy, &job.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if nextRun.Valid {
+		job.NextRun = &nextRun.Time
+	}
+	if lastRun.Valid {
+		job.LastRun = &lastRun.Time
+	}
+	job.CreatedBy = createdBy.Int64
+	return &job, nil
+}
+
+func (s *Scheduler) Get(id int64) (*Job, error) {
+	job, err := scanJob(s.db.QueryRow("SELECT "+jobColumns+" FROM scheduled_jobs WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrJobNotFound
+	}
+	return job, err
+}
+
+func (s *Scheduler) List() ([]*Job, error) {
+	rows, err := s.db.Query("SELECT " + jobColumns + " FROM scheduled_jobs ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// Runs returns the most recent runs of a job, newest first.
+func (s *Scheduler) Runs(jobID int64, limit int) ([]*Run, error) {
+	rows, err := s.db.Query(
+		`SELECT id, job_id, status, triggered_by, started_at, finished_at, duration_ms, error, artifact, summary, pruned
+		FROM job_runs WHERE job_id = ? ORDER BY started_at DESC, id DESC LIMIT ?`,
+		jobID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []*Run
+	for rows.Next() {
+		var run Run
+		var finished sql.NullTime
+		if err := rows.Scan(&run.ID, &run.JobID, &run.Status, &run.Trigger, &run.StartedAt,
+			&finished, &run.DurationMs, &run.Error, &run.Artifact, &run.Summary, &run.Pruned); err != nil {
+			return nil, err
+		}
+		if finished.Valid {
+			run.FinishedAt = &finished.Time
+		}
+		runs = append(runs, &run)
+	}
+	return runs, rows.Err()
+}
+
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+func nullID(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
@@ -0,0 +1,169 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// BundleAPIVersion is the bundle format version written by Marshal.
+	BundleAPIVersion = "cyber.platform/v1"
+	// BundleKind identifies a template bundle document.
+	BundleKind = "Template"
+)
+
+// Bundle is the portable, self-contained representation of a container
+// template. It is what templates are exported as and imported from, and the
+// format of the built-in catalog.
+type Bundle struct {
+	APIVersion string                 `json:"api_version" yaml:"api_version"`
+	Kind       string                 `json:"kind" yaml:"kind"`
+	Metadata   Metadata               `json:"metadata" yaml:"metadata"`
+	Image      string                 `json:"image" yaml:"image"`
+	Parameters []Parameter            `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	Schema     map[string]interface{} `json:"schema,omitempty" yaml:"schema,omitempty"`
+	Config     interface{}            `json:"config" yaml:"config"`
+}
+
+type Metadata struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Version     string   `json:"version" yaml:"version"`
+	Icon        string   `json:"icon,omitempty" yaml:"icon,omitempty"`
+	Tags        []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Author      string   `json:"author,omitempty" yaml:"author,omitempty"`
+}
+
+// Parameter is a value the user supplies when deploying a template.
+type Parameter struct {
+	Name        string `json:"name" yaml:"name"`
+	Label       string `json:"label,omitempty" yaml:"label,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Type        string `json:"type" yaml:"type"`
+	Default     string `json:"default,omitempty" yaml:"default,omitempty"`
+	Required    bool   `json:"required,omitempty" yaml:"required,omitempty"`
+}
+
+var parameterTypes = map[string]bool{
+	"string":   true,
+	"number":   true,
+	"boolean":  true,
+	"port":     true,
+	"password": true,
+}
+
+// Parse decodes a bundle from JSON or YAML. JSON is detected by a leading
+// '{'; anything else is treated as YAML, which is a superset of JSON anyway.
+func Parse(data []byte) (*Bundle, error) {
+	var bundle Bundle
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("bundle is empty")
+	}
+
+	if trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &bundle); err != nil {
+			return nil, fmt.Errorf("invalid JSON bundle: %w", err)
+		}
+	} else {
+		if err := yaml.Unmarshal(trimmed, &bundle); err != nil {
+			return nil, fmt.Errorf("invalid YAML bundle: %w", err)
+		}
+	}
+
+	if err := bundle.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &bundle, nil
+}
+
+// Marshal encodes the bundle as "yaml" or "json".
+func Marshal(bundle *Bundle, format string) ([]byte, error) {
+	switch format {
+	case "yaml", "yml", "":
+		return yaml.Marshal(bundle)
+	case "json":
+		return json.MarshalIndent(bundle, "", "  ")
+	default:
+		return nil, fmt.Errorf("unsupported bundle format %q", format)
+	}
+}
+
+// Validate checks that the bundle is complete enough to become a template.
+// Missing api_version and kind are filled in so hand-written bundles stay short.
+func (b *Bundle) Validate() error {
+	if b.APIVersion == "" {
+		b.APIVersion = BundleAPIVersion
+	}
+	if b.Kind == "" {
+		b.Kind = BundleKind
+	}
+
+	if b.APIVersion != BundleAPIVersion {
+		return fmt.Errorf("unsupported bundle api_version %q", b.APIVersion)
+	}
+	if b.Kind != BundleKind {
+		return fmt.Errorf("unsupported bundle kind %q", b.Kind)
+	}
+	if strings.TrimSpace(b.Metadata.Name) == "" {
+		return fmt.Errorf("bundle metadata.name is required")
+	}
+	if strings.TrimSpace(b.Image) == "" {
+		return fmt.Errorf("bundle image is required")
+	}
+	if b.Config == nil {
+		return fmt.Errorf("bundle config is required")
+	}
+	if b.Metadata.Version == "" {
+		b.Metadata.Version = "1.0.0"
+	}
+
+	seen := make(map[string]bool)
+	for i, param := range b.Parameters {
+		if param.Name == "" {
+			return fmt.Errorf("parameter %d has no name", i)
+		}
+		if seen[param.Name] {
+			return fmt.Errorf("duplicate parameter %q", param.Name)
+		}
+		seen[param.Name] = true
+
+		if param.Type == "" {
+			b.Parameters[i].Type = "string"
+		} else if !parameterTypes[param.Type] {
+			return fmt.Errorf("parameter %q has unsupported type %q", param.Name, param.Type)
+		}
+	}
+
+	return nil
+}
+
+// ConfigString returns the config as the JSON text stored in
+// container_templates.config. A config that is already a string is kept as is.
+func (b *Bundle) ConfigString() (string, error) {
+	if s, ok := b.Config.(string); ok {
+		return s, nil
+	}
+
+	data, err := json.Marshal(b.Config)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode config: %w", err)
+	}
+	return string(data), nil
+}
+
+// DecodeConfig is the inverse of ConfigString: JSON config text becomes a
+// structured value so it round-trips readably through YAML.
+func DecodeConfig(config string) interface{} {
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(config), &decoded); err != nil {
+		return config
+	}
+	return decoded
+}
@@ -0,0 +1,63 @@
+package templates
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseYAMLAndJSON(t *testing.T) {
+	yamlBundle := []byte(`
+metadata:
+  name: Web
+image: nginx:alpine
+parameters:
+  - name: PORT
+config:
+  ports:
+    "8080": "80"
+`)
+	bundle, err := Parse(yamlBundle)
+	assert.NoError(t, err)
+	assert.Equal(t, BundleAPIVersion, bundle.APIVersion)
+	assert.Equal(t, "1.0.0", bundle.Metadata.Version)
+	assert.Equal(t, "string", bundle.Parameters[0].Type)
+
+	config, err := bundle.ConfigString()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"ports":{"8080":"80"}}`, config)
+
+	data, err := Marshal(bundle, "json")
+	assert.NoError(t, err)
+	roundTrip, err := Parse(data)
+	assert.NoError(t, err)
+	assert.Equal(t, bundle.Metadata, roundTrip.Metadata)
+}
+
+func TestParseRejectsInvalidBundles(t *testing.T) {
+	_, err := Parse([]byte(""))
+	assert.Error(t, err)
+
+	_, err = Parse([]byte(`{"metadata":{"name":"x"},"config":{}}`))
+	assert.ErrorContains(t, err, "image")
+
+	_, err = Parse([]byte(`{"kind":"Network","metadata":{"name":"x"},"image":"nginx","config":{}}`))
+	assert.ErrorContains(t, err, "kind")
+
+	_, err = Parse([]byte(`{"metadata":{"name":"x"},"image":"nginx","config":{},"parameters":[{"name":"a"},{"name":"a"}]}`))
+	assert.ErrorContains(t, err, "duplicate")
+
+	_, err = Parse([]byte(`{"metadata":{"name":"x"},"image":"nginx","config":{},"parameters":[{"name":"a","type":"date"}]}`))
+	assert.ErrorContains(t, err, "unsupported type")
+}
+
+func TestCatalog(t *testing.T) {
+	bundles, err := Catalog()
+	assert.NoError(t, err)
+
+	var images []string
+	for _, bundle := range bundles {
+		images = append(images, bundle.Image)
+	}
+	assert.ElementsMatch(t, []string{"nginx:alpine", "redis:7-alpine", "postgres:16-alpine", "node:20-alpine", "python:3.12-slim"}, images)
+}
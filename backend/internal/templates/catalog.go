@@ -0,0 +1,42 @@
+package templates
+
+import (
+	"embed"
+	"fmt"
+	"sort"
+)
+
+//go:embed catalog/*.yaml
+var catalogFS embed.FS
+
+// CatalogVersion is bumped whenever the built-in catalog changes. On startup
+// an installation seeded with a different revision updates its built-in
+// templates to the current catalog.
+const CatalogVersion = "1"
+
+// Catalog returns the built-in template bundles embedded in the binary,
+// sorted by file name.
+func Catalog() ([]*Bundle, error) {
+	entries, err := catalogFS.ReadDir("catalog")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template catalog: %w", err)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var bundles []*Bundle
+	for _, entry := range entries {
+		data, err := catalogFS.ReadFile("catalog/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+
+		bundle, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid catalog template %s: %w", entry.Name(), err)
+		}
+		bundles = append(bundles, bundle)
+	}
+
+	return bundles, nil
+}
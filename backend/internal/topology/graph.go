@@ -0,0 +1,204 @@
+package topology
+
+import (
+	"fmt"
+	"sort"
+
+	"cyber-container-platform/internal/docker"
+)
+
+// Node kinds.
+const (
+	KindNetwork   = "network"
+	KindContainer = "container"
+	KindVolume    = "volume"
+	KindBind      = "bind"
+	KindPort      = "port"
+)
+
+// Edge kinds.
+const (
+	EdgeAttached  = "attached"  // container -> network
+	EdgeMounts    = "mounts"    // container -> volume or bind
+	EdgePublishes = "publishes" // container -> host port
+)
+
+type Node struct {
+	ID    string                 `json:"id"`
+	Kind  string                 `json:"kind"`
+	Label string                 `json:"label"`
+	Data  map[string]interface{} `json:"data,omitempty"`
+}
+
+type Edge struct {
+	Source string                 `json:"source"`
+	Target string                 `json:"target"`
+	Kind   string                 `json:"kind"`
+	Data   map[string]interface{} `json:"data,omitempty"`
+}
+
+// Peer is a pair of containers that share at least one network and can
+// therefore reach each other directly.
+type Peer struct {
+	A        string   `json:"a"`
+	B        string   `json:"b"`
+	Networks []string `json:"networks"`
+}
+
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+	Peers []Peer `json:"peers"`
+}
+
+func networkNodeID(id string) string   { return "network:" + id }
+func containerNodeID(id string) string { return "container:" + id }
+func volumeNodeID(name string) string  { return "volume:" + name }
+func bindNodeID(source string) string  { return "bind:" + source }
+func portNodeID(p docker.PortInfo) string {
+	return fmt.Sprintf("port:%s:%d/%s", p.IP, p.PublicPort, p.Type)
+}
+
+// Build assembles the graph from network and container listings. Networks
+// referenced only by containers (for example ones removed while still in use)
+// are still added so that no edge dangles.
+func Build(networks []docker.NetworkInfo, containers []docker.ContainerInfo) Graph {
+	graph := Graph{Nodes: []Node{}, Edges: []Edge{}, Peers: []Peer{}}
+	seen := make(map[string]bool)
+
+	addNode := func(node Node) {
+		if seen[node.ID] {
+			return
+		}
+		seen[node.ID] = true
+		graph.Nodes = append(graph.Nodes, node)
+	}
+
+	for _, net := range networks {
+		var subnets []string
+		for _, cfg := range net.IPAM.Config {
+			subnets = append(subnets, cfg.Subnet)
+		}
+		addNode(Node{
+			ID:    networkNodeID(net.ID),
+			Kind:  KindNetwork,
+			Label: net.Name,
+			Data: map[string]interface{}{
+				"driver":   net.Driver,
+				"scope":    net.Scope,
+				"internal": net.Internal,
+				"subnets":  subnets,
+			},
+		})
+	}
+
+	// network ID -> container names attached to it, for reachability
+	members := make(map[string][]string)
+	networkNames := make(map[string]string)
+
+	for _, cont := range containers {
+		addNode(Node{
+			ID:    containerNodeID(cont.ID),
+			Kind:  KindContainer,
+			Label: cont.Name,
+			Data: map[string]interface{}{
+				"image": cont.Image,
+				"state": cont.State,
+			},
+		})
+
+		for name, endpoint := range cont.Networks {
+			if endpoint.NetworkID == "" {
+				continue
+			}
+			addNode(Node{ID: networkNodeID(endpoint.NetworkID), Kind: KindNetwork, Label: name})
+			graph.Edges = append(graph.Edges, Edge{
+				Source: containerNodeID(cont.ID),
+				Target: networkNodeID(endpoint.NetworkID),
+				Kind:   EdgeAttached,
+				Data: map[string]interface{}{
+					"ipv4_address": endpoint.IPAddress,
+					"ipv6_address": endpoint.IPv6Address,
+					"aliases":      endpoint.Aliases,
+				},
+			})
+			members[endpoint.NetworkID] = append(members[endpoint.NetworkID], cont.Name)
+			networkNames[endpoint.NetworkID] = name
+		}
+
+		for _, mount := range cont.Mounts {
+			var target string
+			switch {
+			case mount.Type == "volume" && mount.Name != "":
+				target = volumeNodeID(mount.Name)
+				addNode(Node{ID: target, Kind: KindVolume, Label: mount.Name})
+			case mount.Type == "bind":
+				target = bindNodeID(mount.Source)
+				addNode(Node{ID: target, Kind: KindBind, Label: mount.Source})
+			default:
+				continue
+			}
+			graph.Edges = append(graph.Edges, Edge{
+				Source: containerNodeID(cont.ID),
+				Target: target,
+				Kind:   EdgeMounts,
+				Data: map[string]interface{}{
+					"destination": mount.Destination,
+					"read_write":  mount.ReadWrite,
+				},
+			})
+		}
+
+		for _, port := range cont.Ports {
+			if port.PublicPort == 0 {
+				continue
+			}
+			target := portNodeID(port)
+			addNode(Node{
+				ID:    target,
+				Kind:  KindPort,
+				Label: fmt.Sprintf("%s:%d/%s", port.IP, port.PublicPort, port.Type),
+			})
+			graph.Edges = append(graph.Edges, Edge{
+				Source: containerNodeID(cont.ID),
+				Target: target,
+				Kind:   EdgePublishes,
+				Data: map[string]interface{}{
+					"container_port": port.PrivatePort,
+				},
+			})
+		}
+	}
+
+	graph.Peers = peers(members, networkNames)
+	return graph
+}
+
+// peers lists each pair of containers sharing a network once, with the
+// names of all networks they share.
+func peers(members map[string][]string, networkNames map[string]string) []Peer {
+	shared := make(map[[2]string][]string)
+	for networkID, names := range members {
+		sort.Strings(names)
+		for i := 0; i < len(names); i++ {
+			for j := i + 1; j < len(names); j++ {
+				key := [2]string{names[i], names[j]}
+				shared[key] = append(shared[key], networkNames[networkID])
+			}
+		}
+	}
+
+	result := []Peer{}
+	for key, networks := range shared {
+		sort.Strings(networks)
+		result = append(result, Peer{A: key[0], B: key[1], Networks: networks})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].A != result[j].A {
+			return result[i].A < result[j].A
+		}
+		return result[i].B < result[j].B
+	})
+
+	return result
+}
@@ -0,0 +1,69 @@
+package topology
+
+import (
+	"testing"
+
+	"cyber-container-platform/internal/docker"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild(t *testing.T) {
+	networks := []docker.NetworkInfo{
+		{ID: "n1", Name: "backend", Driver: "bridge"},
+		{ID: "n2", Name: "frontend", Driver: "bridge"},
+	}
+	containers := []docker.ContainerInfo{
+		{
+			ID:   "c1",
+			Name: "api",
+			Networks: map[string]docker.ContainerNetwork{
+				"backend":  {NetworkID: "n1", IPAddress: "10.0.0.2"},
+				"frontend": {NetworkID: "n2", IPAddress: "10.1.0.2"},
+			},
+			Ports: []docker.PortInfo{{PrivatePort: 80, PublicPort: 8080, Type: "tcp", IP: "0.0.0.0"}, {PrivatePort: 9000, Type: "tcp"}},
+		},
+		{
+			ID:       "c2",
+			Name:     "db",
+			Networks: map[string]docker.ContainerNetwork{"backend": {NetworkID: "n1", IPAddress: "10.0.0.3"}},
+			Mounts:   []docker.MountInfo{{Type: "volume", Name: "pgdata", Destination: "/var/lib/postgresql/data", ReadWrite: true}},
+		},
+		{
+			ID:       "c3",
+			Name:     "web",
+			Networks: map[string]docker.ContainerNetwork{"frontend": {NetworkID: "n2", IPAddress: "10.1.0.3"}},
+			Mounts:   []docker.MountInfo{{Type: "bind", Source: "/srv/www", Destination: "/usr/share/nginx/html"}, {Type: "tmpfs", Destination: "/tmp"}},
+		},
+	}
+
+	graph := Build(networks, containers)
+
+	kinds := make(map[string]int)
+	for _, node := range graph.Nodes {
+		kinds[node.Kind]++
+	}
+	assert.Equal(t, map[string]int{KindNetwork: 2, KindContainer: 3, KindVolume: 1, KindBind: 1, KindPort: 1}, kinds)
+
+	edges := make(map[string]int)
+	for _, edge := range graph.Edges {
+		edges[edge.Kind]++
+	}
+	assert.Equal(t, map[string]int{EdgeAttached: 4, EdgeMounts: 2, EdgePublishes: 1}, edges)
+
+	assert.Equal(t, []Peer{
+		{A: "api", B: "db", Networks: []string{"backend"}},
+		{A: "api", B: "web", Networks: []string{"frontend"}},
+	}, graph.Peers)
+}
+
+func TestBuildAddsUnlistedNetworks(t *testing.T) {
+	graph := Build(nil, []docker.ContainerInfo{{
+		ID:       "c1",
+		Name:     "orphan",
+		Networks: map[string]docker.ContainerNetwork{"gone": {NetworkID: "n9"}},
+	}})
+
+	assert.Len(t, graph.Nodes, 2)
+	assert.Equal(t, "network:n9", graph.Edges[0].Target)
+}
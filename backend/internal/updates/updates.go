@@ -0,0 +1,113 @@
+// Package updates decides which containers the image update watcher
+// tracks and when it may recreate them.
+package updates
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"cyber-container-platform/internal/scheduler"
+
+	"github.com/distribution/reference"
+)
+
+// Label opts a container into update checks. Its value is a Mode.
+const Label = "cyber.platform.update"
+
+const (
+	// ModeNotify only reports available updates.
+	ModeNotify = "notify"
+	// ModeAuto also recreates the container with the new image during the
+	// maintenance window.
+	ModeAuto = "auto"
+)
+
+// ModeOf returns the update mode a container's labels select, or "" if the
+// container is not watched. "true" is accepted as notify.
+func ModeOf(labels map[string]string) string {
+	switch strings.ToLower(strings.TrimSpace(labels[Label])) {
+	case ModeNotify, "true":
+		return ModeNotify
+	case ModeAuto:
+		return ModeAuto
+	default:
+		return ""
+	}
+}
+
+// ErrNotTracked is returned for image references that cannot change in a
+// registry: references pinned by digest and bare image IDs.
+var ErrNotTracked = errors.New("image reference does not follow a tag")
+
+// Trackable checks that ref names a tag whose digest can change.
+func Trackable(ref string) error {
+	if strings.HasPrefix(ref, "sha256:") {
+		return fmt.Errorf("%w: %s is an image ID", ErrNotTracked, ref)
+	}
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotTracked, err)
+	}
+	if _, ok := named.(reference.Digested); ok {
+		return fmt.Errorf("%w: %s is pinned by digest", ErrNotTracked, ref)
+	}
+	return nil
+}
+
+// Window is a recurring maintenance window: it opens at every activation
+// of Schedule, a cron expression in server local time, and stays open for
+// Duration.
+type Window struct {
+	Schedule string `json:"schedule"`
+	Duration string `json:"duration"`
+}
+
+func (w Window) parse() (scheduler.Schedule, time.Duration, error) {
+	if strings.HasPrefix(strings.TrimSpace(w.Schedule), "@every") {
+		return nil, 0, fmt.Errorf("maintenance window schedule must be a cron expression")
+	}
+	schedule, err := scheduler.ParseSchedule(w.Schedule)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid maintenance window schedule: %w", err)
+	}
+	duration, err := time.ParseDuration(w.Duration)
+	if err != nil || duration < time.Minute {
+		return nil, 0, fmt.Errorf("maintenance window duration must be at least 1m, got %q", w.Duration)
+	}
+	return schedule, duration, nil
+}
+
+func (w Window) Validate() error {
+	_, _, err := w.parse()
+	return err
+}
+
+// Open reports whether the window is open at now.
+func (w Window) Open(now time.Time) bool {
+	schedule, duration, err := w.parse()
+	if err != nil {
+		return false
+	}
+	// The last opening no longer ago than duration, if any.
+	return !schedule.Next(now.Add(-duration)).After(now)
+}
+
+// Settings configure automatic updates. Without a maintenance window,
+// containers in auto mode are only reported, like notify.
+type Settings struct {
+	MaintenanceWindow *Window `json:"maintenance_window"`
+}
+
+func (s Settings) Validate() error {
+	if s.MaintenanceWindow != nil {
+		return s.MaintenanceWindow.Validate()
+	}
+	return nil
+}
+
+// CanApply reports whether automatic updates may run at now.
+func (s Settings) CanApply(now time.Time) bool {
+	return s.MaintenanceWindow != nil && s.MaintenanceWindow.Open(now)
+}
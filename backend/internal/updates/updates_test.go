@@ -0,0 +1,54 @@
+package updates
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModeOf(t *testing.T) {
+	assert.Equal(t, ModeNotify, ModeOf(map[string]string{Label: "notify"}))
+	assert.Equal(t, ModeNotify, ModeOf(map[string]string{Label: "true"}))
+	assert.Equal(t, ModeAuto, ModeOf(map[string]string{Label: " Auto "}))
+	assert.Empty(t, ModeOf(map[string]string{Label: "false"}))
+	assert.Empty(t, ModeOf(nil))
+}
+
+func TestTrackable(t *testing.T) {
+	assert.NoError(t, Trackable("nginx:1.27"))
+	assert.NoError(t, Trackable("registry.example.com:5000/team/api"))
+
+	for _, ref := range []string{
+		"nginx@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+		"sha256:0000000000000000000000000000000000000000000000000000000000000000",
+		"Not A Reference",
+	} {
+		assert.True(t, errors.Is(Trackable(ref), ErrNotTracked), ref)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	// Saturdays 02:00 to 04:00.
+	w := Window{Schedule: "0 2 * * 6", Duration: "2h"}
+	assert.NoError(t, w.Validate())
+
+	saturday := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	assert.False(t, w.Open(saturday.Add(time.Hour+59*time.Minute)))
+	assert.True(t, w.Open(saturday.Add(2*time.Hour)))
+	assert.True(t, w.Open(saturday.Add(3*time.Hour+59*time.Minute)))
+	assert.False(t, w.Open(saturday.Add(4*time.Hour)))
+	assert.False(t, w.Open(saturday.Add(26*time.Hour)), "Sunday")
+
+	for _, bad := range []Window{
+		{Schedule: "@every 1h", Duration: "30m"},
+		{Schedule: "0 2 * * 6", Duration: "30s"},
+		{Schedule: "0 2 * *", Duration: "2h"},
+	} {
+		assert.Error(t, bad.Validate(), bad.Schedule+" "+bad.Duration)
+	}
+
+	assert.False(t, Settings{}.CanApply(saturday.Add(3*time.Hour)), "no window, no automatic updates")
+	assert.True(t, Settings{MaintenanceWindow: &w}.CanApply(saturday.Add(3*time.Hour)))
+}
@@ -12,7 +12,10 @@ import (
 
 func main() {
 	// Load configuration
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Invalid configuration:", err)
+	}
 
 	// Initialize database
 	db, err := database.Init(cfg.DatabasePath)
@@ -20,6 +23,9 @@ func main() {
 		log.Fatal("Failed to initialize database:", err)
 	}
 	defer db.Close()
+	if password := db.InitialAdminPassword(); password != "" {
+		log.Printf("Created administrator %q with password %q; it is not shown again", database.AdminUsername, password)
+	}
 
 	// Initialize Docker client
 	dockerClient, err := docker.NewClient()